@@ -26,9 +26,45 @@ type config struct {
 	DBUsername         string
 	DBPassword         string
 	TokenDuration      string
+	RefreshDuration    string
+	AdminEmail         string
+	AdminPassword      string
 	RedisServer        string
 	RedisPassword      string
 	HttpAllowedOrigins string
+	HttpAllowedMethods string
+	HttpAllowedHeaders string
+	HttpExposedHeaders string
+	HttpCorsMaxAge     string
+	HttpAllowCreds     bool
+	AdminPort          string
+	EnableSwagger      bool
+	EnablePprof        bool
+	IdempotencyKeyTTL  string
+	IdempotencyCleanup string
+	ReceiptStoreName   string
+	ReceiptFooter      string
+	ReceiptWidth       int
+	ReceiptSymbol      string
+	ReportTimezone     string
+	TaxPriceMode       string
+	TaxRounding        string
+	HeldOrderTTL       string
+	HeldOrderCleanup   string
+	StorageBackend     string
+	StorageLocalDir    string
+	StoragePublicURL   string
+	StorageS3Endpoint  string
+	StorageS3Region    string
+	StorageS3Bucket    string
+	StorageS3AccessKey string
+	StorageS3SecretKey string
+	ImageMaxBytes      int64
+	ImageThumbSize     int
+	RateLimitStore     string
+	RateLimitIP        string
+	RateLimitUser      string
+	RateLimitGroups    map[string]string
 	Loglevel           string
 	ShutDownTime       string
 	ShutDowntype       string
@@ -62,28 +98,64 @@ type Econfig struct {
 }*/
 
 type Econfig struct {
-	appName            string `mapstructure:"AppName"`
-	appEnv             string `mapstructure:"AppEnv"`
-	dBConnection       string `mapstructure:"DBConnection"`
-	tokenSymmetricKey  string `mapstructure:"TokenSymmetricKey"`
-	httpUrl            string `mapstructure:"HttpUrl"`
-	httpPort           string `mapstructure:"HttpPort"`
-	dBHost             string `mapstructure:"DBHost"`
-	dBPort             string `mapstructure:"DBPort"`
-	dBdatabase         string `mapstructure:"DBdatabase"`
-	dBUsername         string `mapstructure:"DBUsername"`
-	dBPassword         string `mapstructure:"DBPassword"`
-	tokenDuration      string `mapstructure:"TokenDuration"`
-	redisServer        string `mapstructure:"RedisServer"`
-	redisPassword      string `mapstructure:"RedisPassword"`
-	httpAllowedOrigins string `mapstructure:"HttpAllowedOrigins"`
-	loglevel           string `mapstructure:"Loglevel"`
-	shutDownTime       string `mapstructure:"ShutDownTime"`
-	shutDowntype       string `mapstructure:"ShutDowntype"`
-	maxConns           int    `mapstructure:"MaxConns"`
-	minConns           int    `mapstructure:"MinConns"`
-	maxConnLifetime    int    `mapstructure:"MaxConnLifetime"`
-	maxConnIdleTime    int    `mapstructure:"MaxConnIdleTime"`
+	appName            string            `mapstructure:"AppName"`
+	appEnv             string            `mapstructure:"AppEnv"`
+	dBConnection       string            `mapstructure:"DBConnection"`
+	tokenSymmetricKey  string            `mapstructure:"TokenSymmetricKey"`
+	httpUrl            string            `mapstructure:"HttpUrl"`
+	httpPort           string            `mapstructure:"HttpPort"`
+	dBHost             string            `mapstructure:"DBHost"`
+	dBPort             string            `mapstructure:"DBPort"`
+	dBdatabase         string            `mapstructure:"DBdatabase"`
+	dBUsername         string            `mapstructure:"DBUsername"`
+	dBPassword         string            `mapstructure:"DBPassword"`
+	tokenDuration      string            `mapstructure:"TokenDuration"`
+	refreshDuration    string            `mapstructure:"RefreshDuration"`
+	adminEmail         string            `mapstructure:"AdminEmail"`
+	adminPassword      string            `mapstructure:"AdminPassword"`
+	redisServer        string            `mapstructure:"RedisServer"`
+	redisPassword      string            `mapstructure:"RedisPassword"`
+	httpAllowedOrigins string            `mapstructure:"HttpAllowedOrigins"`
+	httpAllowedMethods string            `mapstructure:"HttpAllowedMethods"`
+	httpAllowedHeaders string            `mapstructure:"HttpAllowedHeaders"`
+	httpExposedHeaders string            `mapstructure:"HttpExposedHeaders"`
+	httpCorsMaxAge     string            `mapstructure:"HttpCorsMaxAge"`
+	httpAllowCreds     bool              `mapstructure:"HttpAllowCreds"`
+	adminPort          string            `mapstructure:"AdminPort"`
+	enableSwagger      bool              `mapstructure:"EnableSwagger"`
+	enablePprof        bool              `mapstructure:"EnablePprof"`
+	idempotencyKeyTTL  string            `mapstructure:"IdempotencyKeyTTL"`
+	idempotencyCleanup string            `mapstructure:"IdempotencyCleanup"`
+	receiptStoreName   string            `mapstructure:"ReceiptStoreName"`
+	receiptFooter      string            `mapstructure:"ReceiptFooter"`
+	receiptWidth       int               `mapstructure:"ReceiptWidth"`
+	receiptSymbol      string            `mapstructure:"ReceiptSymbol"`
+	reportTimezone     string            `mapstructure:"ReportTimezone"`
+	taxPriceMode       string            `mapstructure:"TaxPriceMode"`
+	taxRounding        string            `mapstructure:"TaxRounding"`
+	heldOrderTTL       string            `mapstructure:"HeldOrderTTL"`
+	heldOrderCleanup   string            `mapstructure:"HeldOrderCleanup"`
+	storageBackend     string            `mapstructure:"StorageBackend"`
+	storageLocalDir    string            `mapstructure:"StorageLocalDir"`
+	storagePublicURL   string            `mapstructure:"StoragePublicURL"`
+	storageS3Endpoint  string            `mapstructure:"StorageS3Endpoint"`
+	storageS3Region    string            `mapstructure:"StorageS3Region"`
+	storageS3Bucket    string            `mapstructure:"StorageS3Bucket"`
+	storageS3AccessKey string            `mapstructure:"StorageS3AccessKey"`
+	storageS3SecretKey string            `mapstructure:"StorageS3SecretKey"`
+	imageMaxBytes      int64             `mapstructure:"ImageMaxBytes"`
+	imageThumbSize     int               `mapstructure:"ImageThumbSize"`
+	rateLimitStore     string            `mapstructure:"RateLimitStore"`
+	rateLimitIP        string            `mapstructure:"RateLimitIP"`
+	rateLimitUser      string            `mapstructure:"RateLimitUser"`
+	rateLimitGroups    map[string]string `mapstructure:"RateLimitGroups"`
+	loglevel           string            `mapstructure:"Loglevel"`
+	shutDownTime       string            `mapstructure:"ShutDownTime"`
+	shutDowntype       string            `mapstructure:"ShutDowntype"`
+	maxConns           int               `mapstructure:"MaxConns"`
+	minConns           int               `mapstructure:"MinConns"`
+	maxConnLifetime    int               `mapstructure:"MaxConnLifetime"`
+	maxConnIdleTime    int               `mapstructure:"MaxConnIdleTime"`
 }
 
 func NewConfig(c config) Econfig {
@@ -100,9 +172,45 @@ func NewConfig(c config) Econfig {
 		dBUsername:         c.DBUsername,
 		dBPassword:         c.DBPassword,
 		tokenDuration:      c.TokenDuration,
+		refreshDuration:    c.RefreshDuration,
+		adminEmail:         c.AdminEmail,
+		adminPassword:      c.AdminPassword,
 		redisServer:        c.RedisServer,
 		redisPassword:      c.RedisPassword,
 		httpAllowedOrigins: c.HttpAllowedOrigins,
+		httpAllowedMethods: c.HttpAllowedMethods,
+		httpAllowedHeaders: c.HttpAllowedHeaders,
+		httpExposedHeaders: c.HttpExposedHeaders,
+		httpCorsMaxAge:     c.HttpCorsMaxAge,
+		httpAllowCreds:     c.HttpAllowCreds,
+		adminPort:          c.AdminPort,
+		enableSwagger:      c.EnableSwagger,
+		enablePprof:        c.EnablePprof,
+		idempotencyKeyTTL:  c.IdempotencyKeyTTL,
+		idempotencyCleanup: c.IdempotencyCleanup,
+		receiptStoreName:   c.ReceiptStoreName,
+		receiptFooter:      c.ReceiptFooter,
+		receiptWidth:       c.ReceiptWidth,
+		receiptSymbol:      c.ReceiptSymbol,
+		reportTimezone:     c.ReportTimezone,
+		taxPriceMode:       c.TaxPriceMode,
+		taxRounding:        c.TaxRounding,
+		heldOrderTTL:       c.HeldOrderTTL,
+		heldOrderCleanup:   c.HeldOrderCleanup,
+		storageBackend:     c.StorageBackend,
+		storageLocalDir:    c.StorageLocalDir,
+		storagePublicURL:   c.StoragePublicURL,
+		storageS3Endpoint:  c.StorageS3Endpoint,
+		storageS3Region:    c.StorageS3Region,
+		storageS3Bucket:    c.StorageS3Bucket,
+		storageS3AccessKey: c.StorageS3AccessKey,
+		storageS3SecretKey: c.StorageS3SecretKey,
+		imageMaxBytes:      c.ImageMaxBytes,
+		imageThumbSize:     c.ImageThumbSize,
+		rateLimitStore:     c.RateLimitStore,
+		rateLimitIP:        c.RateLimitIP,
+		rateLimitUser:      c.RateLimitUser,
+		rateLimitGroups:    c.RateLimitGroups,
 		loglevel:           c.Loglevel,
 		shutDownTime:       c.ShutDownTime,
 		shutDowntype:       c.ShutDowntype,
@@ -223,6 +331,21 @@ func (c *Econfig) TokenDuration() string {
 	return c.tokenDuration
 }
 
+// RefreshDuration returns the refreshDuration field value.
+func (c *Econfig) RefreshDuration() string {
+	return c.refreshDuration
+}
+
+// AdminEmail returns the adminEmail field value.
+func (c *Econfig) AdminEmail() string {
+	return c.adminEmail
+}
+
+// AdminPassword returns the adminPassword field value.
+func (c *Econfig) AdminPassword() string {
+	return c.adminPassword
+}
+
 // RedisServer returns the redisServer field value.
 func (c *Econfig) RedisServer() string {
 	return c.redisServer
@@ -238,6 +361,171 @@ func (c *Econfig) HttpAllowedOrigins() string {
 	return c.httpAllowedOrigins
 }
 
+// HttpAllowedMethods returns the httpAllowedMethods field value.
+func (c *Econfig) HttpAllowedMethods() string {
+	return c.httpAllowedMethods
+}
+
+// HttpAllowedHeaders returns the httpAllowedHeaders field value.
+func (c *Econfig) HttpAllowedHeaders() string {
+	return c.httpAllowedHeaders
+}
+
+// HttpExposedHeaders returns the httpExposedHeaders field value.
+func (c *Econfig) HttpExposedHeaders() string {
+	return c.httpExposedHeaders
+}
+
+// HttpCorsMaxAge returns the httpCorsMaxAge field value.
+func (c *Econfig) HttpCorsMaxAge() string {
+	return c.httpCorsMaxAge
+}
+
+// HttpAllowCreds returns the httpAllowCreds field value.
+func (c *Econfig) HttpAllowCreds() bool {
+	return c.httpAllowCreds
+}
+
+// AdminPort returns the adminPort field value.
+func (c *Econfig) AdminPort() string {
+	return c.adminPort
+}
+
+// EnableSwagger returns the enableSwagger field value.
+func (c *Econfig) EnableSwagger() bool {
+	return c.enableSwagger
+}
+
+// EnablePprof returns the enablePprof field value.
+func (c *Econfig) EnablePprof() bool {
+	return c.enablePprof
+}
+
+// IdempotencyKeyTTL returns the idempotencyKeyTTL field value.
+func (c *Econfig) IdempotencyKeyTTL() string {
+	return c.idempotencyKeyTTL
+}
+
+// IdempotencyCleanup returns the idempotencyCleanup field value.
+func (c *Econfig) IdempotencyCleanup() string {
+	return c.idempotencyCleanup
+}
+
+// ReceiptStoreName returns the receiptStoreName field value.
+func (c *Econfig) ReceiptStoreName() string {
+	return c.receiptStoreName
+}
+
+// ReceiptFooter returns the receiptFooter field value.
+func (c *Econfig) ReceiptFooter() string {
+	return c.receiptFooter
+}
+
+// ReceiptWidth returns the receiptWidth field value.
+func (c *Econfig) ReceiptWidth() int {
+	return c.receiptWidth
+}
+
+// ReceiptSymbol returns the receiptSymbol field value.
+func (c *Econfig) ReceiptSymbol() string {
+	return c.receiptSymbol
+}
+
+// ReportTimezone returns the reportTimezone field value.
+func (c *Econfig) ReportTimezone() string {
+	return c.reportTimezone
+}
+
+// TaxPriceMode returns the taxPriceMode field value.
+func (c *Econfig) TaxPriceMode() string {
+	return c.taxPriceMode
+}
+
+// TaxRounding returns the taxRounding field value.
+func (c *Econfig) TaxRounding() string {
+	return c.taxRounding
+}
+
+// HeldOrderTTL returns the heldOrderTTL field value.
+func (c *Econfig) HeldOrderTTL() string {
+	return c.heldOrderTTL
+}
+
+// HeldOrderCleanup returns the heldOrderCleanup field value.
+func (c *Econfig) HeldOrderCleanup() string {
+	return c.heldOrderCleanup
+}
+
+// StorageBackend returns the storageBackend field value.
+func (c *Econfig) StorageBackend() string {
+	return c.storageBackend
+}
+
+// StorageLocalDir returns the storageLocalDir field value.
+func (c *Econfig) StorageLocalDir() string {
+	return c.storageLocalDir
+}
+
+// StoragePublicURL returns the storagePublicURL field value.
+func (c *Econfig) StoragePublicURL() string {
+	return c.storagePublicURL
+}
+
+// StorageS3Endpoint returns the storageS3Endpoint field value.
+func (c *Econfig) StorageS3Endpoint() string {
+	return c.storageS3Endpoint
+}
+
+// StorageS3Region returns the storageS3Region field value.
+func (c *Econfig) StorageS3Region() string {
+	return c.storageS3Region
+}
+
+// StorageS3Bucket returns the storageS3Bucket field value.
+func (c *Econfig) StorageS3Bucket() string {
+	return c.storageS3Bucket
+}
+
+// StorageS3AccessKey returns the storageS3AccessKey field value.
+func (c *Econfig) StorageS3AccessKey() string {
+	return c.storageS3AccessKey
+}
+
+// StorageS3SecretKey returns the storageS3SecretKey field value.
+func (c *Econfig) StorageS3SecretKey() string {
+	return c.storageS3SecretKey
+}
+
+// ImageMaxBytes returns the imageMaxBytes field value.
+func (c *Econfig) ImageMaxBytes() int64 {
+	return c.imageMaxBytes
+}
+
+// ImageThumbSize returns the imageThumbSize field value.
+func (c *Econfig) ImageThumbSize() int {
+	return c.imageThumbSize
+}
+
+// RateLimitStore returns the rateLimitStore field value.
+func (c *Econfig) RateLimitStore() string {
+	return c.rateLimitStore
+}
+
+// RateLimitIP returns the rateLimitIP field value.
+func (c *Econfig) RateLimitIP() string {
+	return c.rateLimitIP
+}
+
+// RateLimitUser returns the rateLimitUser field value.
+func (c *Econfig) RateLimitUser() string {
+	return c.rateLimitUser
+}
+
+// RateLimitGroups returns the rateLimitGroups field value.
+func (c *Econfig) RateLimitGroups() map[string]string {
+	return c.rateLimitGroups
+}
+
 // LogLevel returns the loglevel field value.
 func (c *Econfig) LogLevel() string {
 	return c.loglevel
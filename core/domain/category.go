@@ -2,10 +2,12 @@ package domain
 
 import "time"
 
-// Category is an entity that represents a category of product
+// Category is an entity that represents a category of product.
+// TaxRate is the tax in percent of its products without a rate of their own, nil when untaxed.
 type Category struct {
-	ID        uint64    `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint64    `json:"id" query:"eq,in,sort"`
+	Name      string    `json:"name" query:"eq,ne,like,in,sort"`
+	TaxRate   *float64  `json:"tax_rate" query:"eq,ne,gt,gte,lt,lte"`
+	CreatedAt time.Time `json:"created_at" query:"gt,gte,lt,lte,sort"`
+	UpdatedAt time.Time `json:"updated_at" query:"gt,gte,lt,lte,sort"`
 }
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// HeldOrder is an entity that represents a cart parked by a cashier to serve another customer.
+// Its lines reserve no stock and no payment is taken until it is completed as an order;
+// it is discarded once ExpiresAt has passed.
+type HeldOrder struct {
+	ID           uint64             `json:"id"`
+	UserID       uint64             `json:"user_id"`
+	Terminal     string             `json:"terminal"`
+	CustomerName string             `json:"customer_name"`
+	Note         *string            `json:"note"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+	Products     []HeldOrderProduct `json:"products"`
+}
+
+// HeldOrderProduct is an entity that represents a line of a held order
+type HeldOrderProduct struct {
+	ID          uint64   `json:"id"`
+	HeldOrderID uint64   `json:"held_order_id"`
+	ProductID   uint64   `json:"product_id"`
+	Quantity    int64    `json:"quantity"`
+	Product     *Product `json:"product"`
+}
+
+// HeldOrderFilter narrows down a list of held orders; zero values match every held order
+type HeldOrderFilter struct {
+	UserID   uint64
+	Terminal string
+}
+
+// OrderProducts converts the lines of the held order to the lines of an order to price or create
+func (h *HeldOrder) OrderProducts() []OrderProduct {
+	products := make([]OrderProduct, 0, len(h.Products))
+	for _, line := range h.Products {
+		products = append(products, OrderProduct{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+		})
+	}
+	return products
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// IdempotencyKey is an entity that represents a client supplied key
+// and the stored response of the request first made with it
+type IdempotencyKey struct {
+	Key         string    `json:"key"`
+	UserID      uint64    `json:"user_id"`
+	Path        string    `json:"path"`
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	Response    []byte    `json:"response"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// Render builds the response to store once the request succeeded
+	Render func() (statusCode int, body []byte, err error) `json:"-"`
+}
@@ -6,19 +6,86 @@ import (
 	"github.com/google/uuid"
 )
 
-// Order is an entity that represents an order
+// OrderStatus is an enum for order's status
+type OrderStatus string
+
+// OrderStatus enum values
+const (
+	OrderPending           OrderStatus = "pending"
+	OrderPaid              OrderStatus = "paid"
+	OrderCancelled         OrderStatus = "cancelled"
+	OrderRefunded          OrderStatus = "refunded"
+	OrderPartiallyRefunded OrderStatus = "partially_refunded"
+)
+
+// orderTransitions lists the statuses an order can move to from each status
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:           {OrderPaid, OrderCancelled},
+	OrderPaid:              {OrderCancelled, OrderRefunded, OrderPartiallyRefunded},
+	OrderPartiallyRefunded: {OrderRefunded, OrderPartiallyRefunded},
+}
+
+// CanTransitionTo reports whether an order in status s may move to next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Order is an entity that represents an order.
+// TotalNormalPrice is the total before discounts and TotalPrice the total due, TaxAmount included.
+// TaxInclusive and TaxRounding record how the tax was computed, see ApplyTaxes.
 type Order struct {
-	ID           uint64         `json:"id"`
-	UserID       uint64         `json:"user_id"`
-	PaymentID    uint64         `json:"payment_id"`
-	CustomerName string         `json:"customer_name"`
-	TotalPrice   float64        `json:"total_price"`
-	TotalPaid    float64        `json:"total_paid"`
-	TotalReturn  float64        `json:"total_return"`
-	ReceiptCode  uuid.UUID      `json:"receipt_code"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	User         *User          `json:"user"`
-	Payment      *Payment       `json:"payment"`
-	Products     []OrderProduct `json:"products"`
+	ID               uint64         `json:"id"`
+	UserID           uint64         `json:"user_id"`
+	PaymentID        uint64         `json:"payment_id"`
+	ShiftID          *uint64        `json:"shift_id"`
+	CustomerName     string         `json:"customer_name"`
+	TotalNormalPrice float64        `json:"total_normal_price"`
+	Discount         float64        `json:"discount"`
+	PromotionID      *uint64        `json:"promotion_id"`
+	TaxAmount        float64        `json:"tax_amount"`
+	TaxInclusive     bool           `json:"tax_inclusive"`
+	TaxRounding      TaxRounding    `json:"tax_rounding"`
+	TotalPrice       float64        `json:"total_price"`
+	TotalPaid        float64        `json:"total_paid"`
+	TotalReturn      float64        `json:"total_return"`
+	ReceiptCode      uuid.UUID      `json:"receipt_code"`
+	Status           OrderStatus    `json:"status"`
+	CancelledBy      *uint64        `json:"cancelled_by"`
+	CancelledAt      *time.Time     `json:"cancelled_at"`
+	CancelReason     *string        `json:"cancel_reason"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	User             *User          `json:"user"`
+	Payment          *Payment       `json:"payment"`
+	Promotion        *Promotion     `json:"promotion"`
+	Products         []OrderProduct `json:"products"`
+}
+
+// OrderSort is how a list of orders is ordered, a leading - sorts descending
+type OrderSort string
+
+// OrderSort enum values
+const (
+	SortCreatedAsc  OrderSort = "created_at"
+	SortCreatedDesc OrderSort = "-created_at"
+	SortTotalAsc    OrderSort = "total_price"
+	SortTotalDesc   OrderSort = "-total_price"
+)
+
+// OrderFilter narrows down and orders a list of orders; zero values match every order in id order
+type OrderFilter struct {
+	From              *time.Time
+	To                *time.Time
+	UserID            uint64
+	PaymentID         uint64
+	CustomerName      string
+	MinTotal          *float64
+	MaxTotal          *float64
+	ReceiptCodePrefix string
+	Sort              OrderSort
 }
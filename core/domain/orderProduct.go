@@ -2,15 +2,23 @@ package domain
 
 import "time"
 
-// OrderProduct is an entity that represents pivot table between order and product
+// OrderProduct is an entity that represents pivot table between order and product.
+// NormalPrice is the line total before discounts and TotalPrice the line total due, TaxAmount included.
 type OrderProduct struct {
-	ID         uint64    `json:"id"`
-	OrderID    uint64    `json:"order_id"`
-	ProductID  uint64    `json:"product_id"`
-	Quantity   int64     `json:"quantity"`
-	TotalPrice float64   `json:"total_price"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	Order      *Order    `json:"order"`
-	Product    *Product  `json:"product"`
+	ID               uint64     `json:"id"`
+	OrderID          uint64     `json:"order_id"`
+	ProductID        uint64     `json:"product_id"`
+	Quantity         int64      `json:"quantity"`
+	RefundedQuantity int64      `json:"refunded_quantity"`
+	NormalPrice      float64    `json:"normal_price"`
+	Discount         float64    `json:"discount"`
+	PromotionID      *uint64    `json:"promotion_id"`
+	TaxRate          float64    `json:"tax_rate"`
+	TaxAmount        float64    `json:"tax_amount"`
+	TotalPrice       float64    `json:"total_price"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	Order            *Order     `json:"order"`
+	Product          *Product   `json:"product"`
+	Promotion        *Promotion `json:"promotion"`
 }
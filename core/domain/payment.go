@@ -12,12 +12,14 @@ const (
 	EDC     PaymentType = "EDC"
 )
 
-// Payment is an entity that represents a payment
+// Payment is an entity that represents a payment.
+// LogoThumbnail is the URL of a small copy of an uploaded Logo, empty when Logo was set as a URL.
 type Payment struct {
-	ID        uint64      `json:"id"`
-	Name      string      `json:"name"`
-	Type      PaymentType `json:"type"`
-	Logo      string      `json:"logo"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
+	ID            uint64      `json:"id" query:"eq,in,sort"`
+	Name          string      `json:"name" query:"eq,ne,like,in,sort"`
+	Type          PaymentType `json:"type" query:"eq,ne,in"`
+	Logo          string      `json:"logo"`
+	LogoThumbnail string      `json:"logo_thumbnail"`
+	CreatedAt     time.Time   `json:"created_at" query:"gt,gte,lt,lte,sort"`
+	UpdatedAt     time.Time   `json:"updated_at" query:"gt,gte,lt,lte,sort"`
 }
@@ -6,16 +6,35 @@ import (
 	"github.com/google/uuid"
 )
 
-// Product is an entity that represents a product
+// Product is an entity that represents a product.
+// TaxRate is its tax in percent, nil to use the rate of its category.
+// Thumbnail is the URL of a small copy of an uploaded Image, empty when Image was set as a URL.
+// SearchRank is how well the product matched a search, zero outside of searches.
 type Product struct {
-	ID         uint64    `json:"id"`
-	CategoryID uint64    `json:"category_id"`
-	SKU        uuid.UUID `json:"sku"`
-	Name       string    `json:"name"`
-	Stock      int64     `json:"stock"`
-	Price      float64   `json:"price"`
+	ID         uint64    `json:"id" query:"eq,in,sort"`
+	CategoryID uint64    `json:"category_id" query:"eq,ne,in"`
+	SKU        uuid.UUID `json:"sku" query:"eq,in"`
+	Barcode    *string   `json:"barcode" query:"eq,in"`
+	Name       string    `json:"name" query:"eq,ne,like,in,sort"`
+	Stock      int64     `json:"stock" query:"eq,ne,gt,gte,lt,lte,sort"`
+	Price      float64   `json:"price" query:"eq,ne,gt,gte,lt,lte,sort"`
 	Image      string    `json:"image"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Thumbnail  string    `json:"thumbnail"`
+	TaxRate    *float64  `json:"tax_rate" query:"eq,ne,gt,gte,lt,lte"`
+	CreatedAt  time.Time `json:"created_at" query:"gt,gte,lt,lte,sort"`
+	UpdatedAt  time.Time `json:"updated_at" query:"gt,gte,lt,lte,sort"`
 	Category   *Category `json:"category"`
+	SearchRank float32   `json:"search_rank,omitempty"`
+}
+
+// EffectiveTaxRate is the tax rate in percent the product is sold at:
+// its own rate, else the rate of its category, else zero
+func (p *Product) EffectiveTaxRate() float64 {
+	if p.TaxRate != nil {
+		return *p.TaxRate
+	}
+	if p.Category != nil && p.Category.TaxRate != nil {
+		return *p.Category.TaxRate
+	}
+	return 0
 }
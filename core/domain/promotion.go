@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// PromotionKind is an enum for how a promotion discounts
+type PromotionKind string
+
+// PromotionKind enum values
+const (
+	// PromotionPercentage takes a percentage off the price
+	PromotionPercentage PromotionKind = "percentage"
+	// PromotionFixed takes a fixed amount off every unit, or off the order total for order promotions
+	PromotionFixed PromotionKind = "fixed"
+	// PromotionBuyXGetY gives GetQuantity units free for every BuyQuantity units bought on an order line
+	PromotionBuyXGetY PromotionKind = "buy_x_get_y"
+)
+
+// PromotionScope is an enum for what a promotion applies to
+type PromotionScope string
+
+// PromotionScope enum values
+const (
+	ScopeProduct  PromotionScope = "product"
+	ScopeCategory PromotionScope = "category"
+	ScopeOrder    PromotionScope = "order"
+)
+
+// Promotion is an entity that represents a discount applied when pricing an order.
+// TargetID is the product or category id and is nil for order promotions.
+// A promotion without StartsAt or EndsAt is not bounded on that side.
+type Promotion struct {
+	ID          uint64         `json:"id" query:"eq,in,sort"`
+	Name        string         `json:"name" query:"eq,ne,like,in,sort"`
+	Kind        PromotionKind  `json:"kind" query:"eq,ne,in"`
+	Scope       PromotionScope `json:"scope" query:"eq,ne,in"`
+	TargetID    *uint64        `json:"target_id" query:"eq,in"`
+	Value       float64        `json:"value" query:"eq,ne,gt,gte,lt,lte,sort"`
+	BuyQuantity int64          `json:"buy_qty"`
+	GetQuantity int64          `json:"get_qty"`
+	MinSubtotal float64        `json:"min_subtotal"`
+	StartsAt    *time.Time     `json:"starts_at" query:"gt,gte,lt,lte"`
+	EndsAt      *time.Time     `json:"ends_at" query:"gt,gte,lt,lte"`
+	Active      bool           `json:"active" query:"eq"`
+	CreatedAt   time.Time      `json:"created_at" query:"gt,gte,lt,lte,sort"`
+	UpdatedAt   time.Time      `json:"updated_at" query:"gt,gte,lt,lte,sort"`
+}
+
+// ActiveAt reports whether the promotion is enabled and its time window includes at
+func (p *Promotion) ActiveAt(at time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && at.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !at.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// appliesTo reports whether a product or category promotion targets the product of a line
+func (p *Promotion) appliesTo(product *Product) bool {
+	if p.TargetID == nil || product == nil {
+		return false
+	}
+	switch p.Scope {
+	case ScopeProduct:
+		return product.ID == *p.TargetID
+	case ScopeCategory:
+		return product.CategoryID == *p.TargetID
+	}
+	return false
+}
+
+// lineDiscount is the discount of a product or category promotion on qty units at unitPrice
+func (p *Promotion) lineDiscount(unitPrice float64, qty int64) float64 {
+	normal := unitPrice * float64(qty)
+
+	var discount float64
+	switch p.Kind {
+	case PromotionPercentage:
+		discount = normal * p.Value / 100
+	case PromotionFixed:
+		discount = math.Min(p.Value, unitPrice) * float64(qty)
+	case PromotionBuyXGetY:
+		if group := p.BuyQuantity + p.GetQuantity; p.BuyQuantity > 0 && p.GetQuantity > 0 {
+			discount = unitPrice * float64(qty/group*p.GetQuantity)
+		}
+	}
+	return roundMoney(math.Min(discount, normal))
+}
+
+// orderDiscount is the discount of an order promotion on a subtotal, zero below MinSubtotal
+func (p *Promotion) orderDiscount(subtotal float64) float64 {
+	if subtotal <= 0 || subtotal < p.MinSubtotal {
+		return 0
+	}
+
+	var discount float64
+	switch p.Kind {
+	case PromotionPercentage:
+		discount = subtotal * p.Value / 100
+	case PromotionFixed:
+		discount = p.Value
+	}
+	return roundMoney(math.Min(discount, subtotal))
+}
+
+// ApplyPromotions prices the order lines from their product prices and the promotions active at the given time.
+// Every line gets the best product or category promotion for it, then the best order promotion is applied
+// to the discounted subtotal and spread over the lines in proportion to their totals.
+// Promotions do not stack beyond that; ties go to the lowest promotion id.
+// The lines must have their Product loaded.
+func (o *Order) ApplyPromotions(promotions []Promotion, at time.Time) {
+	active := make([]*Promotion, 0, len(promotions))
+	for i := range promotions {
+		if promotions[i].ActiveAt(at) {
+			active = append(active, &promotions[i])
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].ID < active[j].ID })
+
+	var normalTotal, subtotal float64
+	for i := range o.Products {
+		line := &o.Products[i]
+		var price float64
+		if line.Product != nil {
+			price = line.Product.Price
+		}
+
+		line.NormalPrice = roundMoney(price * float64(line.Quantity))
+		line.Discount = 0
+		line.PromotionID = nil
+		line.Promotion = nil
+
+		for _, p := range active {
+			if p.Scope == ScopeOrder || !p.appliesTo(line.Product) {
+				continue
+			}
+			if discount := p.lineDiscount(price, line.Quantity); discount > line.Discount {
+				line.Discount = discount
+				line.PromotionID = &p.ID
+				line.Promotion = p
+			}
+		}
+
+		line.TotalPrice = roundMoney(line.NormalPrice - line.Discount)
+		normalTotal += line.NormalPrice
+		subtotal += line.TotalPrice
+	}
+	subtotal = roundMoney(subtotal)
+
+	var orderDiscount float64
+	o.PromotionID = nil
+	o.Promotion = nil
+	for _, p := range active {
+		if p.Scope != ScopeOrder {
+			continue
+		}
+		if discount := p.orderDiscount(subtotal); discount > orderDiscount {
+			orderDiscount = discount
+			o.PromotionID = &p.ID
+			o.Promotion = p
+		}
+	}
+
+	// Spread the order discount over the lines, the rounding remainder going to the last line it fits on
+	if orderDiscount > 0 {
+		remaining := orderDiscount
+		for i := range o.Products {
+			line := &o.Products[i]
+			share := roundMoney(orderDiscount * line.TotalPrice / subtotal)
+			share = math.Min(share, math.Min(remaining, line.TotalPrice))
+			line.Discount = roundMoney(line.Discount + share)
+			line.TotalPrice = roundMoney(line.TotalPrice - share)
+			remaining = roundMoney(remaining - share)
+		}
+		for i := len(o.Products) - 1; i >= 0 && remaining > 0; i-- {
+			line := &o.Products[i]
+			share := math.Min(remaining, line.TotalPrice)
+			line.Discount = roundMoney(line.Discount + share)
+			line.TotalPrice = roundMoney(line.TotalPrice - share)
+			remaining = roundMoney(remaining - share)
+		}
+	}
+
+	var total float64
+	for _, line := range o.Products {
+		total += line.TotalPrice
+	}
+
+	o.TotalNormalPrice = roundMoney(normalTotal)
+	o.TotalPrice = roundMoney(total)
+	o.Discount = roundMoney(o.TotalNormalPrice - o.TotalPrice)
+}
+
+// roundMoney rounds an amount to two decimal places
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
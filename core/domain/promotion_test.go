@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCart is an order of 3 units of product 1 at 1000 in category 10 and 1 unit of product 2 at 500 in category 20
+func testCart() *Order {
+	return &Order{
+		Products: []OrderProduct{
+			{ProductID: 1, Quantity: 3, Product: &Product{ID: 1, CategoryID: 10, Price: 1000}},
+			{ProductID: 2, Quantity: 1, Product: &Product{ID: 2, CategoryID: 20, Price: 500}},
+		},
+	}
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
+func TestApplyPromotionsWithoutPromotions(t *testing.T) {
+	order := testCart()
+
+	order.ApplyPromotions(nil, time.Now())
+
+	assert.Equal(t, 3000.0, order.Products[0].NormalPrice)
+	assert.Equal(t, 3000.0, order.Products[0].TotalPrice)
+	assert.Zero(t, order.Products[0].Discount)
+	assert.Nil(t, order.Products[0].PromotionID)
+	assert.Equal(t, 3500.0, order.TotalNormalPrice)
+	assert.Equal(t, 3500.0, order.TotalPrice)
+	assert.Zero(t, order.Discount)
+	assert.Nil(t, order.PromotionID)
+}
+
+func TestApplyPromotionsLineKinds(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion Promotion
+		discount  float64
+	}{
+		{"percentage", Promotion{Kind: PromotionPercentage, Scope: ScopeProduct, TargetID: uint64Ptr(1), Value: 10}, 300},
+		{"fixed per unit", Promotion{Kind: PromotionFixed, Scope: ScopeCategory, TargetID: uint64Ptr(10), Value: 150}, 450},
+		{"fixed capped at the price", Promotion{Kind: PromotionFixed, Scope: ScopeProduct, TargetID: uint64Ptr(1), Value: 5000}, 3000},
+		{"buy 2 get 1", Promotion{Kind: PromotionBuyXGetY, Scope: ScopeProduct, TargetID: uint64Ptr(1), BuyQuantity: 2, GetQuantity: 1}, 1000},
+		{"buy 3 get 1 on 3 units", Promotion{Kind: PromotionBuyXGetY, Scope: ScopeProduct, TargetID: uint64Ptr(1), BuyQuantity: 3, GetQuantity: 1}, 0},
+		{"other category", Promotion{Kind: PromotionPercentage, Scope: ScopeCategory, TargetID: uint64Ptr(30), Value: 50}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := testCart()
+			test.promotion.ID = 7
+			test.promotion.Active = true
+
+			order.ApplyPromotions([]Promotion{test.promotion}, time.Now())
+
+			line := order.Products[0]
+			assert.Equal(t, test.discount, line.Discount)
+			assert.Equal(t, 3000-test.discount, line.TotalPrice)
+			assert.Equal(t, test.discount, order.Discount)
+			assert.Equal(t, 3500-test.discount, order.TotalPrice)
+			if test.discount > 0 {
+				require.NotNil(t, line.PromotionID)
+				assert.Equal(t, uint64(7), *line.PromotionID)
+			} else {
+				assert.Nil(t, line.PromotionID)
+			}
+		})
+	}
+}
+
+func TestApplyPromotionsBestLinePromotion(t *testing.T) {
+	order := testCart()
+	promotions := []Promotion{
+		{ID: 2, Active: true, Kind: PromotionPercentage, Scope: ScopeCategory, TargetID: uint64Ptr(10), Value: 20},
+		{ID: 1, Active: true, Kind: PromotionPercentage, Scope: ScopeProduct, TargetID: uint64Ptr(1), Value: 10},
+		{ID: 3, Active: true, Kind: PromotionFixed, Scope: ScopeProduct, TargetID: uint64Ptr(1), Value: 200},
+	}
+
+	order.ApplyPromotions(promotions, time.Now())
+
+	assert.Equal(t, 600.0, order.Products[0].Discount)
+	require.NotNil(t, order.Products[0].PromotionID)
+	assert.Equal(t, uint64(2), *order.Products[0].PromotionID)
+}
+
+func TestApplyPromotionsOrderPromotion(t *testing.T) {
+	order := testCart()
+	promotions := []Promotion{
+		{ID: 1, Active: true, Kind: PromotionBuyXGetY, Scope: ScopeProduct, TargetID: uint64Ptr(1), BuyQuantity: 2, GetQuantity: 1},
+		{ID: 2, Active: true, Kind: PromotionPercentage, Scope: ScopeOrder, Value: 10},
+		{ID: 3, Active: true, Kind: PromotionFixed, Scope: ScopeOrder, Value: 1000, MinSubtotal: 5000},
+	}
+
+	order.ApplyPromotions(promotions, time.Now())
+
+	// 10% of the 2500 subtotal left after buy 2 get 1, spread 200 and 50 over the lines
+	assert.Equal(t, 3500.0, order.TotalNormalPrice)
+	assert.Equal(t, 1250.0, order.Discount)
+	assert.Equal(t, 2250.0, order.TotalPrice)
+	require.NotNil(t, order.PromotionID)
+	assert.Equal(t, uint64(2), *order.PromotionID)
+	assert.Equal(t, 1200.0, order.Products[0].Discount)
+	assert.Equal(t, 1800.0, order.Products[0].TotalPrice)
+	assert.Equal(t, uint64(1), *order.Products[0].PromotionID)
+	assert.Equal(t, 50.0, order.Products[1].Discount)
+	assert.Equal(t, 450.0, order.Products[1].TotalPrice)
+	assert.Nil(t, order.Products[1].PromotionID)
+}
+
+func TestApplyPromotionsSpreadsRounding(t *testing.T) {
+	order := &Order{
+		Products: []OrderProduct{
+			{ProductID: 1, Quantity: 1, Product: &Product{ID: 1, Price: 1}},
+			{ProductID: 2, Quantity: 1, Product: &Product{ID: 2, Price: 1}},
+			{ProductID: 3, Quantity: 1, Product: &Product{ID: 3, Price: 1}},
+		},
+	}
+
+	order.ApplyPromotions([]Promotion{{ID: 1, Active: true, Kind: PromotionFixed, Scope: ScopeOrder, Value: 1}}, time.Now())
+
+	var discount, total float64
+	for _, line := range order.Products {
+		discount += line.Discount
+		total += line.TotalPrice
+	}
+	assert.InDelta(t, 1.0, discount, 1e-9)
+	assert.InDelta(t, 2.0, total, 1e-9)
+	assert.Equal(t, 1.0, order.Discount)
+	assert.Equal(t, 2.0, order.TotalPrice)
+}
+
+func TestApplyPromotionsTimeWindow(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	promotions := []Promotion{
+		{ID: 1, Active: true, Kind: PromotionPercentage, Scope: ScopeOrder, Value: 50, StartsAt: &later},
+		{ID: 2, Active: true, Kind: PromotionPercentage, Scope: ScopeOrder, Value: 40, EndsAt: &now},
+		{ID: 3, Active: false, Kind: PromotionPercentage, Scope: ScopeOrder, Value: 30},
+		{ID: 4, Active: true, Kind: PromotionPercentage, Scope: ScopeOrder, Value: 10, StartsAt: &earlier, EndsAt: &later},
+	}
+
+	order := testCart()
+	order.ApplyPromotions(promotions, now)
+
+	require.NotNil(t, order.PromotionID)
+	assert.Equal(t, uint64(4), *order.PromotionID)
+	assert.Equal(t, 350.0, order.Discount)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Refund is an entity that represents goods returned from an order
+type Refund struct {
+	ID        uint64          `json:"id"`
+	OrderID   uint64          `json:"order_id"`
+	UserID    uint64          `json:"user_id"`
+	ShiftID   *uint64         `json:"shift_id"`
+	Reason    string          `json:"reason"`
+	Amount    float64         `json:"amount"`
+	CreatedAt time.Time       `json:"created_at"`
+	Products  []RefundProduct `json:"products"`
+	Order     *Order          `json:"order"`
+}
+
+// RefundProduct is an entity that represents the quantity of an order line returned by a refund
+type RefundProduct struct {
+	ID             uint64  `json:"id"`
+	RefundID       uint64  `json:"refund_id"`
+	OrderProductID uint64  `json:"order_product_id"`
+	ProductID      uint64  `json:"product_id"`
+	Quantity       int64   `json:"quantity"`
+	Amount         float64 `json:"amount"`
+}
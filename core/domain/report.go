@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// ReportInterval is the length of the periods sales are grouped by
+type ReportInterval string
+
+// ReportInterval enum values
+const (
+	IntervalDay   ReportInterval = "day"
+	IntervalWeek  ReportInterval = "week"
+	IntervalMonth ReportInterval = "month"
+)
+
+// ReportFilter selects the orders a sales report is computed over.
+// Cancelled orders are never counted and refunded quantities are deducted.
+type ReportFilter struct {
+	From       time.Time
+	To         time.Time
+	Timezone   string
+	CategoryID uint64
+}
+
+// PeriodSales is the revenue and number of orders of one day, week or month
+type PeriodSales struct {
+	Period  string  `db:"period"`
+	Orders  int64   `db:"orders"`
+	Items   int64   `db:"items"`
+	Revenue float64 `db:"revenue"`
+}
+
+// ProductSales is the quantity sold and revenue of a product
+type ProductSales struct {
+	ProductID uint64  `db:"product_id"`
+	Name      string  `db:"name"`
+	Quantity  int64   `db:"quantity"`
+	Revenue   float64 `db:"revenue"`
+}
+
+// CashierSales is the number of orders and revenue taken by a cashier
+type CashierSales struct {
+	UserID  uint64  `db:"user_id"`
+	Name    string  `db:"name"`
+	Orders  int64   `db:"orders"`
+	Items   int64   `db:"items"`
+	Revenue float64 `db:"revenue"`
+}
+
+// PaymentSales is the number of orders and revenue paid with a payment type
+type PaymentSales struct {
+	PaymentID uint64      `db:"payment_id"`
+	Name      string      `db:"name"`
+	Type      PaymentType `db:"type"`
+	Orders    int64       `db:"orders"`
+	Revenue   float64     `db:"revenue"`
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is an entity that represents a refresh token issued to a user.
+// Every rotation revokes the current row and inserts its replacement in the same family.
+type Session struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uint64     `json:"user_id" db:"user_id"`
+	FamilyID   uuid.UUID  `json:"family_id" db:"family_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	ClientIP   string     `json:"client_ip" db:"client_ip"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	ReplacedBy *uuid.UUID `json:"replaced_by" db:"replaced_by"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
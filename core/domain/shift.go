@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// Shift is an entity that represents a cashier's working session at the till,
+// from the opening cash float to the counted cash at closing
+type Shift struct {
+	ID           uint64     `json:"id"`
+	UserID       uint64     `json:"user_id"`
+	OpeningFloat float64    `json:"opening_float"`
+	ExpectedCash *float64   `json:"expected_cash"`
+	CountedCash  *float64   `json:"counted_cash"`
+	Variance     *float64   `json:"variance"`
+	Note         *string    `json:"note"`
+	OpenedAt     time.Time  `json:"opened_at"`
+	ClosedAt     *time.Time `json:"closed_at"`
+	ClosedBy     *uint64    `json:"closed_by"`
+}
+
+// IsOpen reports whether the shift has not been closed yet
+func (s *Shift) IsOpen() bool {
+	return s.ClosedAt == nil
+}
+
+// PaymentTotal is the sales and refunds of a payment type over one or more shifts
+type PaymentTotal struct {
+	PaymentID uint64      `db:"payment_id"`
+	Name      string      `db:"name"`
+	Type      PaymentType `db:"type"`
+	Orders    int64       `db:"orders"`
+	Sales     float64     `db:"sales"`
+	Refunds   float64     `db:"refunds"`
+}
+
+// ZReport summarises the sales, refunds and cash reconciliation of one or more shifts.
+// Cancelled orders are left out of the sales of the shift they were taken in.
+// CountedCash and Variance only cover the closed shifts and are nil when none is closed.
+type ZReport struct {
+	Shifts          []Shift
+	Orders          int64
+	GrossSales      float64
+	Cancelled       int64
+	CancelledAmount float64
+	Refunds         int64
+	RefundAmount    float64
+	NetSales        float64
+	Payments        []PaymentTotal
+	OpeningFloat    float64
+	CashSales       float64
+	CashRefunds     float64
+	ExpectedCash    float64
+	CountedCash     *float64
+	Variance        *float64
+}
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// StockSource is an enum for what moved the stock of a product
+type StockSource string
+
+// StockSource enum values
+const (
+	// StockOpening is the stock a product was created with
+	StockOpening StockSource = "opening"
+	// StockSale takes the quantities of an order out of stock
+	StockSale StockSource = "sale"
+	// StockRefund puts the quantities returned by a refund back in stock
+	StockRefund StockSource = "refund"
+	// StockCancellation puts the quantities of a cancelled order not refunded yet back in stock
+	StockCancellation StockSource = "cancellation"
+	// StockAdjustment corrects the stock by a quantity, for damaged, lost or found goods
+	StockAdjustment StockSource = "adjustment"
+	// StockStocktake sets the stock to the quantity counted on the shelves
+	StockStocktake StockSource = "stocktake"
+	// StockReceiving adds the goods received from a supplier
+	StockReceiving StockSource = "receiving"
+)
+
+// IsManual reports whether movements of the source are posted by hand rather than by orders
+func (s StockSource) IsManual() bool {
+	return s == StockAdjustment || s == StockStocktake || s == StockReceiving
+}
+
+// StockMovement is an entity that represents a change of the stock of a product.
+// Quantity is the change, negative when goods went out, and StockAfter the stock it left.
+// OrderID and RefundID reference the order and refund that moved the stock;
+// Reference is a document of a manual movement such as a delivery note number.
+type StockMovement struct {
+	ID         uint64      `json:"id"`
+	ProductID  uint64      `json:"product_id"`
+	Source     StockSource `json:"source"`
+	Quantity   int64       `json:"quantity"`
+	StockAfter int64       `json:"stock_after"`
+	UserID     *uint64     `json:"user_id"`
+	OrderID    *uint64     `json:"order_id"`
+	RefundID   *uint64     `json:"refund_id"`
+	Reference  *string     `json:"reference"`
+	Reason     *string     `json:"reason"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// StockMovementFilter narrows down a list of stock movements; zero values match every movement.
+// The created_at range includes From and excludes To.
+type StockMovementFilter struct {
+	ProductID uint64
+	Source    StockSource
+	From      *time.Time
+	To        *time.Time
+}
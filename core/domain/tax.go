@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"math"
+	"sort"
+)
+
+// TaxRounding is an enum for where the taxes of an order are rounded to cents
+type TaxRounding string
+
+// TaxRounding enum values
+const (
+	// TaxRoundLine rounds the tax of every line
+	TaxRoundLine TaxRounding = "line"
+	// TaxRoundInvoice rounds the tax once per rate over the whole order, then splits it over the lines
+	TaxRoundInvoice TaxRounding = "invoice"
+)
+
+// TaxLine is the tax of an order at one rate
+type TaxLine struct {
+	Rate    float64 `json:"rate"`
+	Taxable float64 `json:"taxable"`
+	Amount  float64 `json:"amount"`
+}
+
+// lineTax is the unrounded tax of a line of an order
+type lineTax struct {
+	index int
+	exact float64
+}
+
+// ApplyTaxes computes the tax of every line at the effective tax rate of its product, after ApplyPromotions.
+// With TaxInclusive the line totals already include the tax, which is worked out of them;
+// otherwise the tax is added to the line totals and so to the total due.
+// TaxRounding decides where the amounts are rounded; the line taxes always add up to TaxAmount.
+// The lines must have their Product loaded.
+func (o *Order) ApplyTaxes() {
+	if o.TaxRounding == "" {
+		o.TaxRounding = TaxRoundLine
+	}
+
+	groups := make(map[float64][]lineTax)
+	var rates []float64
+	for i := range o.Products {
+		line := &o.Products[i]
+		line.TaxRate = 0
+		if line.Product != nil {
+			line.TaxRate = line.Product.EffectiveTaxRate()
+		}
+
+		var exact float64
+		if o.TaxInclusive {
+			exact = line.TotalPrice * line.TaxRate / (100 + line.TaxRate)
+		} else {
+			exact = line.TotalPrice * line.TaxRate / 100
+		}
+
+		if _, ok := groups[line.TaxRate]; !ok {
+			rates = append(rates, line.TaxRate)
+		}
+		groups[line.TaxRate] = append(groups[line.TaxRate], lineTax{i, exact})
+	}
+	sort.Float64s(rates)
+
+	for _, rate := range rates {
+		if o.TaxRounding == TaxRoundInvoice {
+			splitInvoiceTax(o.Products, groups[rate])
+			continue
+		}
+		for _, tax := range groups[rate] {
+			o.Products[tax.index].TaxAmount = roundMoney(tax.exact)
+		}
+	}
+
+	var total, taxAmount float64
+	for i := range o.Products {
+		line := &o.Products[i]
+		if !o.TaxInclusive {
+			line.TotalPrice = roundMoney(line.TotalPrice + line.TaxAmount)
+		}
+		total += line.TotalPrice
+		taxAmount += line.TaxAmount
+	}
+
+	o.TotalPrice = roundMoney(total)
+	o.TaxAmount = roundMoney(taxAmount)
+}
+
+// splitInvoiceTax rounds the total tax of lines at one rate, then splits it over them in cents,
+// the cents left after rounding every line down going to the lines with the largest remainders
+func splitInvoiceTax(lines []OrderProduct, taxes []lineTax) {
+	var exactTotal float64
+	cents := make([]int64, len(taxes))
+	remainders := make([]float64, len(taxes))
+	var allocated int64
+	for i, tax := range taxes {
+		exactTotal += tax.exact
+		cents[i] = int64(math.Floor(tax.exact*100 + 1e-9))
+		remainders[i] = tax.exact*100 - float64(cents[i])
+		allocated += cents[i]
+	}
+
+	order := make([]int, len(taxes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+
+	left := int64(math.Round(exactTotal*100)) - allocated
+	for _, i := range order {
+		if left <= 0 {
+			break
+		}
+		cents[i]++
+		left--
+	}
+
+	for i, tax := range taxes {
+		lines[tax.index].TaxAmount = float64(cents[i]) / 100
+	}
+}
+
+// TaxBreakdown sums the taxed lines of the order per rate, in ascending rate order.
+// Taxable is the amount the tax was computed on, without the tax.
+func (o *Order) TaxBreakdown() []TaxLine {
+	var breakdown []TaxLine
+	byRate := make(map[float64]int)
+	for _, line := range o.Products {
+		if line.TaxRate == 0 {
+			continue
+		}
+		i, ok := byRate[line.TaxRate]
+		if !ok {
+			i = len(breakdown)
+			byRate[line.TaxRate] = i
+			breakdown = append(breakdown, TaxLine{Rate: line.TaxRate})
+		}
+		breakdown[i].Taxable = roundMoney(breakdown[i].Taxable + line.TotalPrice - line.TaxAmount)
+		breakdown[i].Amount = roundMoney(breakdown[i].Amount + line.TaxAmount)
+	}
+
+	sort.Slice(breakdown, func(i, j int) bool { return breakdown[i].Rate < breakdown[j].Rate })
+	return breakdown
+}
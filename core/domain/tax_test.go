@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testNow is the time the tax test orders are priced at
+var testNow = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// testTaxedCart is an order of three lines taxed at 11%, 11% from the category and 0%
+func testTaxedCart(inclusive bool, rounding TaxRounding) *Order {
+	food := &Category{ID: 10, TaxRate: float64Ptr(11)}
+	order := &Order{
+		TaxInclusive: inclusive,
+		TaxRounding:  rounding,
+		Products: []OrderProduct{
+			{ProductID: 1, Quantity: 1, Product: &Product{ID: 1, Price: 10.05, TaxRate: float64Ptr(11), Category: food}},
+			{ProductID: 2, Quantity: 1, Product: &Product{ID: 2, Price: 10.05, Category: food}},
+			{ProductID: 3, Quantity: 2, Product: &Product{ID: 3, Price: 5, TaxRate: float64Ptr(0), Category: food}},
+		},
+	}
+	order.ApplyPromotions(nil, testNow)
+	return order
+}
+
+func TestEffectiveTaxRate(t *testing.T) {
+	category := &Category{TaxRate: float64Ptr(11)}
+
+	assert.Equal(t, 5.0, (&Product{TaxRate: float64Ptr(5), Category: category}).EffectiveTaxRate())
+	assert.Equal(t, 0.0, (&Product{TaxRate: float64Ptr(0), Category: category}).EffectiveTaxRate())
+	assert.Equal(t, 11.0, (&Product{Category: category}).EffectiveTaxRate())
+	assert.Equal(t, 0.0, (&Product{Category: &Category{}}).EffectiveTaxRate())
+	assert.Equal(t, 0.0, (&Product{}).EffectiveTaxRate())
+}
+
+func TestApplyTaxesExclusivePerLine(t *testing.T) {
+	order := testTaxedCart(false, TaxRoundLine)
+
+	order.ApplyTaxes()
+
+	// 11% of 10.05 is 1.1055, rounded on every line
+	assert.Equal(t, 1.11, order.Products[0].TaxAmount)
+	assert.Equal(t, 11.16, order.Products[0].TotalPrice)
+	assert.Equal(t, 1.11, order.Products[1].TaxAmount)
+	assert.Equal(t, 0.0, order.Products[2].TaxAmount)
+	assert.Equal(t, 10.0, order.Products[2].TotalPrice)
+	assert.Equal(t, 2.22, order.TaxAmount)
+	assert.Equal(t, 32.32, order.TotalPrice)
+	assert.Equal(t, 30.1, order.TotalNormalPrice)
+	assert.Equal(t, []TaxLine{{Rate: 11, Taxable: 20.1, Amount: 2.22}}, order.TaxBreakdown())
+}
+
+func TestApplyTaxesExclusivePerInvoice(t *testing.T) {
+	order := testTaxedCart(false, TaxRoundInvoice)
+
+	order.ApplyTaxes()
+
+	// 11% of 20.10 is 2.211, rounded once and split in cents over the lines
+	assert.Equal(t, 2.21, order.TaxAmount)
+	assert.Equal(t, 1.11, order.Products[0].TaxAmount)
+	assert.Equal(t, 1.10, order.Products[1].TaxAmount)
+	assert.Equal(t, 32.31, order.TotalPrice)
+	assert.Equal(t, []TaxLine{{Rate: 11, Taxable: 20.1, Amount: 2.21}}, order.TaxBreakdown())
+}
+
+func TestApplyTaxesInclusive(t *testing.T) {
+	order := testTaxedCart(true, TaxRoundLine)
+
+	order.ApplyTaxes()
+
+	// 10.05 includes 10.05 * 11 / 111 = 0.9959 of tax
+	assert.Equal(t, 1.0, order.Products[0].TaxAmount)
+	assert.Equal(t, 10.05, order.Products[0].TotalPrice)
+	assert.Equal(t, 2.0, order.TaxAmount)
+	assert.Equal(t, 30.1, order.TotalPrice)
+	assert.Equal(t, []TaxLine{{Rate: 11, Taxable: 18.1, Amount: 2}}, order.TaxBreakdown())
+}
+
+func TestApplyTaxesAfterDiscount(t *testing.T) {
+	order := &Order{
+		Products: []OrderProduct{
+			{ProductID: 1, Quantity: 2, Product: &Product{ID: 1, Price: 1000, TaxRate: float64Ptr(10)}},
+		},
+	}
+	order.ApplyPromotions([]Promotion{
+		{ID: 1, Active: true, Kind: PromotionPercentage, Scope: ScopeProduct, TargetID: uint64Ptr(1), Value: 50},
+	}, testNow)
+
+	order.ApplyTaxes()
+
+	assert.Equal(t, TaxRoundLine, order.TaxRounding)
+	assert.Equal(t, 1000.0, order.Discount)
+	assert.Equal(t, 100.0, order.TaxAmount)
+	assert.Equal(t, 1100.0, order.TotalPrice)
+}
+
+func TestApplyTaxesUntaxed(t *testing.T) {
+	order := &Order{
+		Products: []OrderProduct{
+			{ProductID: 1, Quantity: 3, Product: &Product{ID: 1, Price: 1000}},
+		},
+	}
+	order.ApplyPromotions(nil, testNow)
+
+	order.ApplyTaxes()
+
+	assert.Zero(t, order.TaxAmount)
+	assert.Equal(t, 3000.0, order.TotalPrice)
+	assert.Empty(t, order.TaxBreakdown())
+}
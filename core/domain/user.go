@@ -29,11 +29,11 @@ const (
 // id,name,email,password,updated_at,created_time
 // User is an entity that represents a user
 type User struct {
-	ID       uint64 `json:"id" db:"id" select:"-" `
-	Name     string `json:"name" insert:"name" select:"name" insert_pickup:"name"`
-	Email    string `json:"email" insert:"email" select:"email"`
-	Password string `json:"password" insert:"password" select:"password"`
-	//Role        UserRole    `json:"role" db:"role" `
+	ID       uint64   `json:"id" db:"id" select:"-" `
+	Name     string   `json:"name" insert:"name" select:"name" insert_pickup:"name"`
+	Email    string   `json:"email" insert:"email" select:"email"`
+	Password string   `json:"-" insert:"password" select:"password"`
+	Role     UserRole `json:"role" db:"role" insert:"role" select:"role"`
 
 	CreatedAt   zero.String `json:"created_at" db:"created_at" insert:"created_at" select:"-" `
 	UpdatedAt   null.String `json:"updated_at" db:"updated_at" select:"-" `
@@ -41,11 +41,11 @@ type User struct {
 }
 
 type UserDB struct {
-	ID       uint64 `json:"id" db:"id" select:"id" `
-	Name     string `json:"name" insert:"name" select:"name" insert_pickup:"name"`
-	Email    string `json:"email" insert:"email" select:"email"`
-	Password string `json:"password" insert:"password" select:"password"`
-	//Role      UserRole  `json:"role" db:"role" select:"role"`
+	ID       uint64   `json:"id" db:"id" select:"id" `
+	Name     string   `json:"name" insert:"name" select:"name" insert_pickup:"name"`
+	Email    string   `json:"email" insert:"email" select:"email"`
+	Password string   `json:"-" insert:"password"`
+	Role     UserRole `json:"role" db:"role" select:"role"`
 	//CreatedAt   null.Val[string] `json:"created_at" db:"created_at" insert:"created_at"  `
 	CreatedAt   null.String `json:"created_at" db:"created_at" select:"created_at" `
 	UpdatedAt   null.String `json:"updated_at" db:"updated_at" `
@@ -32,6 +32,30 @@ var (
 	ErrUnauthorized = errors.New("user is unauthorized to access the resource")
 	// ErrForbidden is an error for when the user is forbidden to access the resource
 	ErrForbidden = errors.New("user is forbidden to access the resource")
+	// ErrInvalidOrderStatus is an error for when an order cannot move to the requested status
+	ErrInvalidOrderStatus = errors.New("order status does not allow this operation")
+	// ErrRefundExceedsQuantity is an error for when a refund returns more than what is left on an order line
+	ErrRefundExceedsQuantity = errors.New("refund quantity exceeds the quantity left on the order line")
+	// ErrInvalidIdempotencyKey is an error for when the idempotency key header is malformed
+	ErrInvalidIdempotencyKey = errors.New("idempotency key must be between 1 and 255 printable characters")
+	// ErrIdempotencyKeyMismatch is an error for when an idempotency key is reused with a different request
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was already used with a different request")
+	// ErrIdempotencyKeyInUse is an error for when the request first made with an idempotency key is still in progress
+	ErrIdempotencyKeyInUse = errors.New("a request with this idempotency key is already in progress")
+	// ErrNotAcceptable is an error for when none of the formats in the Accept header can be produced
+	ErrNotAcceptable = errors.New("none of the accepted formats can be produced")
+	// ErrShiftAlreadyOpen is an error for when a cashier opens a shift while another one is still open
+	ErrShiftAlreadyOpen = errors.New("cashier already has an open shift")
+	// ErrShiftClosed is an error for when a shift that is already closed is closed again
+	ErrShiftClosed = errors.New("shift is already closed")
+	// ErrInvalidCursor is an error for when a pagination cursor is malformed or was made for another list order
+	ErrInvalidCursor = errors.New("pagination cursor is invalid")
+	// ErrTooManyRequests is an error for when the client exceeded its rate limit
+	ErrTooManyRequests = errors.New("too many requests, please try again later")
+	// ErrImageTooLarge is an error for when an uploaded image is over the size limit
+	ErrImageTooLarge = errors.New("image is larger than the upload size limit")
+	// ErrUnsupportedImage is an error for when an uploaded file is not a JPEG, PNG or GIF image
+	ErrUnsupportedImage = errors.New("image must be a JPEG, PNG or GIF file")
 )
 
 // IsUniqueConstraintViolationError checks if the error is a unique constraint violation error
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+
+	"gotemplate/config"
+	"gotemplate/token"
+
+	"github.com/gin-contrib/pprof"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+const (
+	// swaggerPath is where the Swagger UI is mounted
+	swaggerPath = "/swagger"
+	// pprofPath is where the pprof handlers are mounted
+	pprofPath = "/debug/pprof"
+)
+
+// registerDiagnostics mounts the Swagger UI and pprof handlers enabled in the config.
+// guard returns the middleware protecting the group mounted at the given path.
+func registerDiagnostics(rg *gin.RouterGroup, cfg config.Econfig, guard func(path string) []gin.HandlerFunc) {
+	if cfg.EnableSwagger() {
+		swagger := rg.Group(swaggerPath, guard(swaggerPath)...)
+		swagger.GET("/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+
+	if cfg.EnablePprof() {
+		pprof.RouteRegister(rg.Group("/", guard(pprofPath)...), pprofPath)
+	}
+}
+
+// adminGuard requires an admin access token on the main router
+func adminGuard(tm *token.JWTMaker) func(path string) []gin.HandlerFunc {
+	return func(path string) []gin.HandlerFunc {
+		return []gin.HandlerFunc{authMiddleware(tm), authorize(path)}
+	}
+}
+
+// NewAdminRouter creates the diagnostics router served on AdminPort.
+// It returns nil when AdminPort is not set, in which case the diagnostics
+// are mounted on the main router behind admin authentication instead.
+// The admin port is meant to be reachable from the internal network only.
+func NewAdminRouter(cfg config.Econfig) *Router {
+	if cfg.AdminPort() == "" {
+		return nil
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.NoRoute(func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": []string{"Invalid Path"},
+			"errorno": []string{"INV1"},
+		})
+	})
+
+	registerDiagnostics(&router.RouterGroup, cfg, func(string) []gin.HandlerFunc {
+		return nil
+	})
+
+	return &Router{
+		router,
+	}
+}
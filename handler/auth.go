@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+	"gotemplate/password"
+	repo "gotemplate/repo/postgres"
+	"gotemplate/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AuthHandler represents the HTTP handler for authentication-related requests
+type AuthHandler struct {
+	svc repo.UserRepository
+	ss  repo.SessionRepository
+	tm  *token.JWTMaker
+	log *logger.Logger
+	vs  *ValidatorService
+}
+
+// NewAuthHandler creates a new AuthHandler instance
+func NewAuthHandler(svc repo.UserRepository, ss repo.SessionRepository, tm *token.JWTMaker, log *logger.Logger, vs *ValidatorService) *AuthHandler {
+	return &AuthHandler{
+		svc,
+		ss,
+		tm,
+		log,
+		vs,
+	}
+}
+
+// loginRequest represents the request body for logging in a user
+type loginRequest struct {
+	Email    string `json:"email" validate:"required,email" example:"test@example.com"`
+	Password string `json:"password" validate:"required,min=8" u:"P1" example:"12345678"`
+}
+
+// Login godoc
+//
+//	@Summary		Login and get an access token
+//	@Description	Logs in a registered user and returns an access token and a refresh token if the credentials are valid.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		loginRequest		true	"Login request body"
+//	@Success		200		{object}	authResponse		"Succesfully logged in"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/auth/login [post]
+func (ah *AuthHandler) Login(ctx *gin.Context) {
+	var req loginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ah.vs.handleError(ctx, err)
+		return
+	}
+	if !ah.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	user, ok, err := ah.svc.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		ah.log.Error(err.Error())
+		ah.vs.handledbError(ctx, err)
+		return
+	}
+
+	if !ok {
+		handleAbort(ctx, port.ErrInvalidCredentials, "AUTH1")
+		return
+	}
+
+	match, needsRehash, err := password.Verify(req.Password, user.Password)
+	if errors.Is(err, password.ErrInvalidHash) {
+		// Rows written before passwords were hashed still hold the plain text
+		match = subtle.ConstantTimeCompare([]byte(user.Password), []byte(req.Password)) == 1
+		needsRehash = true
+	} else if err != nil {
+		ah.log.Error(err.Error())
+	}
+	if !match {
+		handleAbort(ctx, port.ErrInvalidCredentials, "AUTH1")
+		return
+	}
+
+	// Upgrade hashes created with outdated parameters while the plain password is at hand
+	if needsRehash {
+		if hashedPassword, err := password.Hash(req.Password); err == nil {
+			if err := ah.svc.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+				ah.log.Error(err.Error())
+			}
+		}
+	}
+
+	refreshToken, refreshHash, err := token.NewRefreshToken()
+	if err != nil {
+		ah.log.Error(err.Error())
+		handleAbort(ctx, err, "AUTH6")
+		return
+	}
+
+	session := domain.Session{
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		TokenHash: refreshHash,
+		UserAgent: ctx.Request.UserAgent(),
+		ClientIP:  ctx.ClientIP(),
+		ExpiresAt: time.Now().Add(ah.tm.RefreshDuration()),
+	}
+
+	_, err = ah.ss.CreateSession(ctx, &session)
+	if err != nil {
+		ah.log.Error(err.Error())
+		ah.vs.handledbError(ctx, err)
+		return
+	}
+
+	accessToken, err := ah.tm.CreateToken(user)
+	if err != nil {
+		ah.log.Error(err.Error())
+		handleAbort(ctx, err, "AUTH6")
+		return
+	}
+
+	rsp := newAuthResponse(accessToken, refreshToken)
+
+	handleSuccess(ctx, rsp)
+}
+
+// refreshRequest represents the request body for exchanging a refresh token
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required" example:"Zm9vYmFy"`
+}
+
+// Refresh godoc
+//
+//	@Summary		Refresh an access token
+//	@Description	Exchanges a refresh token for a new access token and a new refresh token. The presented refresh token is revoked; presenting it again revokes the whole session.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		refreshRequest		true	"Refresh request body"
+//	@Success		200		{object}	authResponse		"Token refreshed"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/auth/refresh [post]
+func (ah *AuthHandler) Refresh(ctx *gin.Context) {
+	var req refreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ah.vs.handleError(ctx, err)
+		return
+	}
+	if !ah.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	refreshToken, refreshHash, err := token.NewRefreshToken()
+	if err != nil {
+		ah.log.Error(err.Error())
+		handleAbort(ctx, err, "AUTH6")
+		return
+	}
+
+	next := domain.Session{
+		TokenHash: refreshHash,
+		UserAgent: ctx.Request.UserAgent(),
+		ClientIP:  ctx.ClientIP(),
+		ExpiresAt: time.Now().Add(ah.tm.RefreshDuration()),
+	}
+
+	session, err := ah.ss.RotateSession(ctx, token.HashRefreshToken(req.RefreshToken), &next)
+	if err != nil {
+		ah.log.Error(err.Error())
+		ah.vs.handledbError(ctx, err)
+		return
+	}
+
+	user, ok, err := ah.svc.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		ah.log.Error(err.Error())
+		ah.vs.handledbError(ctx, err)
+		return
+	}
+	if !ok {
+		handleAbort(ctx, port.ErrInvalidToken, "AUTH5")
+		return
+	}
+
+	accessToken, err := ah.tm.CreateToken(user)
+	if err != nil {
+		ah.log.Error(err.Error())
+		handleAbort(ctx, err, "AUTH6")
+		return
+	}
+
+	rsp := newAuthResponse(accessToken, refreshToken)
+
+	handleSuccess(ctx, rsp)
+}
+
+// ListSessions godoc
+//
+//	@Summary		List active sessions
+//	@Description	List the active refresh token sessions of the logged in user
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]sessionResponse	"Sessions displayed"
+//	@Failure		401	{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/auth/sessions [get]
+//	@Security		BearerAuth
+func (ah *AuthHandler) ListSessions(ctx *gin.Context) {
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	sessions, err := ah.ss.ListSessions(ctx, authPayload.UserID)
+	if err != nil {
+		ah.log.Error(err.Error())
+		ah.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newSessionResponses(sessions)
+
+	handleSuccess(ctx, rsp)
+}
+
+// revokeSessionRequest represents the request uri for revoking a session
+type revokeSessionRequest struct {
+	ID string `uri:"id" validate:"required,uuid" example:"4979cf6e-d215-4ff8-9d0d-b3e99bcc7750"`
+}
+
+// RevokeSession godoc
+//
+//	@Summary		Revoke a session
+//	@Description	Revoke one of the logged in user's sessions so its refresh token can no longer be used
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string				true	"Session ID"
+//	@Success		200	{object}	Response			"Session revoked"
+//	@Failure		400	{object}	errorValidResponse	"Validation error"
+//	@Failure		401	{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404	{object}	errorValidResponse	"Data not found error"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/auth/sessions/{id} [delete]
+//	@Security		BearerAuth
+func (ah *AuthHandler) RevokeSession(ctx *gin.Context) {
+	var req revokeSessionRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ah.vs.handleError(ctx, err)
+		return
+	}
+	if !ah.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	err := ah.ss.RevokeSession(ctx, authPayload.UserID, req.ID)
+	if err != nil {
+		ah.log.Error(err.Error())
+		ah.vs.handledbError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, nil)
+}
+
+// revokeUserSessionsRequest represents the request uri for revoking every session of a user
+type revokeUserSessionsRequest struct {
+	ID uint64 `uri:"id" validate:"required,min=1" example:"1"`
+}
+
+// RevokeUserSessions godoc
+//
+//	@Summary		Revoke all sessions of a user
+//	@Description	Revoke every active session of a user; only admins can call this
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		uint64				true	"User ID"
+//	@Success		200	{object}	Response			"Sessions revoked"
+//	@Failure		400	{object}	errorValidResponse	"Validation error"
+//	@Failure		401	{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403	{object}	errorValidResponse	"Forbidden error"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/users/{id}/sessions [delete]
+//	@Security		BearerAuth
+func (ah *AuthHandler) RevokeUserSessions(ctx *gin.Context) {
+	var req revokeUserSessionsRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ah.vs.handleError(ctx, err)
+		return
+	}
+	if !ah.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	revoked, err := ah.ss.RevokeUserSessions(ctx, req.ID)
+	if err != nil {
+		ah.log.Error(err.Error())
+		ah.vs.handledbError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, gin.H{"revoked": revoked})
+}
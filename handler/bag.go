@@ -178,8 +178,7 @@ func (bh *BagHandler) GetBag(ctx *gin.Context) {
 }
 
 type listBagsRequest struct {
-	Skip  uint64 `form:"skip" validate:"required,min=0" example:"0"`
-	Limit uint64 `form:"limit" validate:"required,min=5" example:"5"`
+	pageRequest
 }
 
 func (ub *BagHandler) ListBags(ctx *gin.Context) {
@@ -194,15 +193,16 @@ func (ub *BagHandler) ListBags(ctx *gin.Context) {
 		return
 	}
 
-	bags, err := ub.svc.GetBags(ctx, req.Skip, req.Limit)
+	params := req.params()
+	bags, page, err := ub.svc.GetBags(ctx, params)
 	if err != nil {
 		ub.log.Error(err.Error())
 		ub.vs.handledbError(ctx, err)
 		return
 	}
 
-	total := uint64(len(bags))
-	meta := newMeta(total, req.Limit, req.Skip)
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
 	rsp := toMap(meta, bags, "bags")
 	handleSuccess(ctx, rsp)
 }
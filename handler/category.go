@@ -27,13 +27,14 @@ func NewCategoryHandler(svc repo.CategoryRepository, log *logger.Logger,vs *Vali
 
 // createCategoryRequest represents a request body for creating a new category
 type createCategoryRequest struct {
-	Name string `json:"name" validate:"required" example:"Foods"`
+	Name    string   `json:"name" validate:"required" example:"Foods"`
+	TaxRate *float64 `json:"tax_rate" validate:"omitempty,min=0,max=100" example:"11"`
 }
 
 // CreateCategory godoc
 //
 //	@Summary		Create a new category
-//	@Description	create a new category with name
+//	@Description	create a new category with name and the tax rate in percent of its products
 //	@Tags			Categories
 //	@Accept			json
 //	@Produce		json
@@ -57,7 +58,8 @@ func (ch *CategoryHandler) CreateCategory(ctx *gin.Context) {
 		return
 	}
 	category := domain.Category{
-		Name: req.Name,
+		Name:    req.Name,
+		TaxRate: req.TaxRate,
 	}
 
 	_, err := ch.svc.CreateCategory(ctx, &category)
@@ -113,19 +115,23 @@ func (ch *CategoryHandler) GetCategory(ctx *gin.Context) {
 
 // listCategoriesRequest represents a request body for listing categories
 type listCategoriesRequest struct {
-	Skip  uint64 `form:"skip" validate:"required,min=0" example:"0"`
-	Limit uint64 `form:"limit" validate:"required,min=5" example:"5"`
+	pageRequest
+	listQueryRequest
 }
 
 // ListCategories godoc
 //
 //	@Summary		List categories
 //	@Description	List categories with pagination
+//	@Description	Filter with filter[field][op]=value, op being eq, ne, gt, gte, lt, lte, like or in, as allowed by the field.
 //	@Tags			Categories
 //	@Accept			json
 //	@Produce		json
-//	@Param			skip	query		uint64			true	"Skip"
-//	@Param			limit	query		uint64			true	"Limit"
+//	@Param			cursor	query		string			false	"Cursor of the next or previous page"
+//	@Param			limit	query		uint64			false	"Page size, 20 by default"
+//	@Param			total	query		string			false	"Count the matching rows"	Enums(exact, estimate)
+//	@Param			sort	query		string			false	"Sort fields, comma separated, descending with a leading -"
+//	@Param			fields	query		string			false	"Fields of the items to return, comma separated"
 //	@Success		200		{object}	categoryResponse			"Categories displayed"
 //	@Failure		400		{object}	errorValidResponse	"Validation error"
 //	@Failure		500		{object}	errorValidResponse	"Internal server error"
@@ -140,29 +146,42 @@ func (ch *CategoryHandler) ListCategories(ctx *gin.Context) {
 	if !ch.vs.handleValidation(ctx, req) {
 		return
 	}
-	categories, err := ch.svc.ListCategories(ctx, req.Skip, req.Limit)
+	lq, fields, ok := ch.vs.handleListQuery(ctx, req.listQueryRequest, repo.CategorySchema)
+	if !ok {
+		return
+	}
+	params := req.params()
+	categories, page, err := ch.svc.ListCategories(ctx, lq, params)
 	if err != nil {
 		ch.log.Error(err.Error())
 		ch.vs.handledbError(ctx, err)
 		return
 	}
 
-	total := uint64(len(categories))
-	meta := newMeta(total, req.Limit, req.Skip)
-	rsp := toMap(meta, categories, "categories")
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
+	items, err := selectFields(categories, fields)
+	if err != nil {
+		ch.log.Error(err.Error())
+		ch.vs.handledbError(ctx, err)
+		return
+	}
+	rsp := toMap(meta, items, "categories")
 
 	handleSuccess(ctx, rsp)
 }
 
 // updateCategoryRequest represents a request body for updating a category
 type updateCategoryRequest struct {
-	Name string `json:"name" validate:"omitempty,required" example:"Beverages"`
+	Name         string   `json:"name" validate:"omitempty,required" example:"Beverages"`
+	TaxRate      *float64 `json:"tax_rate" validate:"omitempty,min=0,max=100,excluded_with=ClearTaxRate" example:"11"`
+	ClearTaxRate bool     `json:"clear_tax_rate" example:"false"`
 }
 
 // UpdateCategory godoc
 //
 //	@Summary		Update a category
-//	@Description	update a category's name by id
+//	@Description	update a category's name and tax rate by id, clear_tax_rate removing the rate
 //	@Tags			Categories
 //	@Accept			json
 //	@Produce		json
@@ -193,11 +212,12 @@ func (ch *CategoryHandler) UpdateCategory(ctx *gin.Context) {
 		return
 	}
 	category := domain.Category{
-		ID:   id,
-		Name: req.Name,
+		ID:      id,
+		Name:    req.Name,
+		TaxRate: req.TaxRate,
 	}
 
-	_, err = ch.svc.UpdateCategory(ctx, &category)
+	_, err = ch.svc.UpdateCategory(ctx, &category, req.ClearTaxRate)
 	if err != nil {
 		ch.log.Error(err.Error())
 		ch.vs.handledbError(ctx, err)
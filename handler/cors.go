@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gotemplate/config"
+
+	"github.com/gin-contrib/cors"
+)
+
+// wildcardOrigin allows requests from every origin
+const wildcardOrigin = "*"
+
+var (
+	// defaultCorsMethods are allowed when HttpAllowedMethods is empty
+	defaultCorsMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	// defaultCorsHeaders are allowed when HttpAllowedHeaders is empty
+	defaultCorsHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", idempotencyKeyHeader}
+	// defaultCorsExposedHeaders are exposed when HttpExposedHeaders is empty
+	defaultCorsExposedHeaders = []string{rateLimitLimitHeader, rateLimitRemainingHeader, rateLimitResetHeader, retryAfterHeader, idempotentReplayedHeader, linkHeader}
+	// defaultCorsMaxAge is used when HttpCorsMaxAge is empty
+	defaultCorsMaxAge = 12 * time.Hour
+)
+
+// originPattern is an allowed origin, either exact or a wildcard subdomain pattern like https://*.example.com
+type originPattern struct {
+	scheme string
+	host   string
+	suffix string
+}
+
+// parseOriginPattern parses one entry of HttpAllowedOrigins
+func parseOriginPattern(origin string) (originPattern, error) {
+	scheme, host, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok || (scheme != "http" && scheme != "https") || host == "" || strings.Contains(host, "/") {
+		return originPattern{}, fmt.Errorf("invalid allowed origin %q: expected http(s)://host[:port]", origin)
+	}
+
+	if !strings.Contains(host, "*") {
+		return originPattern{scheme: scheme, host: host}, nil
+	}
+
+	if !strings.HasPrefix(host, "*.") || strings.Count(host, "*") > 1 || len(host) < 3 {
+		return originPattern{}, fmt.Errorf("invalid allowed origin %q: a wildcard is only allowed as the leftmost label", origin)
+	}
+
+	return originPattern{scheme: scheme, suffix: host[1:]}, nil
+}
+
+// matches reports whether a request origin is allowed by the pattern
+func (p originPattern) matches(origin string) bool {
+	scheme, host, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok || scheme != p.scheme {
+		return false
+	}
+
+	if p.suffix == "" {
+		return host == p.host
+	}
+	return len(host) > len(p.suffix) && strings.HasSuffix(host, p.suffix)
+}
+
+// newCorsConfig builds the CORS policy from the HttpAllowed* config values.
+// It fails when credentials are allowed together with the wildcard origin.
+func newCorsConfig(cfg config.Econfig) (cors.Config, error) {
+	corsConfig := cors.Config{
+		AllowMethods:     splitList(cfg.HttpAllowedMethods(), defaultCorsMethods),
+		AllowHeaders:     splitList(cfg.HttpAllowedHeaders(), defaultCorsHeaders),
+		ExposeHeaders:    splitList(cfg.HttpExposedHeaders(), defaultCorsExposedHeaders),
+		AllowCredentials: cfg.HttpAllowCreds(),
+		MaxAge:           defaultCorsMaxAge,
+	}
+
+	if maxAge := cfg.HttpCorsMaxAge(); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil {
+			return cors.Config{}, fmt.Errorf("invalid HttpCorsMaxAge: %w", err)
+		}
+		corsConfig.MaxAge = d
+	}
+
+	origins := splitList(cfg.HttpAllowedOrigins(), nil)
+	if len(origins) == 0 {
+		return cors.Config{}, errors.New("HttpAllowedOrigins must list at least one origin")
+	}
+
+	patterns := make([]originPattern, 0, len(origins))
+	for _, origin := range origins {
+		if origin == wildcardOrigin {
+			if corsConfig.AllowCredentials {
+				return cors.Config{}, errors.New("HttpAllowCreds cannot be combined with the wildcard origin \"*\"")
+			}
+			corsConfig.AllowAllOrigins = true
+			return corsConfig, nil
+		}
+
+		p, err := parseOriginPattern(origin)
+		if err != nil {
+			return cors.Config{}, err
+		}
+		patterns = append(patterns, p)
+	}
+
+	corsConfig.AllowOriginFunc = func(origin string) bool {
+		for _, p := range patterns {
+			if p.matches(origin) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return corsConfig, corsConfig.Validate()
+}
+
+// splitList splits a comma separated config value, returning def when it is empty
+func splitList(s string, def []string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	if len(list) == 0 {
+		return def
+	}
+	return list
+}
@@ -0,0 +1,361 @@
+package handler
+
+import (
+	"time"
+
+	"gotemplate/core/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// holdOrderRequest represents a request body for holding or re-holding a cart
+type holdOrderRequest struct {
+	Terminal     string                `json:"terminal" validate:"omitempty,max=50" example:"till-1"`
+	CustomerName string                `json:"customer_name" validate:"omitempty,max=255" example:"John Doe"`
+	Note         *string               `json:"note" validate:"omitempty,max=500" example:"Fetching a second bag"`
+	Products     []orderProductRequest `json:"products" validate:"required,min=1,dive"`
+}
+
+// heldOrder converts the request to a held order expiring ttl from now
+func (req holdOrderRequest) heldOrder(ttl time.Duration) domain.HeldOrder {
+	var products []domain.HeldOrderProduct
+	for _, product := range req.Products {
+		products = append(products, domain.HeldOrderProduct{
+			ProductID: product.ProductID,
+			Quantity:  product.Quantity,
+		})
+	}
+
+	return domain.HeldOrder{
+		Terminal:     req.Terminal,
+		CustomerName: req.CustomerName,
+		Note:         req.Note,
+		ExpiresAt:    time.Now().Add(ttl),
+		Products:     products,
+	}
+}
+
+// priceHeldOrder prices the lines of a held order as a cart, writing the error response when it fails
+func (oh *OrderHandler) priceHeldOrder(ctx *gin.Context, held *domain.HeldOrder) (*domain.Order, bool) {
+	cart := domain.Order{
+		TaxInclusive: oh.taxInclusive,
+		TaxRounding:  oh.taxRounding,
+		Products:     held.OrderProducts(),
+	}
+
+	_, err := oh.svc.PriceOrder(ctx, &cart)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return nil, false
+	}
+
+	return &cart, true
+}
+
+// HoldOrder godoc
+//
+//	@Summary		Hold a cart
+//	@Description	Park the lines of a cart to resume the sale later, without taking them out of stock or taking payment.
+//	@Description	The held order expires after the configured HeldOrderTTL.
+//	@Tags			Held orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			holdOrderRequest	body		holdOrderRequest	true	"Hold order request"
+//	@Success		200					{object}	heldOrderResponse	"Cart held"
+//	@Failure		400					{object}	errorValidResponse	"Validation error"
+//	@Failure		401					{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404					{object}	errorValidResponse	"Data not found error"
+//	@Failure		500					{object}	errorValidResponse	"Internal server error"
+//	@Router			/held-orders [post]
+//	@Security		BearerAuth
+func (oh *OrderHandler) HoldOrder(ctx *gin.Context) {
+	var req holdOrderRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	held := req.heldOrder(oh.heldOrderTTL)
+	held.UserID = authPayload.UserID
+
+	_, err := oh.svc.HoldOrder(ctx, &held)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	cart, ok := oh.priceHeldOrder(ctx, &held)
+	if !ok {
+		return
+	}
+
+	rsp := newHeldOrderResponse(&held, cart)
+
+	handleSuccess(ctx, rsp)
+}
+
+// heldOrderIDRequest represents the path of a request on one held order
+type heldOrderIDRequest struct {
+	ID uint64 `uri:"id" validate:"required,min=1" example:"1"`
+}
+
+// GetHeldOrder godoc
+//
+//	@Summary		Resume a held order
+//	@Description	Get an unexpired held order by id with its lines priced at the current prices, promotions and tax rates
+//	@Tags			Held orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		uint64				true	"Held order ID"
+//	@Success		200	{object}	heldOrderResponse	"Held order displayed"
+//	@Failure		400	{object}	errorValidResponse	"Validation error"
+//	@Failure		401	{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404	{object}	errorValidResponse	"Data not found or expired error"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/held-orders/{id} [get]
+//	@Security		BearerAuth
+func (oh *OrderHandler) GetHeldOrder(ctx *gin.Context) {
+	var req heldOrderIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+	held, err := oh.svc.GetHeldOrderByID(ctx, req.ID)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	cart, ok := oh.priceHeldOrder(ctx, held)
+	if !ok {
+		return
+	}
+
+	rsp := newHeldOrderResponse(held, cart)
+
+	handleSuccess(ctx, rsp)
+}
+
+// listHeldOrdersRequest represents the query of a held order listing
+type listHeldOrdersRequest struct {
+	pageRequest
+	Terminal string `form:"terminal" validate:"omitempty,max=50" example:"till-1"`
+	UserID   uint64 `form:"user_id" validate:"omitempty,min=1" example:"1"`
+}
+
+// ListHeldOrders godoc
+//
+//	@Summary		List held orders
+//	@Description	List the unexpired held orders of a terminal or a cashier, the most recently held first
+//	@Tags			Held orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			cursor		query		string				false	"Cursor of the next or previous page"
+//	@Param			limit		query		uint64				false	"Page size, 20 by default"
+//	@Param			total		query		string				false	"Count the matching rows"	Enums(exact, estimate)
+//	@Param			terminal	query		string				false	"Terminal"
+//	@Param			user_id		query		uint64				false	"Cashier ID"
+//	@Success		200			{object}	meta				"Held orders displayed"
+//	@Failure		400			{object}	errorValidResponse	"Validation error"
+//	@Failure		401			{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		500			{object}	errorValidResponse	"Internal server error"
+//	@Router			/held-orders [get]
+//	@Security		BearerAuth
+func (oh *OrderHandler) ListHeldOrders(ctx *gin.Context) {
+	var req listHeldOrdersRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+	params := req.params()
+	helds, page, err := oh.svc.ListHeldOrders(ctx, domain.HeldOrderFilter{
+		UserID:   req.UserID,
+		Terminal: req.Terminal,
+	}, params)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
+	rsp := toMap(meta, newHeldOrderResponses(helds), "held_orders")
+
+	handleSuccess(ctx, rsp)
+}
+
+// UpdateHeldOrder godoc
+//
+//	@Summary		Update a held order
+//	@Description	Replace the lines and details of an unexpired held order and hold it again for the configured HeldOrderTTL
+//	@Tags			Held orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		uint64				true	"Held order ID"
+//	@Param			holdOrderRequest	body		holdOrderRequest	true	"Hold order request"
+//	@Success		200					{object}	heldOrderResponse	"Held order updated"
+//	@Failure		400					{object}	errorValidResponse	"Validation error"
+//	@Failure		401					{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404					{object}	errorValidResponse	"Data not found or expired error"
+//	@Failure		500					{object}	errorValidResponse	"Internal server error"
+//	@Router			/held-orders/{id} [put]
+//	@Security		BearerAuth
+func (oh *OrderHandler) UpdateHeldOrder(ctx *gin.Context) {
+	var uri heldOrderIDRequest
+	var req holdOrderRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, uri) {
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	held := req.heldOrder(oh.heldOrderTTL)
+	held.ID = uri.ID
+
+	_, err := oh.svc.UpdateHeldOrder(ctx, &held)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	cart, ok := oh.priceHeldOrder(ctx, &held)
+	if !ok {
+		return
+	}
+
+	rsp := newHeldOrderResponse(&held, cart)
+
+	handleSuccess(ctx, rsp)
+}
+
+// DeleteHeldOrder godoc
+//
+//	@Summary		Discard a held order
+//	@Description	Delete an unexpired held order by id
+//	@Tags			Held orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		uint64				true	"Held order ID"
+//	@Success		200	{object}	Response			"Held order discarded"
+//	@Failure		400	{object}	errorValidResponse	"Validation error"
+//	@Failure		401	{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404	{object}	errorValidResponse	"Data not found or expired error"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/held-orders/{id} [delete]
+//	@Security		BearerAuth
+func (oh *OrderHandler) DeleteHeldOrder(ctx *gin.Context) {
+	var req heldOrderIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+	err := oh.svc.DeleteHeldOrder(ctx, req.ID)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, nil)
+}
+
+// completeHeldOrderRequest represents a request body for converting a held order to an order
+type completeHeldOrderRequest struct {
+	ID           uint64   `uri:"id" validate:"required,min=1" example:"1"`
+	PaymentID    uint64   `json:"payment_id" validate:"required" example:"1"`
+	CustomerName string   `json:"customer_name" validate:"omitempty,max=255" example:"John Doe"`
+	TotalPaid    *float64 `json:"total_paid" validate:"required,min=0" example:"100000"`
+}
+
+// CompleteHeldOrder godoc
+//
+//	@Summary		Complete a held order
+//	@Description	Create an order from the lines of an unexpired held order, as creating an order does, and delete the held order.
+//	@Description	The customer name of the held order is used when none is given.
+//	@Tags			Held orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			Idempotency-Key				header		string						false	"Key making retries of the request safe"
+//	@Param			id							path		uint64						true	"Held order ID"
+//	@Param			completeHeldOrderRequest	body		completeHeldOrderRequest	true	"Complete held order request"
+//	@Success		200							{object}	orderResponse				"Order created"
+//	@Failure		400							{object}	errorValidResponse			"Validation error, insufficient stock or payment"
+//	@Failure		401							{object}	errorValidResponse			"Unauthorized error"
+//	@Failure		404							{object}	errorValidResponse			"Data not found or expired error"
+//	@Failure		409							{object}	errorValidResponse			"Data conflict error or idempotency key in use"
+//	@Failure		422							{object}	errorValidResponse			"Idempotency key reused with a different request"
+//	@Failure		500							{object}	errorValidResponse			"Internal server error"
+//	@Router			/held-orders/{id}/complete [post]
+//	@Security		BearerAuth
+func (oh *OrderHandler) CompleteHeldOrder(ctx *gin.Context) {
+	var req completeHeldOrderRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	order := domain.Order{
+		UserID:       authPayload.UserID,
+		PaymentID:    req.PaymentID,
+		CustomerName: req.CustomerName,
+		TotalPaid:    *req.TotalPaid,
+		TaxInclusive: oh.taxInclusive,
+		TaxRounding:  oh.taxRounding,
+	}
+
+	idem := getIdempotencyKey(ctx, func() any {
+		return newOrderResponse(&order)
+	})
+
+	_, err := oh.svc.CompleteHeldOrder(ctx, req.ID, &order, idem)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newOrderResponse(&order)
+
+	handleSuccess(ctx, rsp)
+}
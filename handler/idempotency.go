@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"gotemplate/config"
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+	repo "gotemplate/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// idempotencyKeyHeader is the request header carrying the idempotency key
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks a response replayed from a stored idempotency key
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// idempotencyKeyContextKey is the key for the idempotency key in the context
+	idempotencyKeyContextKey = "idempotency_key"
+	// maxIdempotencyKeyLength is the longest accepted idempotency key
+	maxIdempotencyKeyLength = 255
+	// defaultIdempotencyKeyTTL is used when IdempotencyKeyTTL is empty
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+)
+
+/**
+ * Idempotency replays the stored response of requests retried
+ * with the same Idempotency-Key header
+ */
+type Idempotency struct {
+	svc repo.IdempotencyRepository
+	ttl time.Duration
+	log *logger.Logger
+}
+
+// NewIdempotency creates a new Idempotency instance with the key lifetime from the config
+func NewIdempotency(svc repo.IdempotencyRepository, cfg config.Econfig, log *logger.Logger) (*Idempotency, error) {
+	ttl := defaultIdempotencyKeyTTL
+	if s := cfg.IdempotencyKeyTTL(); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IdempotencyKeyTTL: %w", err)
+		}
+		ttl = d
+	}
+
+	return &Idempotency{
+		svc,
+		ttl,
+		log,
+	}, nil
+}
+
+// Middleware replays the stored response when the Idempotency-Key of the request was already used.
+// Otherwise it puts the key in the context for the handler to store with its response.
+// It must run after authMiddleware. Keys are scoped by user and bound to the method, URL path and body
+// of their first request, so that reusing one on another resource, such as another held order, is a mismatch.
+func (i *Idempotency) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			handleAbort(ctx, port.ErrInvalidIdempotencyKey, "IDM1")
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			handleAbort(ctx, err, "IDM2")
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		payload := getAuthPayload(ctx, authorizationPayloadKey)
+		idem := &domain.IdempotencyKey{
+			Key:         key,
+			UserID:      payload.UserID,
+			Path:        ctx.Request.URL.Path,
+			RequestHash: hashRequest(ctx.Request.Method, ctx.Request.URL.Path, body),
+			ExpiresAt:   time.Now().Add(i.ttl),
+		}
+
+		stored, err := i.svc.GetIdempotencyKey(ctx, idem.UserID, idem.Key)
+		if err != nil && !errors.Is(err, port.ErrDataNotFound) {
+			i.log.Error(err.Error())
+			handleAbort(ctx, err, "IDM2")
+			return
+		}
+
+		if stored != nil {
+			if stored.RequestHash != idem.RequestHash {
+				handleAbort(ctx, port.ErrIdempotencyKeyMismatch, "IDM3")
+				return
+			}
+
+			ctx.Header(idempotentReplayedHeader, "true")
+			ctx.Data(stored.StatusCode, "application/json; charset=utf-8", stored.Response)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set(idempotencyKeyContextKey, idem)
+		ctx.Next()
+	}
+}
+
+// getIdempotencyKey returns the idempotency key of the request, or nil when there is none.
+// build is rendered into the success response stored with the key.
+func getIdempotencyKey(ctx *gin.Context, build func() any) *domain.IdempotencyKey {
+	value, ok := ctx.Get(idempotencyKeyContextKey)
+	if !ok {
+		return nil
+	}
+
+	idem := value.(*domain.IdempotencyKey)
+	idem.Render = func() (int, []byte, error) {
+		body, err := json.Marshal(newResponse(true, "Success", build()))
+		return http.StatusOK, body, err
+	}
+	return idem
+}
+
+// validIdempotencyKey checks the length and characters of an idempotency key
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// hashRequest hashes the method, URL path and body of a request.
+// JSON bodies are hashed in a canonical form so formatting differences do not matter.
+func hashRequest(method, path string, body []byte) string {
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"gotemplate/config"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+	"gotemplate/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultImageMaxBytes is the upload size limit when ImageMaxBytes is not configured
+	defaultImageMaxBytes = 5 << 20
+	// defaultImageThumbSize is the thumbnail size when ImageThumbSize is not configured
+	defaultImageThumbSize = 200
+	// multipartOverhead is the room left in upload request bodies for the multipart framing around the file
+	multipartOverhead = 64 << 10
+)
+
+// ImageUploader stores the images uploaded with multipart forms along with their thumbnails
+type ImageUploader struct {
+	store     storage.Store
+	maxBytes  int64
+	thumbSize int
+	log       *logger.Logger
+}
+
+// NewImageUploader creates a new ImageUploader instance storing images in store
+func NewImageUploader(cfg config.Econfig, store storage.Store, log *logger.Logger) (*ImageUploader, error) {
+	maxBytes, thumbSize := cfg.ImageMaxBytes(), cfg.ImageThumbSize()
+	if maxBytes < 0 || thumbSize < 0 {
+		return nil, fmt.Errorf("invalid image upload size limit %d or thumbnail size %d", maxBytes, thumbSize)
+	}
+	if maxBytes == 0 {
+		maxBytes = defaultImageMaxBytes
+	}
+	if thumbSize == 0 {
+		thumbSize = defaultImageThumbSize
+	}
+
+	return &ImageUploader{
+		store:     store,
+		maxBytes:  maxBytes,
+		thumbSize: thumbSize,
+		log:       log,
+	}, nil
+}
+
+// bind binds the multipart form of the request into obj, its body limited to what an image under the size limit needs.
+// A larger body fails with port.ErrImageTooLarge.
+func (iu *ImageUploader) bind(ctx *gin.Context, obj any) error {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, iu.maxBytes+multipartOverhead)
+
+	err := ctx.ShouldBind(obj)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return port.ErrImageTooLarge
+	}
+	return err
+}
+
+// save checks the uploaded image and stores it with its thumbnail under prefix, returning their URLs
+func (iu *ImageUploader) save(ctx *gin.Context, file *multipart.FileHeader, prefix string) (string, string, error) {
+	if file.Size > iu.maxBytes {
+		return "", "", port.ErrImageTooLarge
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, iu.maxBytes+1))
+	if err != nil {
+		return "", "", err
+	}
+	if int64(len(data)) > iu.maxBytes {
+		return "", "", port.ErrImageTooLarge
+	}
+
+	img, err := storage.ProcessImage(data, iu.thumbSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Every upload gets new names, so that caches never serve a replaced image
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	name := prefix + "/" + hex.EncodeToString(id)
+
+	url, err := iu.store.Put(ctx.Request.Context(), name+img.Ext, img.Data, img.ContentType)
+	if err != nil {
+		return "", "", err
+	}
+	thumbnail, err := iu.store.Put(ctx.Request.Context(), name+"-thumb"+img.ThumbnailExt, img.Thumbnail, img.ThumbnailType)
+	if err != nil {
+		iu.discard(url)
+		return "", "", err
+	}
+
+	return url, thumbnail, nil
+}
+
+// discard deletes stored files no longer referenced, logging rather than failing the request when it cannot.
+// Empty URLs and URLs the store did not hand out are skipped.
+func (iu *ImageUploader) discard(urls ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		if err := iu.store.Delete(ctx, url); err != nil {
+			iu.log.Warn("unable to delete stored file %s: %s", url, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"strings"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// authorizationHeaderKey is the key for authorization header in the request
+	authorizationHeaderKey = "authorization"
+	// authorizationType is the accepted authorization type
+	authorizationType = "bearer"
+	// authorizationPayloadKey is the key for authorization payload in the context
+	authorizationPayloadKey = "authorization_payload"
+)
+
+// authMiddleware is a middleware to check if the request carries a valid bearer token
+func authMiddleware(tm *token.JWTMaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+
+		if len(authorizationHeader) == 0 {
+			handleAbort(ctx, port.ErrEmptyAuthorizationHeader, "AUTH2")
+			return
+		}
+
+		fields := strings.Fields(authorizationHeader)
+		if len(fields) != 2 {
+			handleAbort(ctx, port.ErrInvalidAuthorizationHeader, "AUTH3")
+			return
+		}
+
+		currentAuthorizationType := strings.ToLower(fields[0])
+		if currentAuthorizationType != authorizationType {
+			handleAbort(ctx, port.ErrInvalidAuthorizationType, "AUTH4")
+			return
+		}
+
+		payload, err := tm.VerifyToken(fields[1])
+		if err != nil {
+			handleAbort(ctx, err, "AUTH5")
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// getAuthPayload is a helper function to get the auth payload set by authMiddleware
+func getAuthPayload(ctx *gin.Context, key string) *domain.TokenPayload {
+	return ctx.MustGet(key).(*domain.TokenPayload)
+}
@@ -1,7 +1,13 @@
 package handler
 
 import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"gotemplate/config"
 	"gotemplate/core/domain"
+	"gotemplate/core/port"
 	"gotemplate/logger"
 	repo "gotemplate/repo/postgres"
 
@@ -10,48 +16,89 @@ import (
 
 // OrderHandler represents the HTTP handler for order-related requests
 type OrderHandler struct {
-	svc repo.OrderRepository
-	log *logger.Logger
-	vs *ValidatorService
+	svc          repo.OrderRepository
+	log          *logger.Logger
+	vs           *ValidatorService
+	taxInclusive bool
+	taxRounding  domain.TaxRounding
+	heldOrderTTL time.Duration
 }
 
-// NewOrderHandler creates a new OrderHandler instance
-func NewOrderHandler(svc repo.OrderRepository,log *logger.Logger,vs *ValidatorService) *OrderHandler {
+// defaultHeldOrderTTL is used when HeldOrderTTL is empty
+const defaultHeldOrderTTL = 8 * time.Hour
+
+// NewOrderHandler creates a new OrderHandler instance taxing orders with the TaxPriceMode and TaxRounding
+// from config, exclusive of tax and rounded per line by default, and holding carts for HeldOrderTTL
+func NewOrderHandler(svc repo.OrderRepository, log *logger.Logger, vs *ValidatorService, cfg config.Econfig) (*OrderHandler, error) {
+	var taxInclusive bool
+	switch cfg.TaxPriceMode() {
+	case "", "exclusive":
+	case "inclusive":
+		taxInclusive = true
+	default:
+		return nil, fmt.Errorf("invalid TaxPriceMode %q, want inclusive or exclusive", cfg.TaxPriceMode())
+	}
+
+	taxRounding := domain.TaxRounding(cfg.TaxRounding())
+	switch taxRounding {
+	case "":
+		taxRounding = domain.TaxRoundLine
+	case domain.TaxRoundLine, domain.TaxRoundInvoice:
+	default:
+		return nil, fmt.Errorf("invalid TaxRounding %q, want line or invoice", cfg.TaxRounding())
+	}
+
+	heldOrderTTL := defaultHeldOrderTTL
+	if s := cfg.HeldOrderTTL(); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HeldOrderTTL: %w", err)
+		}
+		heldOrderTTL = d
+	}
+
 	return &OrderHandler{
 		svc,
 		log,
 		vs,
-	}
+		taxInclusive,
+		taxRounding,
+		heldOrderTTL,
+	}, nil
 }
 
 // orderProductRequest represents an order product request body
 type orderProductRequest struct {
 	ProductID uint64 `json:"product_id" validate:"required,min=1" example:"1"`
-	Quantity  int64  `json:"qty" validate:"required,number" example:"1"`
+	Quantity  int64  `json:"qty" validate:"required,min=1" example:"1"`
 }
 
 // createOrderRequest represents a request body for creating a new order
 type createOrderRequest struct {
 	PaymentID    uint64                `json:"payment_id" validate:"required" example:"1"`
 	CustomerName string                `json:"customer_name" validate:"required" example:"John Doe"`
-	TotalPaid    int64                 `json:"total_paid" validate:"required" example:"100000"`
-	Products     []orderProductRequest `json:"products" validate:"required"`
+	TotalPaid    *float64              `json:"total_paid" validate:"required,min=0" example:"100000"`
+	Products     []orderProductRequest `json:"products" validate:"required,min=1,dive"`
 }
 
 // CreateOrder godoc
 //
 //	@Summary		Create a new order
-//	@Description	Create a new order and return the order data with purchase details
+//	@Description	Create a new order priced from the current product prices, active promotions and tax rates and return the order data with purchase details, tax breakdown and change due
 //	@Tags			Orders
 //	@Accept			json
 //	@Produce		json
+//	@Param			Idempotency-Key		header		string				false	"Key making retries of the request safe"
 //	@Param			createOrderRequest	body		createOrderRequest	true	"Create order request"
 //	@Success		200					{object}	orderResponse		"Order created"
-//	@Failure		400					{object}	errorValidResponse		"Validation error"
+//	@Failure		400					{object}	errorValidResponse		"Validation error, insufficient stock or payment"
+//	@Failure		401					{object}	errorValidResponse		"Unauthorized error"
 //	@Failure		404					{object}	errorValidResponse		"Data not found error"
-//	@Failure		409					{object}	errorValidResponse		"Data conflict error"
+//	@Failure		409					{object}	errorValidResponse		"Data conflict error or idempotency key in use"
+//	@Failure		422					{object}	errorValidResponse		"Idempotency key reused with a different request"
 //	@Failure		500					{object}	errorValidResponse		"Internal server error"
 //	@Router			/orders [post]
+//	@Security		BearerAuth
 func (oh *OrderHandler) CreateOrder(ctx *gin.Context) {
 	var req createOrderRequest
 	var products []domain.OrderProduct
@@ -70,18 +117,23 @@ func (oh *OrderHandler) CreateOrder(ctx *gin.Context) {
 		})
 	}
 
-	//authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
 
 	order := domain.Order{
-		//UserID:       authPayload.UserID,
-		UserID:       123,
+		UserID:       authPayload.UserID,
 		PaymentID:    req.PaymentID,
 		CustomerName: req.CustomerName,
-		TotalPaid:    float64(req.TotalPaid),
+		TotalPaid:    *req.TotalPaid,
+		TaxInclusive: oh.taxInclusive,
+		TaxRounding:  oh.taxRounding,
 		Products:     products,
 	}
 
-	_, err := oh.svc.CreateOrder(ctx, &order)
+	idem := getIdempotencyKey(ctx, func() any {
+		return newOrderResponse(&order)
+	})
+
+	_, err := oh.svc.CreateOrder(ctx, &order, idem)
 	if err != nil {
 		oh.log.Error(err.Error())
 		oh.vs.handledbError(ctx, err)
@@ -93,6 +145,63 @@ func (oh *OrderHandler) CreateOrder(ctx *gin.Context) {
 	handleSuccess(ctx, rsp)
 }
 
+// previewOrderRequest represents a request body for pricing a cart
+type previewOrderRequest struct {
+	Products []orderProductRequest `json:"products" validate:"required,min=1,dive"`
+}
+
+// PreviewOrder godoc
+//
+//	@Summary		Price a cart
+//	@Description	Price a cart from the current product prices, active promotions and tax rates without creating an order,
+//	@Description	checking stock or taking payment
+//	@Tags			Orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			previewOrderRequest	body		previewOrderRequest		true	"Preview order request"
+//	@Success		200					{object}	orderPreviewResponse	"Cart priced"
+//	@Failure		400					{object}	errorValidResponse		"Validation error"
+//	@Failure		401					{object}	errorValidResponse		"Unauthorized error"
+//	@Failure		404					{object}	errorValidResponse		"Data not found error"
+//	@Failure		500					{object}	errorValidResponse		"Internal server error"
+//	@Router			/orders/preview [post]
+//	@Security		BearerAuth
+func (oh *OrderHandler) PreviewOrder(ctx *gin.Context) {
+	var req previewOrderRequest
+	var products []domain.OrderProduct
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+	for _, product := range req.Products {
+		products = append(products, domain.OrderProduct{
+			ProductID: product.ProductID,
+			Quantity:  product.Quantity,
+		})
+	}
+
+	order := domain.Order{
+		TaxInclusive: oh.taxInclusive,
+		TaxRounding:  oh.taxRounding,
+		Products:     products,
+	}
+
+	_, err := oh.svc.PriceOrder(ctx, &order)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newOrderPreviewResponse(&order)
+
+	handleSuccess(ctx, rsp)
+}
+
 // getOrderRequest represents a request body for retrieving an order
 type getOrderRequest struct {
 	ID uint64 `uri:"id" validate:"required,min=1" example:"1"`
@@ -111,6 +220,7 @@ type getOrderRequest struct {
 //	@Failure		404	{object}	errorValidResponse	"Data not found error"
 //	@Failure		500	{object}	errorValidResponse	"Internal server error"
 //	@Router			/orders/{id} [get]
+//	@Security		BearerAuth
 func (oh *OrderHandler) GetOrder(ctx *gin.Context) {
 	var req getOrderRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
@@ -132,26 +242,88 @@ func (oh *OrderHandler) GetOrder(ctx *gin.Context) {
 	handleSuccess(ctx, rsp)
 }
 
-// listOrdersRequest represents a request body for listing orders
+// orderTimeLayout is the format of the created_at range of an order search
+const orderTimeLayout = time.RFC3339
+
+// listOrdersRequest represents the query of an order search
 type listOrdersRequest struct {
-	Skip  uint64 `form:"skip" validate:"required,min=0" example:"0"`
-	Limit uint64 `form:"limit" validate:"required,min=5" example:"5"`
+	pageRequest
+	From              string   `form:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2024-01-01T00:00:00Z"`
+	To                string   `form:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2024-02-01T00:00:00Z"`
+	UserID            uint64   `form:"user_id" validate:"omitempty,min=1" example:"1"`
+	PaymentID         uint64   `form:"payment_id" validate:"omitempty,min=1" example:"1"`
+	CustomerName      string   `form:"customer_name" validate:"omitempty,max=100" example:"john"`
+	MinTotal          *float64 `form:"min_total" validate:"omitempty,min=0" example:"10000"`
+	MaxTotal          *float64 `form:"max_total" validate:"omitempty,min=0" example:"500000"`
+	ReceiptCodePrefix string   `form:"receipt_code" validate:"omitempty,max=36,printascii" example:"4979cf6e"`
+	Sort              string   `form:"sort" validate:"omitempty,oneof=created_at -created_at total_price -total_price" example:"-created_at"`
+}
+
+// filter converts the request to an order filter, checking that the ranges are not inverted,
+// returning the validation messages with their tags when they are
+func (req listOrdersRequest) filter() (domain.OrderFilter, []string, []string) {
+	var messages, tags []string
+	fail := func(message, tag string) {
+		messages = append(messages, message)
+		tags = append(tags, tag)
+	}
+
+	filter := domain.OrderFilter{
+		UserID:            req.UserID,
+		PaymentID:         req.PaymentID,
+		CustomerName:      req.CustomerName,
+		MinTotal:          req.MinTotal,
+		MaxTotal:          req.MaxTotal,
+		ReceiptCodePrefix: req.ReceiptCodePrefix,
+		Sort:              domain.OrderSort(req.Sort),
+	}
+
+	// The layouts were checked by the datetime validation
+	if req.From != "" {
+		from, _ := time.Parse(orderTimeLayout, req.From)
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, _ := time.Parse(orderTimeLayout, req.To)
+		filter.To = &to
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		fail("to must be after from", "gtfield")
+	}
+	if filter.MinTotal != nil && filter.MaxTotal != nil && *filter.MaxTotal < *filter.MinTotal {
+		fail("max_total must be greater than or equal to min_total", "gtefield")
+	}
+
+	return filter, messages, tags
 }
 
 // ListOrders godoc
 //
 //	@Summary		List orders
-//	@Description	List orders and return an array of order data with purchase details
+//	@Description	Search orders and return an array of order data with purchase details.
+//	@Description	The created_at range includes from and excludes to; customer_name matches part of the name, ignoring case.
 //	@Tags			Orders
 //	@Accept			json
 //	@Produce		json
-//	@Param			skip	query		uint64			true	"Skip records"
-//	@Param			limit	query		uint64			true	"Limit records"
-//	@Success		200		{object}	meta			"Orders displayed"
-//	@Failure		400		{object}	errorValidResponse	"Validation error"
-//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
-//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Param			cursor			query		string			false	"Cursor of the next or previous page"
+//	@Param			limit			query		uint64			false	"Page size, 20 by default"
+//	@Param			total			query		string			false	"Count the matching rows"	Enums(exact, estimate)
+//	@Param			from			query		string			false	"Created at or after (RFC 3339)"
+//	@Param			to				query		string			false	"Created before (RFC 3339)"
+//	@Param			user_id			query		uint64			false	"Cashier ID"
+//	@Param			payment_id		query		uint64			false	"Payment type ID"
+//	@Param			customer_name	query		string			false	"Part of the customer name"
+//	@Param			min_total		query		number			false	"Minimum total price"
+//	@Param			max_total		query		number			false	"Maximum total price"
+//	@Param			receipt_code	query		string			false	"Receipt code prefix"
+//	@Param			sort			query		string			false	"Sort order"	Enums(created_at, -created_at, total_price, -total_price)
+//	@Success		200				{object}	meta			"Orders displayed"
+//	@Failure		400				{object}	errorValidResponse	"Validation error"
+//	@Failure		401				{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		500				{object}	errorValidResponse	"Internal server error"
 //	@Router			/orders [get]
+//	@Security		BearerAuth
 func (oh *OrderHandler) ListOrders(ctx *gin.Context) {
 	var req listOrdersRequest
 
@@ -163,7 +335,18 @@ func (oh *OrderHandler) ListOrders(ctx *gin.Context) {
 	if !oh.vs.handleValidation(ctx, req) {
 		return
 	}
-	orders, err := oh.svc.ListOrders(ctx, req.Skip, req.Limit)
+	filter, messages, tags := req.filter()
+	if len(messages) > 0 {
+		codes := make([]string, len(tags))
+		for i, tag := range tags {
+			codes[i] = oh.vs.tagToNumber[tag]
+		}
+		ctx.JSON(http.StatusBadRequest, newErrorValidResponse(messages, codes))
+		return
+	}
+
+	params := req.params()
+	orders, page, err := oh.svc.ListOrders(ctx, filter, params)
 	if err != nil {
 		oh.log.Error(err.Error())
 		oh.vs.handledbError(ctx, err)
@@ -172,9 +355,163 @@ func (oh *OrderHandler) ListOrders(ctx *gin.Context) {
 
 	
 
-	total := uint64(len(orders))
-	meta := newMeta(total, req.Limit, req.Skip)
-	rsp := toMap(meta, orders, "orders")
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
+	rsp := toMap(meta, newOrderResponses(orders), "orders")
+
+	handleSuccess(ctx, rsp)
+}
+
+// cancelOrderRequest represents a request body for cancelling an order
+type cancelOrderRequest struct {
+	ID     uint64 `uri:"id" validate:"required,min=1" example:"1"`
+	Reason string `json:"reason" validate:"required,max=500" example:"Customer changed their mind"`
+}
+
+// canManageOrder reports whether the caller may cancel or refund the order id, writing the error response when not.
+// Cashiers can only cancel and refund the orders they took.
+func (oh *OrderHandler) canManageOrder(ctx *gin.Context, id uint64) bool {
+	payload := getAuthPayload(ctx, authorizationPayloadKey)
+	if payload.Role == domain.Admin {
+		return true
+	}
+
+	order, err := oh.svc.GetOrderByID(ctx, id)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return false
+	}
+	if order.UserID != payload.UserID {
+		handleAbort(ctx, port.ErrForbidden, "ORD1")
+		return false
+	}
+	return true
+}
+
+// CancelOrder godoc
+//
+//	@Summary		Cancel an order
+//	@Description	Cancel a pending or paid order, put its items back in stock and record who cancelled it and why.
+//	@Description	Cashiers can only cancel the orders they took.
+//	@Tags			Orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		uint64				true	"Order ID"
+//	@Param			cancelOrderRequest	body		cancelOrderRequest	true	"Cancel order request"
+//	@Success		200					{object}	orderResponse		"Order cancelled"
+//	@Failure		400					{object}	errorValidResponse	"Validation error"
+//	@Failure		401					{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403					{object}	errorValidResponse	"Order of another cashier"
+//	@Failure		404					{object}	errorValidResponse	"Data not found error"
+//	@Failure		409					{object}	errorValidResponse	"Order status does not allow cancellation"
+//	@Failure		500					{object}	errorValidResponse	"Internal server error"
+//	@Router			/orders/{id}/cancel [post]
+//	@Security		BearerAuth
+func (oh *OrderHandler) CancelOrder(ctx *gin.Context) {
+	var req cancelOrderRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+	if !oh.canManageOrder(ctx, req.ID) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	order, err := oh.svc.CancelOrder(ctx, req.ID, authPayload.UserID, req.Reason)
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newOrderResponse(order)
+
+	handleSuccess(ctx, rsp)
+}
+
+// refundProductRequest represents an order line returned by a refund request
+type refundProductRequest struct {
+	OrderProductID uint64 `json:"order_product_id" validate:"required,min=1" example:"1"`
+	Quantity       int64  `json:"qty" validate:"required,min=1" example:"1"`
+}
+
+// refundOrderRequest represents a request body for refunding an order
+type refundOrderRequest struct {
+	ID       uint64                 `uri:"id" validate:"required,min=1" example:"1"`
+	Reason   string                 `json:"reason" validate:"required,max=500" example:"Damaged item"`
+	Products []refundProductRequest `json:"products" validate:"omitempty,dive"`
+}
+
+// RefundOrder godoc
+//
+//	@Summary		Refund an order
+//	@Description	Refund some or, when no products are given, all remaining lines of an order and put the returned items back in stock.
+//	@Description	Cashiers can only refund the orders they took.
+//	@Tags			Orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		uint64				true	"Order ID"
+//	@Param			refundOrderRequest	body		refundOrderRequest	true	"Refund order request"
+//	@Success		200					{object}	refundResponse		"Order refunded"
+//	@Failure		400					{object}	errorValidResponse	"Validation error or refund quantity too large"
+//	@Failure		401					{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403					{object}	errorValidResponse	"Order of another cashier"
+//	@Failure		404					{object}	errorValidResponse	"Data not found error"
+//	@Failure		409					{object}	errorValidResponse	"Order status does not allow a refund"
+//	@Failure		500					{object}	errorValidResponse	"Internal server error"
+//	@Router			/orders/{id}/refunds [post]
+//	@Security		BearerAuth
+func (oh *OrderHandler) RefundOrder(ctx *gin.Context) {
+	var req refundOrderRequest
+	var products []domain.RefundProduct
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		oh.vs.handleError(ctx, err)
+		return
+	}
+	if !oh.vs.handleValidation(ctx, req) {
+		return
+	}
+	if !oh.canManageOrder(ctx, req.ID) {
+		return
+	}
+	for _, product := range req.Products {
+		products = append(products, domain.RefundProduct{
+			OrderProductID: product.OrderProductID,
+			Quantity:       product.Quantity,
+		})
+	}
+
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	refund, err := oh.svc.RefundOrder(ctx, &domain.Refund{
+		OrderID:  req.ID,
+		UserID:   authPayload.UserID,
+		Reason:   req.Reason,
+		Products: products,
+	})
+	if err != nil {
+		oh.log.Error(err.Error())
+		oh.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newRefundResponse(refund)
 
 	handleSuccess(ctx, rsp)
 }
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	repo "gotemplate/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultPageLimit is the page size of list requests without a limit
+	defaultPageLimit = 20
+	// linkHeader is the response header carrying the links to the next and previous pages
+	linkHeader = "Link"
+)
+
+// pageRequest represents the pagination query of a list request.
+// Cursor is the next_cursor or prev_cursor of a previous page, the first page being listed without one;
+// total asks for the exact or the estimated number of matching rows.
+type pageRequest struct {
+	Cursor string `form:"cursor" validate:"omitempty,max=1024,printascii" example:"eyJvIjoiaWQiLCJrIjpbIjIwIl19"`
+	Limit  uint64 `form:"limit" validate:"omitempty,min=1,max=100" example:"20"`
+	Total  string `form:"total" validate:"omitempty,oneof=exact estimate" example:"exact"`
+}
+
+// params converts the request to the page parameters of a repository list
+func (req pageRequest) params() repo.PageParams {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+
+	return repo.PageParams{
+		Cursor: req.Cursor,
+		Limit:  limit,
+		Total:  repo.TotalMode(req.Total),
+	}
+}
+
+// setLinkHeader sets the RFC 8288 Link header of a page to the request URL with the cursors of the next and previous pages
+func setLinkHeader(ctx *gin.Context, page repo.Page) {
+	var links []string
+	for _, link := range []struct{ rel, cursor string }{{"next", page.Next}, {"prev", page.Prev}} {
+		if link.cursor == "" {
+			continue
+		}
+
+		query := ctx.Request.URL.Query()
+		query.Set("cursor", link.cursor)
+		target := url.URL{Path: ctx.Request.URL.Path, RawQuery: query.Encode()}
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, target.String(), link.rel))
+	}
+
+	if len(links) > 0 {
+		ctx.Header(linkHeader, strings.Join(links, ", "))
+	}
+}
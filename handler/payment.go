@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"fmt"
+	"mime/multipart"
+
 	"gotemplate/core/domain"
 	"gotemplate/logger"
 
@@ -14,14 +17,16 @@ type PaymentHandler struct {
 	svc repo.PaymentRepository
 	log *logger.Logger
 	vs *ValidatorService
+	images *ImageUploader
 }
 
 // NewPaymentHandler creates a new PaymentHandler instance
-func NewPaymentHandler(svc repo.PaymentRepository, log *logger.Logger,vs *ValidatorService) *PaymentHandler {
+func NewPaymentHandler(svc repo.PaymentRepository, log *logger.Logger,vs *ValidatorService, images *ImageUploader) *PaymentHandler {
 	return &PaymentHandler{
 		svc,
 		log,
 		vs,
+		images,
 	}
 }
 
@@ -39,13 +44,15 @@ type createPaymentRequest struct {
 //	@Tags			Payments
 //	@Accept			json
 //	@Produce		json
+//	@Param			Idempotency-Key			header		string					false	"Key making retries of the request safe"
 //	@Param			createPaymentRequest	body		createPaymentRequest	true	"Create payment request"
 //	@Success		200						{object}	paymentResponse			"Payment created"
 //	@Failure		400						{object}	errorResponse			"Validation error"
 //	@Failure		401						{object}	errorResponse			"Unauthorized error"
 //	@Failure		403						{object}	errorResponse			"Forbidden error"
 //	@Failure		404						{object}	errorResponse			"Data not found error"
-//	@Failure		409						{object}	errorResponse			"Data conflict error"
+//	@Failure		409						{object}	errorResponse			"Data conflict error or idempotency key in use"
+//	@Failure		422						{object}	errorResponse			"Idempotency key reused with a different request"
 //	@Failure		500						{object}	errorResponse			"Internal server error"
 //	@Router			/payments [post]
 func (ph *PaymentHandler) CreatePayment(ctx *gin.Context) {
@@ -63,7 +70,11 @@ func (ph *PaymentHandler) CreatePayment(ctx *gin.Context) {
 		Logo: req.Logo,
 	}
 
-	_, err := ph.svc.CreatePayment(ctx, &payment)
+	idem := getIdempotencyKey(ctx, func() any {
+		return newPaymentResponse(&payment)
+	})
+
+	_, err := ph.svc.CreatePayment(ctx, &payment, idem)
 	if err != nil {
 		ph.log.Error(err.Error())
 		ph.vs.handledbError(ctx, err)
@@ -117,19 +128,23 @@ func (ph *PaymentHandler) GetPayment(ctx *gin.Context) {
 
 // listPaymentsRequest represents a request body for listing payments
 type listPaymentsRequest struct {
-	Skip  uint64 `form:"skip" validate:"required,min=0" example:"0"`
-	Limit uint64 `form:"limit" validate:"required,min=5" example:"5"`
+	pageRequest
+	listQueryRequest
 }
 
 // ListPayments godoc
 //
 //	@Summary		List payments
 //	@Description	List payments with pagination
+//	@Description	Filter with filter[field][op]=value, op being eq, ne, gt, gte, lt, lte, like or in, as allowed by the field.
 //	@Tags			Payments
 //	@Accept			json
 //	@Produce		json
-//	@Param			skip	query		uint64			true	"Skip"
-//	@Param			limit	query		uint64			true	"Limit"
+//	@Param			cursor	query		string			false	"Cursor of the next or previous page"
+//	@Param			limit	query		uint64			false	"Page size, 20 by default"
+//	@Param			total	query		string			false	"Count the matching rows"	Enums(exact, estimate)
+//	@Param			sort	query		string			false	"Sort fields, comma separated, descending with a leading -"
+//	@Param			fields	query		string			false	"Fields of the items to return, comma separated"
 //	@Success		200		{object}	meta			"Payments displayed"
 //	@Failure		400		{object}	errorValidResponse	"Validation error"
 //	@Failure		500		{object}	errorValidResponse	"Internal server error"
@@ -145,16 +160,27 @@ func (ph *PaymentHandler) ListPayments(ctx *gin.Context) {
 	if !ph.vs.handleValidation(ctx, req) {
 		return
 	}
-	payments, err := ph.svc.ListPayments(ctx, req.Skip, req.Limit)
+	lq, fields, ok := ph.vs.handleListQuery(ctx, req.listQueryRequest, repo.PaymentSchema)
+	if !ok {
+		return
+	}
+	params := req.params()
+	payments, page, err := ph.svc.ListPayments(ctx, lq, params)
 	if err != nil {
 		ph.log.Error(err.Error())
 		ph.vs.handledbError(ctx, err)
 		return
 	}
 
-	total := uint64(len(payments))
-	meta := newMeta(total, req.Limit, req.Skip)
-	rsp := toMap(meta, payments, "payments")
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
+	items, err := selectFields(payments, fields)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+	rsp := toMap(meta, items, "payments")
 
 	handleSuccess(ctx, rsp)
 }
@@ -256,3 +282,75 @@ func (ph *PaymentHandler) DeletePayment(ctx *gin.Context) {
 
 	handleSuccess(ctx, nil)
 }
+
+// uploadPaymentLogoRequest represents a multipart request uploading the logo of a payment
+type uploadPaymentLogoRequest struct {
+	ID   uint64                `uri:"id" validate:"required,min=1" example:"1"`
+	Logo *multipart.FileHeader `json:"logo" form:"logo" validate:"required" swaggerignore:"true"`
+}
+
+// UploadPaymentLogo godoc
+//
+//	@Summary		Upload a payment logo
+//	@Description	replace the logo of a payment with an uploaded JPEG, PNG or GIF file, which a thumbnail is made of.
+//	@Description	The logo and logo thumbnail URLs of the payment point to the stored files.
+//	@Tags			Payments
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			id		path		uint64				true	"Payment ID"
+//	@Param			logo	formData	file				true	"Logo file"
+//	@Success		200		{object}	paymentResponse		"Payment logo uploaded"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403		{object}	errorValidResponse	"Forbidden error"
+//	@Failure		404		{object}	errorValidResponse	"Data not found error"
+//	@Failure		413		{object}	errorValidResponse	"Logo larger than the upload size limit"
+//	@Failure		415		{object}	errorValidResponse	"File is not a JPEG, PNG or GIF image"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/payments/{id}/logo [post]
+func (ph *PaymentHandler) UploadPaymentLogo(ctx *gin.Context) {
+	var req uploadPaymentLogoRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if err := ph.images.bind(ctx, &req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if !ph.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	// Nothing is stored for a payment that does not exist
+	if _, err := ph.svc.GetPaymentByID(ctx, req.ID); err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	logo, thumbnail, err := ph.images.save(ctx, req.Logo, fmt.Sprintf("payments/%d", req.ID))
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	payment := domain.Payment{
+		ID:            req.ID,
+		Logo:          logo,
+		LogoThumbnail: thumbnail,
+	}
+	previous, err := ph.svc.SetPaymentLogo(ctx, &payment)
+	if err != nil {
+		ph.images.discard(logo, thumbnail)
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+	ph.images.discard(previous.Logo, previous.LogoThumbnail)
+
+	rsp := newPaymentResponse(&payment)
+
+	handleSuccess(ctx, rsp)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+
+	"github.com/gin-gonic/gin"
+)
+
+// anyMethod is the policy key that applies to every HTTP method without its own entry
+const anyMethod = "*"
+
+// Policy lists the roles allowed to call a route group, keyed by HTTP method
+type Policy map[string][]domain.UserRole
+
+// policies holds the access policy of every authenticated route group, keyed by its base path
+var policies = map[string]Policy{
+	"/v1/users": {
+		anyMethod: {domain.Admin},
+	},
+	"/v1/bags": {
+		anyMethod: {domain.Admin},
+	},
+	"/v1/payments": {
+		http.MethodGet: {domain.Admin, domain.Cashier},
+		anyMethod:      {domain.Admin},
+	},
+	"/v1/categories": {
+		http.MethodGet: {domain.Admin, domain.Cashier},
+		anyMethod:      {domain.Admin},
+	},
+	"/v1/products": {
+		http.MethodGet: {domain.Admin, domain.Cashier},
+		anyMethod:      {domain.Admin},
+	},
+	"/v1/orders": {
+		anyMethod: {domain.Admin, domain.Cashier},
+	},
+	"/v1/held-orders": {
+		anyMethod: {domain.Admin, domain.Cashier},
+	},
+	"/v1/receipts": {
+		http.MethodGet: {domain.Admin, domain.Cashier},
+	},
+	"/v1/reports": {
+		anyMethod: {domain.Admin},
+	},
+	"/v1/shifts": {
+		anyMethod: {domain.Admin, domain.Cashier},
+	},
+	"/v1/promotions": {
+		http.MethodGet: {domain.Admin, domain.Cashier},
+		anyMethod:      {domain.Admin},
+	},
+	swaggerPath: {
+		anyMethod: {domain.Admin},
+	},
+	pprofPath: {
+		anyMethod: {domain.Admin},
+	},
+}
+
+// allows reports whether the role may call the route with the given method
+func (p Policy) allows(method string, role domain.UserRole) bool {
+	roles, ok := p[method]
+	if !ok {
+		roles = p[anyMethod]
+	}
+
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize is a middleware enforcing the access policy registered for a route group.
+// It must run after authMiddleware; groups without a policy are denied to everyone.
+func authorize(group string) gin.HandlerFunc {
+	policy := policies[group]
+
+	return func(ctx *gin.Context) {
+		payload := getAuthPayload(ctx, authorizationPayloadKey)
+
+		if !policy.allows(ctx.Request.Method, payload.Role) {
+			handleAbort(ctx, port.ErrForbidden, "AUTH7")
+			return
+		}
+
+		ctx.Next()
+	}
+}
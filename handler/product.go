@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"fmt"
+	"mime/multipart"
+
 	"gotemplate/core/domain"
 	"gotemplate/logger"
 
@@ -14,30 +17,34 @@ type ProductHandler struct {
 	svc repo.ProductRepository
 	log *logger.Logger
 	vs *ValidatorService
+	images *ImageUploader
 }
 
 // NewProductHandler creates a new ProductHandler instance
-func NewProductHandler(svc repo.ProductRepository, log *logger.Logger,vs *ValidatorService) *ProductHandler {
+func NewProductHandler(svc repo.ProductRepository, log *logger.Logger,vs *ValidatorService, images *ImageUploader) *ProductHandler {
 	return &ProductHandler{
 		svc,
 		log,
 		vs,
+		images,
 	}
 }
 
 // createProductRequest represents a request body for creating a new product
 type createProductRequest struct {
-	CategoryID uint64  `json:"category_id" validate:"required,min=1" example:"1"`
-	Name       string  `json:"name" validate:"required" example:"Chiki Ball"`
-	Image      string  `json:"image" validate:"required" example:"https://example.com/chiki-ball.png"`
-	Price      float64 `json:"price" validate:"required,min=0" example:"5000"`
-	Stock      int64   `json:"stock" validate:"required,min=0" example:"100"`
+	CategoryID uint64   `json:"category_id" validate:"required,min=1" example:"1"`
+	Barcode    *string  `json:"barcode" validate:"omitempty,min=1,max=64,printascii" example:"8991001101234"`
+	Name       string   `json:"name" validate:"required" example:"Chiki Ball"`
+	Image      string   `json:"image" validate:"required" example:"https://example.com/chiki-ball.png"`
+	Price      float64  `json:"price" validate:"required,min=0" example:"5000"`
+	Stock      int64    `json:"stock" validate:"required,min=0" example:"100"`
+	TaxRate    *float64 `json:"tax_rate" validate:"omitempty,min=0,max=100" example:"11"`
 }
 
 // CreateProduct godoc
 //
 //	@Summary		Create a new product
-//	@Description	create a new product with name, image, price, and stock
+//	@Description	create a new product with name, barcode, image, price, stock and its own tax rate in percent, without which the category rate applies
 //	@Tags			Products
 //	@Accept			json
 //	@Produce		json
@@ -62,13 +69,15 @@ func (ph *ProductHandler) CreateProduct(ctx *gin.Context) {
 
 	product := domain.Product{
 		CategoryID: req.CategoryID,
+		Barcode:    req.Barcode,
 		Name:       req.Name,
 		Image:      req.Image,
 		Price:      req.Price,
 		Stock:      req.Stock,
+		TaxRate:    req.TaxRate,
 	}
 
-	_, err := ph.svc.CreateProduct(ctx, &product)
+	_, err := ph.svc.CreateProduct(ctx, &product, getAuthPayload(ctx, authorizationPayloadKey).UserID)
 	if err != nil {
 		ph.log.Error(err.Error())
 		ph.vs.handledbError(ctx, err)
@@ -119,25 +128,69 @@ func (ph *ProductHandler) GetProduct(ctx *gin.Context) {
 	handleSuccess(ctx, rsp)
 }
 
+// lookupProductRequest represents the path of a product lookup by the code scanned at the till
+type lookupProductRequest struct {
+	Code string `uri:"code" validate:"required,max=64,printascii" example:"8991001101234"`
+}
+
+// LookupProduct godoc
+//
+//	@Summary		Look up a product by code
+//	@Description	get the product with an SKU or barcode, as scanned at the till
+//	@Tags			Products
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	path		string				true	"SKU or barcode"
+//	@Success		200		{object}	productResponse		"Product retrieved"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		404		{object}	errorValidResponse	"Data not found error"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/products/lookup/{code} [get]
+func (ph *ProductHandler) LookupProduct(ctx *gin.Context) {
+	var req lookupProductRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if !ph.vs.handleValidation(ctx, req) {
+		return
+	}
+	product, err := ph.svc.GetProductByCode(ctx, req.Code)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newProductResponse(product)
+
+	handleSuccess(ctx, rsp)
+}
+
 // listProductsRequest represents a request body for listing products
 type listProductsRequest struct {
 	CategoryID uint64 `form:"category_id" validate:"omitempty,min=1" example:"1"`
-	Query      string `form:"q" validate:"omitempty" example:"Chiki"`
-	Skip       uint64 `form:"skip" validate:"required,min=0" example:"0"`
-	Limit      uint64 `form:"limit" validate:"required,min=5" example:"5"`
+	Query      string `form:"q" validate:"omitempty,max=255" example:"Chiki"`
+	pageRequest
+	listQueryRequest
 }
 
 // ListProducts godoc
 //
 //	@Summary		List products
 //	@Description	List products with pagination
+//	@Description	Filter with filter[field][op]=value, op being eq, ne, gt, gte, lt, lte, like or in, as allowed by the field.
+//	@Description	q searches the name, category name, SKU and barcode of the products by word prefix, tolerating typos in the name.
 //	@Tags			Products
 //	@Accept			json
 //	@Produce		json
 //	@Param			category_id	query		uint64			false	"Category ID"
-//	@Param			q			query		string			false	"Query"
-//	@Param			skip		query		uint64			true	"Skip"
-//	@Param			limit		query		uint64			true	"Limit"
+//	@Param			q			query		string			false	"Search words, matched by prefix or with typos, the best match first"
+//	@Param			cursor		query		string			false	"Cursor of the next or previous page"
+//	@Param			limit		query		uint64			false	"Page size, 20 by default"
+//	@Param			total		query		string			false	"Count the matching rows"	Enums(exact, estimate)
+//	@Param			sort		query		string			false	"Sort fields, comma separated, descending with a leading -"
+//	@Param			fields		query		string			false	"Fields of the items to return, comma separated"
 //	@Success		200			{object}	meta			"Products retrieved"
 //	@Failure		400			{object}	errorValidResponse	"Validation error"
 //	@Failure		500			{object}	errorValidResponse	"Internal server error"
@@ -153,33 +206,47 @@ func (ph *ProductHandler) ListProducts(ctx *gin.Context) {
 	if !ph.vs.handleValidation(ctx, req) {
 		return
 	}
-	products, err := ph.svc.ListProducts(ctx, req.Query, req.CategoryID, req.Skip, req.Limit)
+	lq, fields, ok := ph.vs.handleListQuery(ctx, req.listQueryRequest, repo.ProductSchema)
+	if !ok {
+		return
+	}
+	params := req.params()
+	products, page, err := ph.svc.ListProducts(ctx, req.Query, req.CategoryID, lq, params)
 	if err != nil {
 		ph.log.Error(err.Error())
 		ph.vs.handledbError(ctx, err)
 		return
 	}
 
-	total := uint64(len(products))
-	meta := newMeta(total, req.Limit, req.Skip)
-	rsp := toMap(meta, products, "products")
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
+	items, err := selectFields(products, fields)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+	rsp := toMap(meta, items, "products")
 
 	handleSuccess(ctx, rsp)
 }
 
 // updateProductRequest represents a request body for updating a product
 type updateProductRequest struct {
-	CategoryID uint64  `json:"category_id" validate:"omitempty,required,min=1" example:"1"`
-	Name       string  `json:"name" validate:"omitempty,required" example:"Nutrisari Jeruk"`
-	Image      string  `json:"image" validate:"omitempty,required" example:"https://example.com/nutrisari-jeruk.png"`
-	Price      float64 `json:"price" validate:"omitempty,required,min=0" example:"2000"`
-	Stock      int64   `json:"stock" validate:"omitempty,required,min=0" example:"200"`
+	CategoryID   uint64   `json:"category_id" validate:"omitempty,required,min=1" example:"1"`
+	Barcode      *string  `json:"barcode" validate:"omitempty,min=1,max=64,printascii" example:"8991001101234"`
+	Name         string   `json:"name" validate:"omitempty,required" example:"Nutrisari Jeruk"`
+	Image        string   `json:"image" validate:"omitempty,required" example:"https://example.com/nutrisari-jeruk.png"`
+	Price        float64  `json:"price" validate:"omitempty,required,min=0" example:"2000"`
+	Stock        int64    `json:"stock" validate:"omitempty,required,min=0" example:"200"`
+	TaxRate      *float64 `json:"tax_rate" validate:"omitempty,min=0,max=100,excluded_with=ClearTaxRate" example:"11"`
+	ClearTaxRate bool     `json:"clear_tax_rate" example:"false"`
 }
 
 // UpdateProduct godoc
 //
 //	@Summary		Update a product
-//	@Description	update a product's name, image, price, or stock by id
+//	@Description	update a product's name, barcode, image, price, stock or tax rate by id, clear_tax_rate falling back to the category rate
 //	@Tags			Products
 //	@Accept			json
 //	@Produce		json
@@ -212,13 +279,15 @@ func (ph *ProductHandler) UpdateProduct(ctx *gin.Context) {
 	product := domain.Product{
 		ID:         id,
 		CategoryID: req.CategoryID,
+		Barcode:    req.Barcode,
 		Name:       req.Name,
 		Image:      req.Image,
 		Price:      req.Price,
 		Stock:      req.Stock,
+		TaxRate:    req.TaxRate,
 	}
 
-	_, err = ph.svc.UpdateProduct(ctx, &product)
+	_, err = ph.svc.UpdateProduct(ctx, &product, req.ClearTaxRate, getAuthPayload(ctx, authorizationPayloadKey).UserID)
 	if err != nil {
 		ph.log.Error(err.Error())
 		ph.vs.handledbError(ctx, err)
@@ -269,3 +338,75 @@ func (ph *ProductHandler) DeleteProduct(ctx *gin.Context) {
 
 	handleSuccess(ctx, nil)
 }
+
+// uploadProductImageRequest represents a multipart request uploading the image of a product
+type uploadProductImageRequest struct {
+	ID    uint64                `uri:"id" validate:"required,min=1" example:"1"`
+	Image *multipart.FileHeader `json:"image" form:"image" validate:"required" swaggerignore:"true"`
+}
+
+// UploadProductImage godoc
+//
+//	@Summary		Upload a product image
+//	@Description	replace the image of a product with an uploaded JPEG, PNG or GIF file, which a thumbnail is made of.
+//	@Description	The image and thumbnail URLs of the product point to the stored files.
+//	@Tags			Products
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			id		path		uint64				true	"Product ID"
+//	@Param			image	formData	file				true	"Image file"
+//	@Success		200		{object}	productResponse		"Product image uploaded"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403		{object}	errorValidResponse	"Forbidden error"
+//	@Failure		404		{object}	errorValidResponse	"Data not found error"
+//	@Failure		413		{object}	errorValidResponse	"Image larger than the upload size limit"
+//	@Failure		415		{object}	errorValidResponse	"File is not a JPEG, PNG or GIF image"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/products/{id}/image [post]
+func (ph *ProductHandler) UploadProductImage(ctx *gin.Context) {
+	var req uploadProductImageRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if err := ph.images.bind(ctx, &req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if !ph.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	// Nothing is stored for a product that does not exist
+	if _, err := ph.svc.GetProductByID(ctx, req.ID); err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	image, thumbnail, err := ph.images.save(ctx, req.Image, fmt.Sprintf("products/%d", req.ID))
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	product := domain.Product{
+		ID:        req.ID,
+		Image:     image,
+		Thumbnail: thumbnail,
+	}
+	previous, err := ph.svc.SetProductImage(ctx, &product)
+	if err != nil {
+		ph.images.discard(image, thumbnail)
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+	ph.images.discard(previous.Image, previous.Thumbnail)
+
+	rsp := newProductResponse(&product)
+
+	handleSuccess(ctx, rsp)
+}
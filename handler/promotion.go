@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/logger"
+	repo "gotemplate/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PromotionHandler represents the HTTP handler for promotion-related requests
+type PromotionHandler struct {
+	svc repo.PromotionRepository
+	log *logger.Logger
+	vs  *ValidatorService
+}
+
+// NewPromotionHandler creates a new PromotionHandler instance
+func NewPromotionHandler(svc repo.PromotionRepository, log *logger.Logger, vs *ValidatorService) *PromotionHandler {
+	return &PromotionHandler{
+		svc,
+		log,
+		vs,
+	}
+}
+
+// promotionRequest represents a request body for creating or replacing a promotion.
+// Value is a percentage or an amount depending on kind and is ignored by buy_x_get_y promotions.
+type promotionRequest struct {
+	Name        string                `json:"name" validate:"required" example:"Weekend 10% off"`
+	Kind        domain.PromotionKind  `json:"kind" validate:"required,oneof=percentage fixed buy_x_get_y" example:"percentage"`
+	Scope       domain.PromotionScope `json:"scope" validate:"required,oneof=product category order" example:"category"`
+	TargetID    *uint64               `json:"target_id" validate:"omitempty,min=1" example:"1"`
+	Value       float64               `json:"value" validate:"min=0" example:"10"`
+	BuyQuantity int64                 `json:"buy_qty" validate:"min=0" example:"0"`
+	GetQuantity int64                 `json:"get_qty" validate:"min=0" example:"0"`
+	MinSubtotal float64               `json:"min_subtotal" validate:"min=0" example:"0"`
+	StartsAt    *time.Time            `json:"starts_at" example:"2024-01-01T00:00:00Z"`
+	EndsAt      *time.Time            `json:"ends_at" example:"2024-02-01T00:00:00Z"`
+	Active      *bool                 `json:"active" example:"true"`
+}
+
+// promotion checks the rules between the fields of the request and converts it to a promotion,
+// returning the validation messages with their tags when it is inconsistent
+func (req promotionRequest) promotion() (domain.Promotion, []string, []string) {
+	var messages, tags []string
+	fail := func(message, tag string) {
+		messages = append(messages, message)
+		tags = append(tags, tag)
+	}
+
+	if req.Scope == domain.ScopeOrder && req.TargetID != nil {
+		fail("target_id must be empty for order promotions", "excluded_if")
+	}
+	if req.Scope != domain.ScopeOrder && req.TargetID == nil {
+		fail("target_id is required for product and category promotions", "required_if")
+	}
+
+	switch req.Kind {
+	case domain.PromotionPercentage:
+		if req.Value <= 0 || req.Value > 100 {
+			fail("value must be a percentage above 0 and at most 100", "max")
+		}
+	case domain.PromotionFixed:
+		if req.Value <= 0 {
+			fail("value must be above 0", "gt")
+		}
+	case domain.PromotionBuyXGetY:
+		if req.Scope == domain.ScopeOrder {
+			fail("buy_x_get_y promotions apply to a product or a category", "oneof")
+		}
+		if req.BuyQuantity < 1 || req.GetQuantity < 1 {
+			fail("buy_qty and get_qty must be at least 1 for buy_x_get_y promotions", "required_if")
+		}
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		fail("ends_at must be after starts_at", "gtfield")
+	}
+
+	promotion := domain.Promotion{
+		Name:        req.Name,
+		Kind:        req.Kind,
+		Scope:       req.Scope,
+		TargetID:    req.TargetID,
+		Value:       req.Value,
+		MinSubtotal: req.MinSubtotal,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if req.Kind == domain.PromotionBuyXGetY {
+		promotion.Value = 0
+		promotion.BuyQuantity = req.BuyQuantity
+		promotion.GetQuantity = req.GetQuantity
+	}
+
+	return promotion, messages, tags
+}
+
+// bindPromotion binds and validates a promotion request body, writing the error response when it fails
+func (ph *PromotionHandler) bindPromotion(ctx *gin.Context) (domain.Promotion, bool) {
+	var req promotionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return domain.Promotion{}, false
+	}
+	if !ph.vs.handleValidation(ctx, req) {
+		return domain.Promotion{}, false
+	}
+
+	promotion, messages, tags := req.promotion()
+	if len(messages) > 0 {
+		codes := make([]string, len(tags))
+		for i, tag := range tags {
+			codes[i] = ph.vs.tagToNumber[tag]
+		}
+		ctx.JSON(http.StatusBadRequest, newErrorValidResponse(messages, codes))
+		return domain.Promotion{}, false
+	}
+
+	return promotion, true
+}
+
+// CreatePromotion godoc
+//
+//	@Summary		Create a new promotion
+//	@Description	Create a percentage, fixed amount or buy X get Y promotion on a product, a category or the whole order.
+//	@Description	Active promotions are applied when orders are created, within their optional time window.
+//	@Tags			Promotions
+//	@Accept			json
+//	@Produce		json
+//	@Param			promotionRequest	body		promotionRequest	true	"Create promotion request"
+//	@Success		200					{object}	promotionResponse	"Promotion created"
+//	@Failure		400					{object}	errorValidResponse	"Validation error"
+//	@Failure		401					{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403					{object}	errorValidResponse	"Forbidden error"
+//	@Failure		500					{object}	errorValidResponse	"Internal server error"
+//	@Router			/promotions [post]
+//	@Security		BearerAuth
+func (ph *PromotionHandler) CreatePromotion(ctx *gin.Context) {
+	promotion, ok := ph.bindPromotion(ctx)
+	if !ok {
+		return
+	}
+
+	_, err := ph.svc.CreatePromotion(ctx, &promotion)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newPromotionResponse(&promotion)
+
+	handleSuccess(ctx, rsp)
+}
+
+// promotionIDRequest represents the path of a request on one promotion
+type promotionIDRequest struct {
+	ID uint64 `uri:"id" validate:"required,min=1" example:"1"`
+}
+
+// GetPromotion godoc
+//
+//	@Summary		Get a promotion
+//	@Description	Get a promotion by id
+//	@Tags			Promotions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		uint64				true	"Promotion ID"
+//	@Success		200	{object}	promotionResponse	"Promotion retrieved"
+//	@Failure		400	{object}	errorValidResponse	"Validation error"
+//	@Failure		404	{object}	errorValidResponse	"Data not found error"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/promotions/{id} [get]
+//	@Security		BearerAuth
+func (ph *PromotionHandler) GetPromotion(ctx *gin.Context) {
+	var req promotionIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if !ph.vs.handleValidation(ctx, req) {
+		return
+	}
+	promotion, err := ph.svc.GetPromotionByID(ctx, req.ID)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newPromotionResponse(promotion)
+
+	handleSuccess(ctx, rsp)
+}
+
+// listPromotionsRequest represents the query of a promotion listing
+type listPromotionsRequest struct {
+	pageRequest
+	listQueryRequest
+	Active bool `form:"active" example:"true"`
+}
+
+// ListPromotions godoc
+//
+//	@Summary		List promotions
+//	@Description	List promotions with pagination, only those applying right now when active is set
+//	@Description	Filter with filter[field][op]=value, op being eq, ne, gt, gte, lt, lte, like or in, as allowed by the field.
+//	@Tags			Promotions
+//	@Accept			json
+//	@Produce		json
+//	@Param			cursor	query		string				false	"Cursor of the next or previous page"
+//	@Param			limit	query		uint64				false	"Page size, 20 by default"
+//	@Param			total	query		string				false	"Count the matching rows"	Enums(exact, estimate)
+//	@Param			sort	query		string				false	"Sort fields, comma separated, descending with a leading -"
+//	@Param			fields	query		string				false	"Fields of the items to return, comma separated"
+//	@Param			active	query		bool				false	"Only promotions active now"
+//	@Success		200		{object}	meta				"Promotions displayed"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/promotions [get]
+//	@Security		BearerAuth
+func (ph *PromotionHandler) ListPromotions(ctx *gin.Context) {
+	var req listPromotionsRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if !ph.vs.handleValidation(ctx, req) {
+		return
+	}
+	lq, fields, ok := ph.vs.handleListQuery(ctx, req.listQueryRequest, repo.PromotionSchema)
+	if !ok {
+		return
+	}
+	params := req.params()
+	promotions, page, err := ph.svc.ListPromotions(ctx, req.Active, lq, params)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
+	items, err := selectFields(newPromotionResponses(promotions), fields)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+	rsp := toMap(meta, items, "promotions")
+
+	handleSuccess(ctx, rsp)
+}
+
+// UpdatePromotion godoc
+//
+//	@Summary		Update a promotion
+//	@Description	Replace a promotion by id. Orders already priced with it keep their discounts.
+//	@Tags			Promotions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		uint64				true	"Promotion ID"
+//	@Param			promotionRequest	body		promotionRequest	true	"Update promotion request"
+//	@Success		200					{object}	promotionResponse	"Promotion updated"
+//	@Failure		400					{object}	errorValidResponse	"Validation error"
+//	@Failure		401					{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403					{object}	errorValidResponse	"Forbidden error"
+//	@Failure		404					{object}	errorValidResponse	"Data not found error"
+//	@Failure		500					{object}	errorValidResponse	"Internal server error"
+//	@Router			/promotions/{id} [put]
+//	@Security		BearerAuth
+func (ph *PromotionHandler) UpdatePromotion(ctx *gin.Context) {
+	var uri promotionIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if !ph.vs.handleValidation(ctx, uri) {
+		return
+	}
+	promotion, ok := ph.bindPromotion(ctx)
+	if !ok {
+		return
+	}
+	promotion.ID = uri.ID
+
+	_, err := ph.svc.UpdatePromotion(ctx, &promotion)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newPromotionResponse(&promotion)
+
+	handleSuccess(ctx, rsp)
+}
+
+// DeletePromotion godoc
+//
+//	@Summary		Delete a promotion
+//	@Description	Delete a promotion by id. Orders already priced with it keep their discounts.
+//	@Tags			Promotions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		uint64				true	"Promotion ID"
+//	@Success		200	{object}	Response			"Promotion deleted"
+//	@Failure		400	{object}	errorValidResponse	"Validation error"
+//	@Failure		401	{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403	{object}	errorValidResponse	"Forbidden error"
+//	@Failure		404	{object}	errorValidResponse	"Data not found error"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/promotions/{id} [delete]
+//	@Security		BearerAuth
+func (ph *PromotionHandler) DeletePromotion(ctx *gin.Context) {
+	var req promotionIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ph.vs.handleError(ctx, err)
+		return
+	}
+	if !ph.vs.handleValidation(ctx, req) {
+		return
+	}
+	err := ph.svc.DeletePromotion(ctx, req.ID)
+	if err != nil {
+		ph.log.Error(err.Error())
+		ph.vs.handledbError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, nil)
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	repo "gotemplate/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// listQueryRequest represents the sort and fields query of a list request, as in ?sort=-created_at,name&fields=id,name.
+// Filters are read from the filter[field][op]=value parameters, filter[field]=value comparing with eq;
+// the in operator takes comma separated values.
+type listQueryRequest struct {
+	Sort   string `form:"sort" validate:"omitempty,max=255" example:"-created_at,name"`
+	Fields string `form:"fields" validate:"omitempty,max=1024" example:"id,name"`
+}
+
+// filterParam matches the filter[field] and filter[field][op] query parameters
+var filterParam = regexp.MustCompile(`^filter\[(\w+)\](?:\[(\w+)\])?$`)
+
+// kindTags maps the value kinds to the validation tag of their error code and the message of a value of another kind
+var kindTags = map[repo.ValueKind][2]string{
+	repo.KindInt:   {"number", "must be an integer"},
+	repo.KindUint:  {"number", "must be a positive integer"},
+	repo.KindFloat: {"number", "must be a number"},
+	repo.KindBool:  {"boolean", "must be true or false"},
+	repo.KindTime:  {"datetime", "must be an RFC 3339 date and time"},
+	repo.KindUUID:  {"uuid", "must be a UUID"},
+}
+
+// parseValue parses a filter value as a value of kind
+func parseValue(kind repo.ValueKind, s string) (any, error) {
+	switch kind {
+	case repo.KindInt:
+		return strconv.ParseInt(s, 10, 64)
+	case repo.KindUint:
+		return strconv.ParseUint(s, 10, 64)
+	case repo.KindFloat:
+		return strconv.ParseFloat(s, 64)
+	case repo.KindBool:
+		return strconv.ParseBool(s)
+	case repo.KindTime:
+		return time.Parse(time.RFC3339, s)
+	case repo.KindUUID:
+		return uuid.Parse(s)
+	}
+	return s, nil
+}
+
+// validateListQuery checks the filters, sort and fields of a list request against the fields of schema,
+// returning the list query and the selected fields with the error messages and codes
+func (vs *ValidatorService) validateListQuery(ctx *gin.Context, req listQueryRequest, schema *repo.ListSchema) (repo.ListQuery, []string, []string, []string) {
+	var lq repo.ListQuery
+	var messages, codes []string
+	fail := func(tag, message string) {
+		messages = append(messages, message)
+		codes = append(codes, vs.tagToNumber[tag])
+	}
+
+	query := ctx.Request.URL.Query()
+	var params []string
+	for param := range query {
+		if strings.HasPrefix(param, "filter") {
+			params = append(params, param)
+		}
+	}
+	sort.Strings(params)
+
+	for _, param := range params {
+		values := query[param]
+
+		matches := filterParam.FindStringSubmatch(param)
+		if matches == nil {
+			fail("oneof", param+" must be filter[field] or filter[field][op]")
+			continue
+		}
+		op := repo.Operator(matches[2])
+		if op == "" {
+			op = repo.OpEq
+		}
+		field, ok := schema.Field(matches[1])
+		if !ok || !field.Allows(op) {
+			fail("oneof", fmt.Sprintf("%s cannot be filtered with %s", matches[1], op))
+			continue
+		}
+
+		for _, value := range values {
+			raw := []string{value}
+			if op == repo.OpIn {
+				raw = strings.Split(value, ",")
+			}
+
+			condition := repo.Condition{Field: field.Name, Op: op}
+			for _, s := range raw {
+				v, err := parseValue(field.Kind, strings.TrimSpace(s))
+				if err != nil {
+					fail(kindTags[field.Kind][0], param+" "+kindTags[field.Kind][1])
+					break
+				}
+				condition.Values = append(condition.Values, v)
+			}
+			lq.Conditions = append(lq.Conditions, condition)
+		}
+	}
+
+	if req.Sort != "" {
+		seen := make(map[string]bool)
+		for _, name := range strings.Split(req.Sort, ",") {
+			by := repo.SortField{Field: strings.TrimPrefix(name, "-"), Desc: strings.HasPrefix(name, "-")}
+			field, ok := schema.Field(by.Field)
+			if !ok || !field.Sortable || seen[by.Field] {
+				fail("oneof", "sort cannot be by "+name)
+				continue
+			}
+			seen[by.Field] = true
+			lq.Sort = append(lq.Sort, by)
+		}
+	}
+
+	var fields []string
+	if req.Fields != "" {
+		fields = strings.Split(req.Fields, ",")
+		for _, name := range fields {
+			if _, ok := schema.Field(name); !ok {
+				fail("oneof", "fields must be among "+strings.Join(schema.Fields(), " "))
+				break
+			}
+		}
+	}
+
+	return lq, fields, messages, codes
+}
+
+// handleListQuery checks the filters, sort and fields of a list request, writing the error response when they are invalid
+func (vs *ValidatorService) handleListQuery(ctx *gin.Context, req listQueryRequest, schema *repo.ListSchema) (repo.ListQuery, []string, bool) {
+	lq, fields, messages, codes := vs.validateListQuery(ctx, req, schema)
+	if len(messages) > 0 {
+		ctx.JSON(http.StatusBadRequest, newErrorValidResponse(messages, codes))
+		return lq, nil, false
+	}
+	return lq, fields, true
+}
+
+// selectFields keeps the given fields of the JSON objects of items, all of them when fields is empty
+func selectFields(items any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, err
+	}
+
+	selected := make([]map[string]json.RawMessage, len(objects))
+	for i, object := range objects {
+		selected[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := object[field]; ok {
+				selected[i][field] = value
+			}
+		}
+	}
+	return selected, nil
+}
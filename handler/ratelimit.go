@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"gotemplate/config"
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// rateLimitLimitHeader is the size of the bucket that limited the request
+	rateLimitLimitHeader = "X-RateLimit-Limit"
+	// rateLimitRemainingHeader is the number of requests left in the bucket
+	rateLimitRemainingHeader = "X-RateLimit-Remaining"
+	// rateLimitResetHeader is the number of seconds until the bucket is full again
+	rateLimitResetHeader = "X-RateLimit-Reset"
+	// retryAfterHeader is the number of seconds a blocked client should wait
+	retryAfterHeader = "Retry-After"
+)
+
+/**
+ * RateLimiter applies the per client IP, per user and per route group
+ * rates from the config to requests, using a ratelimit.Limiter store
+ */
+type RateLimiter struct {
+	limiter ratelimit.Limiter
+	ip      ratelimit.Rate
+	user    ratelimit.Rate
+	groups  map[string]ratelimit.Rate
+}
+
+// NewRateLimiter creates a new RateLimiter instance from the rates in the config
+func NewRateLimiter(cfg config.Econfig, limiter ratelimit.Limiter) (*RateLimiter, error) {
+	ip, err := ratelimit.ParseRate(cfg.RateLimitIP())
+	if err != nil {
+		return nil, fmt.Errorf("RateLimitIP: %w", err)
+	}
+
+	user, err := ratelimit.ParseRate(cfg.RateLimitUser())
+	if err != nil {
+		return nil, fmt.Errorf("RateLimitUser: %w", err)
+	}
+
+	groups := make(map[string]ratelimit.Rate, len(cfg.RateLimitGroups()))
+	for group, s := range cfg.RateLimitGroups() {
+		rate, err := ratelimit.ParseRate(s)
+		if err != nil {
+			return nil, fmt.Errorf("RateLimitGroups %s: %w", group, err)
+		}
+		groups[group] = rate
+	}
+
+	return &RateLimiter{
+		limiter: limiter,
+		ip:      ip,
+		user:    user,
+		groups:  groups,
+	}, nil
+}
+
+// PerIP limits every request by client IP
+func (rl *RateLimiter) PerIP() gin.HandlerFunc {
+	return rl.limit(rl.ip, func(ctx *gin.Context) string {
+		return "ip:" + ctx.ClientIP()
+	})
+}
+
+// PerUser limits requests by authenticated user. It must run after authMiddleware.
+func (rl *RateLimiter) PerUser() gin.HandlerFunc {
+	return rl.limit(rl.user, func(ctx *gin.Context) string {
+		return "user:" + strconv.FormatUint(getAuthPayload(ctx, authorizationPayloadKey).UserID, 10)
+	})
+}
+
+// PerGroup limits requests to a route group by authenticated user, or by client IP when there is none
+func (rl *RateLimiter) PerGroup(group string) gin.HandlerFunc {
+	return rl.limit(rl.groups[group], func(ctx *gin.Context) string {
+		if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+			return "group:" + group + ":user:" + strconv.FormatUint(payload.(*domain.TokenPayload).UserID, 10)
+		}
+		return "group:" + group + ":ip:" + ctx.ClientIP()
+	})
+}
+
+// limit is a middleware taking a token from the bucket of the request key.
+// A zero rate disables it and store errors let the request through.
+func (rl *RateLimiter) limit(rate ratelimit.Rate, key func(ctx *gin.Context) string) gin.HandlerFunc {
+	if rl == nil || rl.limiter == nil || rate.Disabled() {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		res, err := rl.limiter.Allow(ctx.Request.Context(), key(ctx), rate)
+		if err != nil {
+			_ = ctx.Error(err)
+			ctx.Next()
+			return
+		}
+
+		setRateLimitHeaders(ctx, res)
+
+		if !res.Allowed {
+			ctx.Header(retryAfterHeader, strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			handleAbort(ctx, port.ErrTooManyRequests, "RATE1")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// setRateLimitHeaders reports the most restrictive bucket the request went through
+func setRateLimitHeaders(ctx *gin.Context, res ratelimit.Result) {
+	if current := ctx.Writer.Header().Get(rateLimitRemainingHeader); current != "" {
+		if remaining, err := strconv.Atoi(current); err == nil && remaining < res.Remaining {
+			return
+		}
+	}
+
+	ctx.Header(rateLimitLimitHeader, strconv.Itoa(res.Limit))
+	ctx.Header(rateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+	ctx.Header(rateLimitResetHeader, strconv.Itoa(ceilSeconds(res.ResetAfter)))
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+
+	"gotemplate/config"
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+	"gotemplate/receipt"
+	repo "gotemplate/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Receipt formats selectable with the format query parameter
+const (
+	receiptFormatJSON   = "json"
+	receiptFormatText   = "text"
+	receiptFormatHTML   = "html"
+	receiptFormatESCPOS = "escpos"
+)
+
+// mimeOctetStream is the content type of raw ESC/POS printer bytes
+const mimeOctetStream = "application/octet-stream"
+
+// receiptMIMETypes maps the Accept header types to receipt formats
+var receiptMIMETypes = map[string]string{
+	gin.MIMEJSON:    receiptFormatJSON,
+	gin.MIMEPlain:   receiptFormatText,
+	gin.MIMEHTML:    receiptFormatHTML,
+	mimeOctetStream: receiptFormatESCPOS,
+}
+
+// ReceiptHandler represents the HTTP handler for receipt requests
+type ReceiptHandler struct {
+	svc   repo.OrderRepository
+	log   *logger.Logger
+	vs    *ValidatorService
+	store receipt.Store
+}
+
+// NewReceiptHandler creates a new ReceiptHandler instance printing the store details from config
+func NewReceiptHandler(svc repo.OrderRepository, log *logger.Logger, vs *ValidatorService, cfg config.Econfig) *ReceiptHandler {
+	return &ReceiptHandler{
+		svc,
+		log,
+		vs,
+		receipt.Store{
+			Name:   cfg.ReceiptStoreName(),
+			Footer: cfg.ReceiptFooter(),
+			Width:  cfg.ReceiptWidth(),
+			Symbol: receipt.Symbol(cfg.ReceiptSymbol()),
+		},
+	}
+}
+
+// receiptFormatRequest represents the query of a receipt request
+type receiptFormatRequest struct {
+	Format string `form:"format" validate:"omitempty,oneof=json text html escpos" example:"text"`
+}
+
+// getReceiptRequest represents a request for a receipt by receipt code
+type getReceiptRequest struct {
+	Code string `uri:"code" validate:"required,uuid" example:"4979cf6e-d215-4ff8-9d0d-b3e99bcc7750"`
+	receiptFormatRequest
+}
+
+// GetReceipt godoc
+//
+//	@Summary		Get a receipt
+//	@Description	Look an order up by its receipt code and render its receipt as JSON, plain text for thermal printers, HTML
+//	@Description	or ESC/POS printer commands returned as application/octet-stream for a local print agent.
+//	@Description	The format query parameter takes precedence over the Accept header.
+//	@Tags			Receipts
+//	@Produce		json,plain,html,octet-stream
+//	@Param			code	path		string				true	"Receipt code"
+//	@Param			format	query		string				false	"Receipt format"	Enums(json, text, html, escpos)
+//	@Success		200		{object}	receipt.Receipt		"Receipt displayed"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404		{object}	errorValidResponse	"Data not found error"
+//	@Failure		406		{object}	errorValidResponse	"No acceptable receipt format"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/receipts/{code} [get]
+//	@Security		BearerAuth
+func (rh *ReceiptHandler) GetReceipt(ctx *gin.Context) {
+	var req getReceiptRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		rh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindQuery(&req.receiptFormatRequest); err != nil {
+		rh.vs.handleError(ctx, err)
+		return
+	}
+	if !rh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	order, err := rh.svc.GetOrderByReceiptCode(ctx, uuid.MustParse(req.Code))
+	if err != nil {
+		rh.log.Error(err.Error())
+		rh.vs.handledbError(ctx, err)
+		return
+	}
+
+	rh.render(ctx, req.Format, order)
+}
+
+// getOrderReceiptRequest represents a request for the receipt of an order
+type getOrderReceiptRequest struct {
+	ID uint64 `uri:"id" validate:"required,min=1" example:"1"`
+	receiptFormatRequest
+}
+
+// GetOrderReceipt godoc
+//
+//	@Summary		Get the receipt of an order
+//	@Description	Render the receipt of an order as JSON, plain text for thermal printers, HTML
+//	@Description	or ESC/POS printer commands returned as application/octet-stream for a local print agent.
+//	@Description	The format query parameter takes precedence over the Accept header.
+//	@Tags			Orders
+//	@Produce		json,plain,html,octet-stream
+//	@Param			id		path		uint64				true	"Order ID"
+//	@Param			format	query		string				false	"Receipt format"	Enums(json, text, html, escpos)
+//	@Success		200		{object}	receipt.Receipt		"Receipt displayed"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404		{object}	errorValidResponse	"Data not found error"
+//	@Failure		406		{object}	errorValidResponse	"No acceptable receipt format"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/orders/{id}/receipt [get]
+//	@Security		BearerAuth
+func (rh *ReceiptHandler) GetOrderReceipt(ctx *gin.Context) {
+	var req getOrderReceiptRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		rh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindQuery(&req.receiptFormatRequest); err != nil {
+		rh.vs.handleError(ctx, err)
+		return
+	}
+	if !rh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	order, err := rh.svc.GetOrderByID(ctx, req.ID)
+	if err != nil {
+		rh.log.Error(err.Error())
+		rh.vs.handledbError(ctx, err)
+		return
+	}
+
+	rh.render(ctx, req.Format, order)
+}
+
+// render writes the receipt of order in the requested format,
+// negotiating it from the Accept header when format is empty
+func (rh *ReceiptHandler) render(ctx *gin.Context, format string, order *domain.Order) {
+	if format == "" {
+		format = receiptMIMETypes[ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain, gin.MIMEHTML, mimeOctetStream)]
+	}
+
+	rcpt := receipt.New(rh.store, order)
+
+	var buf bytes.Buffer
+	var contentType string
+	var err error
+
+	switch format {
+	case receiptFormatJSON:
+		handleSuccess(ctx, rcpt)
+		return
+	case receiptFormatText:
+		contentType = "text/plain; charset=utf-8"
+		err = rcpt.WriteText(&buf)
+	case receiptFormatHTML:
+		contentType = "text/html; charset=utf-8"
+		err = rcpt.WriteHTML(&buf)
+	case receiptFormatESCPOS:
+		contentType = mimeOctetStream
+		err = rcpt.WriteESCPOS(&buf)
+	default:
+		handleAbort(ctx, port.ErrNotAcceptable, "RCP1")
+		return
+	}
+
+	if err != nil {
+		rh.log.Error(err.Error())
+		handleAbort(ctx, err, "RCP2")
+		return
+	}
+
+	if format == receiptFormatESCPOS {
+		ctx.Header("Content-Disposition", `attachment; filename="receipt-`+rcpt.ReceiptCode+`.bin"`)
+	}
+
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gotemplate/config"
+	"gotemplate/core/domain"
+	"gotemplate/logger"
+	repo "gotemplate/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Report formats selectable with the format query parameter
+const (
+	reportFormatJSON = "json"
+	reportFormatCSV  = "csv"
+)
+
+// mimeCSV is the content type of CSV reports
+const mimeCSV = "text/csv"
+
+// reportDateLayout is the format of the date range of a report
+const reportDateLayout = "2006-01-02"
+
+// defaultTopProducts is the number of products listed when no limit is given
+const defaultTopProducts = 10
+
+// ReportHandler represents the HTTP handler for sales report requests
+type ReportHandler struct {
+	svc      repo.ReportRepository
+	log      *logger.Logger
+	vs       *ValidatorService
+	timezone string
+}
+
+// NewReportHandler creates a new ReportHandler instance computing reports in the ReportTimezone from config
+func NewReportHandler(svc repo.ReportRepository, log *logger.Logger, vs *ValidatorService, cfg config.Econfig) (*ReportHandler, error) {
+	timezone := cfg.ReportTimezone()
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, err
+	}
+
+	return &ReportHandler{
+		svc,
+		log,
+		vs,
+		timezone,
+	}, nil
+}
+
+// reportRequest represents the query shared by every report
+type reportRequest struct {
+	From       string `form:"from" validate:"required,datetime=2006-01-02" example:"2024-01-01"`
+	To         string `form:"to" validate:"required,datetime=2006-01-02" example:"2024-01-31"`
+	Timezone   string `form:"tz" validate:"omitempty,timezone" example:"Asia/Jakarta"`
+	CategoryID uint64 `form:"category_id" validate:"omitempty,min=1" example:"1"`
+	Format     string `form:"format" validate:"omitempty,oneof=json csv" example:"csv"`
+}
+
+// bind binds and validates the report query into req, writing the error response on failure.
+// The dates are whole days in the report timezone, to included.
+func (rh *ReportHandler) bind(ctx *gin.Context, req any, rr *reportRequest) (domain.ReportFilter, bool) {
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		rh.vs.handleError(ctx, err)
+		return domain.ReportFilter{}, false
+	}
+	if !rh.vs.handleValidation(ctx, req) {
+		return domain.ReportFilter{}, false
+	}
+
+	if rr.Timezone == "" {
+		rr.Timezone = rh.timezone
+	}
+	loc, _ := time.LoadLocation(rr.Timezone)
+
+	// The layouts were checked by the datetime validation
+	from, _ := time.ParseInLocation(reportDateLayout, rr.From, loc)
+	to, _ := time.ParseInLocation(reportDateLayout, rr.To, loc)
+	if to.Before(from) {
+		ctx.JSON(http.StatusBadRequest, newErrorValidResponse([]string{"to must be on or after from"}, []string{rh.vs.tagToNumber["gtefield"]}))
+		return domain.ReportFilter{}, false
+	}
+
+	return domain.ReportFilter{
+		From:       from,
+		To:         to.AddDate(0, 0, 1),
+		Timezone:   rr.Timezone,
+		CategoryID: rr.CategoryID,
+	}, true
+}
+
+// respond sends rows as JSON, or table as a CSV download when requested by the format parameter or the Accept header
+func (rh *ReportHandler) respond(ctx *gin.Context, req reportRequest, name string, rows any, table [][]string) {
+	format := req.Format
+	if format == "" && ctx.NegotiateFormat(gin.MIMEJSON, mimeCSV) == mimeCSV {
+		format = reportFormatCSV
+	}
+
+	if format != reportFormatCSV {
+		handleSuccess(ctx, reportResponse{
+			From:       req.From,
+			To:         req.To,
+			Timezone:   req.Timezone,
+			CategoryID: req.CategoryID,
+			Rows:       rows,
+		})
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="`+name+`-`+req.From+`-`+req.To+`.csv"`)
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Header().Set("Content-Type", mimeCSV+"; charset=utf-8")
+
+	w := csv.NewWriter(ctx.Writer)
+	if err := w.WriteAll(table); err != nil {
+		rh.log.Error(err.Error())
+	}
+}
+
+// formatAmount formats an amount for CSV output
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// salesReportRequest represents the query of the sales per period report
+type salesReportRequest struct {
+	reportRequest
+	Interval string `form:"interval" validate:"omitempty,oneof=day week month" example:"day"`
+}
+
+// SalesReport godoc
+//
+//	@Summary		Sales per period
+//	@Description	Revenue, orders and items sold per day, week or month between two dates, both included.
+//	@Description	Periods start at midnight in the tz timezone, weeks on Monday. Cancelled orders and refunded items are not counted.
+//	@Tags			Reports
+//	@Produce		json,text/csv
+//	@Param			from		query		string				true	"First day (YYYY-MM-DD)"
+//	@Param			to			query		string				true	"Last day (YYYY-MM-DD)"
+//	@Param			tz			query		string				false	"IANA timezone, ReportTimezone from config by default"
+//	@Param			category_id	query		uint64				false	"Only count products of this category"
+//	@Param			interval	query		string				false	"Period length"	Enums(day, week, month)
+//	@Param			format		query		string				false	"Response format"	Enums(json, csv)
+//	@Success		200			{object}	reportResponse		"Report displayed"
+//	@Failure		400			{object}	errorValidResponse	"Validation error"
+//	@Failure		401			{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403			{object}	errorValidResponse	"Forbidden error"
+//	@Failure		500			{object}	errorValidResponse	"Internal server error"
+//	@Router			/reports/sales [get]
+//	@Security		BearerAuth
+func (rh *ReportHandler) SalesReport(ctx *gin.Context) {
+	var req salesReportRequest
+
+	filter, ok := rh.bind(ctx, &req, &req.reportRequest)
+	if !ok {
+		return
+	}
+	if req.Interval == "" {
+		req.Interval = string(domain.IntervalDay)
+	}
+
+	sales, err := rh.svc.SalesByPeriod(ctx, filter, domain.ReportInterval(req.Interval))
+	if err != nil {
+		rh.log.Error(err.Error())
+		rh.vs.handledbError(ctx, err)
+		return
+	}
+
+	table := [][]string{{"period", "orders", "items", "revenue"}}
+	for _, s := range sales {
+		table = append(table, []string{s.Period, strconv.FormatInt(s.Orders, 10), strconv.FormatInt(s.Items, 10), formatAmount(s.Revenue)})
+	}
+
+	rh.respond(ctx, req.reportRequest, "sales-"+req.Interval, newPeriodSalesResponses(sales), table)
+}
+
+// topProductsReportRequest represents the query of the top products report
+type topProductsReportRequest struct {
+	reportRequest
+	Limit uint64 `form:"limit" validate:"omitempty,min=1,max=100" example:"10"`
+	By    string `form:"by" validate:"omitempty,oneof=quantity revenue" example:"quantity"`
+}
+
+// TopProductsReport godoc
+//
+//	@Summary		Top products
+//	@Description	The best selling products between two dates, both included, ranked by quantity or revenue net of refunds
+//	@Tags			Reports
+//	@Produce		json,text/csv
+//	@Param			from		query		string				true	"First day (YYYY-MM-DD)"
+//	@Param			to			query		string				true	"Last day (YYYY-MM-DD)"
+//	@Param			tz			query		string				false	"IANA timezone, ReportTimezone from config by default"
+//	@Param			category_id	query		uint64				false	"Only count products of this category"
+//	@Param			limit		query		uint64				false	"Number of products, 10 by default"
+//	@Param			by			query		string				false	"Ranking"	Enums(quantity, revenue)
+//	@Param			format		query		string				false	"Response format"	Enums(json, csv)
+//	@Success		200			{object}	reportResponse		"Report displayed"
+//	@Failure		400			{object}	errorValidResponse	"Validation error"
+//	@Failure		401			{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403			{object}	errorValidResponse	"Forbidden error"
+//	@Failure		500			{object}	errorValidResponse	"Internal server error"
+//	@Router			/reports/top-products [get]
+//	@Security		BearerAuth
+func (rh *ReportHandler) TopProductsReport(ctx *gin.Context) {
+	var req topProductsReportRequest
+
+	filter, ok := rh.bind(ctx, &req, &req.reportRequest)
+	if !ok {
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultTopProducts
+	}
+
+	products, err := rh.svc.TopProducts(ctx, filter, req.Limit, req.By == "revenue")
+	if err != nil {
+		rh.log.Error(err.Error())
+		rh.vs.handledbError(ctx, err)
+		return
+	}
+
+	table := [][]string{{"product_id", "name", "qty", "revenue"}}
+	for _, p := range products {
+		table = append(table, []string{strconv.FormatUint(p.ProductID, 10), p.Name, strconv.FormatInt(p.Quantity, 10), formatAmount(p.Revenue)})
+	}
+
+	rh.respond(ctx, req.reportRequest, "top-products", newProductSalesResponses(products), table)
+}
+
+// CashierReport godoc
+//
+//	@Summary		Sales per cashier
+//	@Description	Revenue, orders and items sold by each cashier between two dates, both included
+//	@Tags			Reports
+//	@Produce		json,text/csv
+//	@Param			from		query		string				true	"First day (YYYY-MM-DD)"
+//	@Param			to			query		string				true	"Last day (YYYY-MM-DD)"
+//	@Param			tz			query		string				false	"IANA timezone, ReportTimezone from config by default"
+//	@Param			category_id	query		uint64				false	"Only count products of this category"
+//	@Param			format		query		string				false	"Response format"	Enums(json, csv)
+//	@Success		200			{object}	reportResponse		"Report displayed"
+//	@Failure		400			{object}	errorValidResponse	"Validation error"
+//	@Failure		401			{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403			{object}	errorValidResponse	"Forbidden error"
+//	@Failure		500			{object}	errorValidResponse	"Internal server error"
+//	@Router			/reports/cashiers [get]
+//	@Security		BearerAuth
+func (rh *ReportHandler) CashierReport(ctx *gin.Context) {
+	var req reportRequest
+
+	filter, ok := rh.bind(ctx, &req, &req)
+	if !ok {
+		return
+	}
+
+	cashiers, err := rh.svc.SalesByCashier(ctx, filter)
+	if err != nil {
+		rh.log.Error(err.Error())
+		rh.vs.handledbError(ctx, err)
+		return
+	}
+
+	table := [][]string{{"user_id", "name", "orders", "items", "revenue"}}
+	for _, c := range cashiers {
+		table = append(table, []string{strconv.FormatUint(c.UserID, 10), c.Name, strconv.FormatInt(c.Orders, 10), strconv.FormatInt(c.Items, 10), formatAmount(c.Revenue)})
+	}
+
+	rh.respond(ctx, req, "cashiers", newCashierSalesResponses(cashiers), table)
+}
+
+// PaymentReport godoc
+//
+//	@Summary		Payment type mix
+//	@Description	Revenue and orders per payment type between two dates, both included, with each type's share of the revenue in percent
+//	@Tags			Reports
+//	@Produce		json,text/csv
+//	@Param			from		query		string				true	"First day (YYYY-MM-DD)"
+//	@Param			to			query		string				true	"Last day (YYYY-MM-DD)"
+//	@Param			tz			query		string				false	"IANA timezone, ReportTimezone from config by default"
+//	@Param			category_id	query		uint64				false	"Only count products of this category"
+//	@Param			format		query		string				false	"Response format"	Enums(json, csv)
+//	@Success		200			{object}	reportResponse		"Report displayed"
+//	@Failure		400			{object}	errorValidResponse	"Validation error"
+//	@Failure		401			{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403			{object}	errorValidResponse	"Forbidden error"
+//	@Failure		500			{object}	errorValidResponse	"Internal server error"
+//	@Router			/reports/payments [get]
+//	@Security		BearerAuth
+func (rh *ReportHandler) PaymentReport(ctx *gin.Context) {
+	var req reportRequest
+
+	filter, ok := rh.bind(ctx, &req, &req)
+	if !ok {
+		return
+	}
+
+	payments, err := rh.svc.SalesByPayment(ctx, filter)
+	if err != nil {
+		rh.log.Error(err.Error())
+		rh.vs.handledbError(ctx, err)
+		return
+	}
+
+	rows := newPaymentSalesResponses(payments)
+	table := [][]string{{"payment_id", "name", "type", "orders", "revenue", "share"}}
+	for _, p := range rows {
+		table = append(table, []string{strconv.FormatUint(p.PaymentID, 10), p.Name, string(p.Type), strconv.FormatInt(p.Orders, 10), formatAmount(p.Revenue), formatAmount(p.Share)})
+	}
+
+	rh.respond(ctx, req, "payments", rows, table)
+}
@@ -6,6 +6,8 @@ import (
 	
 	"gotemplate/core/domain"
 	"gotemplate/core/port"
+	repo "gotemplate/repo/postgres"
+	"math"
 	"net/http"
 	"time"
 
@@ -44,30 +46,78 @@ func newErrorResponse(message string) errorResponse {
 	}
 }
 
-// meta represents metadata for a paginated response
+// meta represents metadata for a paginated response.
+// The cursors are left out on the last and first pages and the total when it was not asked for.
 type meta struct {
-	Total uint64 `json:"total" example:"100"`
-	Limit uint64 `json:"limit" example:"10"`
-	Skip  uint64 `json:"skip" example:"0"`
+	Total          *uint64 `json:"total,omitempty" example:"100"`
+	TotalEstimated bool    `json:"total_estimated,omitempty" example:"false"`
+	Limit          uint64  `json:"limit" example:"10"`
+	NextCursor     string  `json:"next_cursor,omitempty" example:"eyJvIjoiaWQiLCJrIjpbIjIwIl19"`
+	PrevCursor     string  `json:"prev_cursor,omitempty" example:"eyJvIjoiaWQiLCJrIjpbIjExIl0sInAiOnRydWV9"`
 }
 
 // newMeta is a helper function to create metadata for a paginated response
-func newMeta(total, limit, skip uint64) meta {
+func newMeta(page repo.Page, limit uint64) meta {
 	return meta{
-		Total: total,
-		Limit: limit,
-		Skip:  skip,
+		Total:          page.Total,
+		TotalEstimated: page.Estimated,
+		Limit:          limit,
+		NextCursor:     page.Next,
+		PrevCursor:     page.Prev,
 	}
 }
 
+// authResponse represents an authentication response body
+type authResponse struct {
+	AccessToken  string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9"`
+	RefreshToken string `json:"refresh_token" example:"Zm9vYmFy"`
+}
+
+// newAuthResponse is a helper function to create a response body for handling authentication data
+func newAuthResponse(token, refreshToken string) authResponse {
+	return authResponse{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+	}
+}
+
+// sessionResponse represents a refresh token session response body
+type sessionResponse struct {
+	ID         string     `json:"id" example:"4979cf6e-d215-4ff8-9d0d-b3e99bcc7750"`
+	UserAgent  string     `json:"user_agent" example:"Mozilla/5.0"`
+	ClientIP   string     `json:"client_ip" example:"127.0.0.1"`
+	ExpiresAt  time.Time  `json:"expires_at" example:"1970-01-01T00:00:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at" example:"1970-01-01T00:00:00Z"`
+	CreatedAt  time.Time  `json:"created_at" example:"1970-01-01T00:00:00Z"`
+}
+
+// newSessionResponses is a helper function to create a response body for handling session data
+func newSessionResponses(sessions []domain.Session) []sessionResponse {
+	sessionResponses := make([]sessionResponse, 0, len(sessions))
+
+	for _, session := range sessions {
+		sessionResponses = append(sessionResponses, sessionResponse{
+			ID:         session.ID.String(),
+			UserAgent:  session.UserAgent,
+			ClientIP:   session.ClientIP,
+			ExpiresAt:  session.ExpiresAt,
+			LastUsedAt: session.LastUsedAt,
+			CreatedAt:  session.CreatedAt,
+		})
+	}
+
+	return sessionResponses
+}
+
 // userResponse represents a user response body
 type UserResponse struct {
-	ID          uint64 `json:"id" example:"1"`
-	Name        string `json:"name" example:"John Doe"`
-	Email       string `json:"email" example:"test@example.com"`
-	CreatedAt   string `json:"created_at" example:"1970-01-01T00:00:00Z"`
-	UpdatedAt   string `json:"updated_at" `
-	CreatedTime string `db:"created_time"  select:"created_time"`
+	ID          uint64          `json:"id" example:"1"`
+	Name        string          `json:"name" example:"John Doe"`
+	Email       string          `json:"email" example:"test@example.com"`
+	Role        domain.UserRole `json:"role" example:"cashier"`
+	CreatedAt   string          `json:"created_at" example:"1970-01-01T00:00:00Z"`
+	UpdatedAt   string          `json:"updated_at" `
+	CreatedTime string          `db:"created_time"  select:"created_time"`
 }
 
 // newUserResponse is a helper function to create a response body for handling user data
@@ -76,6 +126,7 @@ func newUserResponse(user *UserResponse) UserResponse {
 		ID:        user.ID,
 		Name:      user.Name,
 		Email:     user.Email,
+		Role:      user.Role,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 	}
@@ -86,6 +137,7 @@ func newUserResponse1(user UserResponse) UserResponse {
 		ID:          user.ID,
 		Name:        user.Name,
 		Email:       user.Email,
+		Role:        user.Role,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		CreatedTime: user.CreatedTime,
@@ -94,33 +146,43 @@ func newUserResponse1(user UserResponse) UserResponse {
 
 // paymentResponse represents a payment response body
 type paymentResponse struct {
-	ID   uint64             `json:"id" example:"1"`
-	Name string             `json:"name" example:"Tunai"`
-	Type domain.PaymentType `json:"type" example:"CASH"`
-	Logo string             `json:"logo" example:"https://example.com/cash.png"`
+	ID            uint64             `json:"id" example:"1"`
+	Name          string             `json:"name" example:"Tunai"`
+	Type          domain.PaymentType `json:"type" example:"CASH"`
+	Logo          string             `json:"logo" example:"https://example.com/cash.png"`
+	LogoThumbnail string             `json:"logo_thumbnail" example:"/uploads/payments/1/3f2a9c0d6b7e41a5-thumb.png"`
 }
 
 // newPaymentResponse is a helper function to create a response body for handling payment data
 func newPaymentResponse(payment *domain.Payment) paymentResponse {
+	if payment == nil {
+		return paymentResponse{}
+	}
 	return paymentResponse{
-		ID:   payment.ID,
-		Name: payment.Name,
-		Type: payment.Type,
-		Logo: payment.Logo,
+		ID:            payment.ID,
+		Name:          payment.Name,
+		Type:          payment.Type,
+		Logo:          payment.Logo,
+		LogoThumbnail: payment.LogoThumbnail,
 	}
 }
 
 // categoryResponse represents a category response body
 type categoryResponse struct {
-	ID   uint64 `json:"id" example:"1"`
-	Name string `json:"name" example:"Foods"`
+	ID      uint64   `json:"id" example:"1"`
+	Name    string   `json:"name" example:"Foods"`
+	TaxRate *float64 `json:"tax_rate" example:"11"`
 }
 
 // newCategoryResponse is a helper function to create a response body for handling category data
 func newCategoryResponse(category *domain.Category) categoryResponse {
+	if category == nil {
+		return categoryResponse{}
+	}
 	return categoryResponse{
-		ID:   category.ID,
-		Name: category.Name,
+		ID:      category.ID,
+		Name:    category.Name,
+		TaxRate: category.TaxRate,
 	}
 }
 
@@ -128,10 +190,13 @@ func newCategoryResponse(category *domain.Category) categoryResponse {
 type productResponse struct {
 	ID        uint64           `json:"id" example:"1"`
 	SKU       string           `json:"sku" example:"9a4c25d3-9786-492c-b084-85cb75c1ee3e"`
+	Barcode   *string          `json:"barcode,omitempty" example:"8991001101234"`
 	Name      string           `json:"name" example:"Chiki Ball"`
 	Stock     int64            `json:"stock" example:"100"`
 	Price     float64          `json:"price" example:"5000"`
 	Image     string           `json:"image" example:"https://example.com/chiki-ball.png"`
+	Thumbnail string           `json:"thumbnail" example:"/uploads/products/1/3f2a9c0d6b7e41a5-thumb.jpg"`
+	TaxRate   *float64         `json:"tax_rate" example:"11"`
 	Category  categoryResponse `json:"category"`
 	CreatedAt time.Time        `json:"created_at" example:"1970-01-01T00:00:00Z"`
 	UpdatedAt time.Time        `json:"updated_at" example:"1970-01-01T00:00:00Z"`
@@ -139,65 +204,139 @@ type productResponse struct {
 
 // newProductResponse is a helper function to create a response body for handling product data
 func newProductResponse(product *domain.Product) productResponse {
+	if product == nil {
+		return productResponse{}
+	}
 	return productResponse{
 		ID:        product.ID,
 		SKU:       product.SKU.String(),
+		Barcode:   product.Barcode,
 		Name:      product.Name,
 		Stock:     product.Stock,
 		Price:     product.Price,
 		Image:     product.Image,
+		Thumbnail: product.Thumbnail,
+		TaxRate:   product.TaxRate,
 		Category:  newCategoryResponse(product.Category),
 		CreatedAt: product.CreatedAt,
 		UpdatedAt: product.UpdatedAt,
 	}
 }
 
-// orderResponse represents an order response body
+// orderResponse represents an order response body.
+// TotalNormalPrice is the order at list prices and Discount what the promotions took off it.
+// TotalPrice includes TaxAmount, which was added to the prices unless TaxInclusive.
 type orderResponse struct {
-	ID           uint64                 `json:"id" example:"1"`
-	UserID       uint64                 `json:"user_id" example:"1"`
-	PaymentID    uint64                 `json:"payment_type_id" example:"1"`
-	CustomerName string                 `json:"customer_name" example:"John Doe"`
-	TotalPrice   float64                `json:"total_price" example:"100000"`
-	TotalPaid    float64                `json:"total_paid" example:"100000"`
-	TotalReturn  float64                `json:"total_return" example:"0"`
-	ReceiptCode  string                 `json:"receipt_id" example:"4979cf6e-d215-4ff8-9d0d-b3e99bcc7750"`
-	Products     []orderProductResponse `json:"products"`
-	PaymentType  paymentResponse        `json:"payment_type"`
-	CreatedAt    time.Time              `json:"created_at" example:"1970-01-01T00:00:00Z"`
-	UpdatedAt    time.Time              `json:"updated_at" example:"1970-01-01T00:00:00Z"`
+	ID               uint64                 `json:"id" example:"1"`
+	UserID           uint64                 `json:"user_id" example:"1"`
+	PaymentID        uint64                 `json:"payment_type_id" example:"1"`
+	ShiftID          *uint64                `json:"shift_id,omitempty" example:"1"`
+	CustomerName     string                 `json:"customer_name" example:"John Doe"`
+	TotalNormalPrice float64                `json:"total_normal_price" example:"110000"`
+	Discount         float64                `json:"discount" example:"10000"`
+	TotalPrice       float64                `json:"total_price" example:"100000"`
+	TotalPaid        float64                `json:"total_paid" example:"100000"`
+	TotalReturn      float64                `json:"total_return" example:"0"`
+	ReceiptCode      string                 `json:"receipt_id" example:"4979cf6e-d215-4ff8-9d0d-b3e99bcc7750"`
+	Status           domain.OrderStatus     `json:"status" example:"paid"`
+	CancelledBy      *uint64                `json:"cancelled_by,omitempty" example:"1"`
+	CancelledAt      *time.Time             `json:"cancelled_at,omitempty" example:"1970-01-01T00:00:00Z"`
+	CancelReason     *string                `json:"cancel_reason,omitempty" example:"Customer changed their mind"`
+	PromotionID      *uint64                `json:"promotion_id,omitempty" example:"1"`
+	Promotion        *promotionResponse     `json:"promotion,omitempty"`
+	TaxAmount        float64                `json:"tax_amount" example:"11000"`
+	TaxInclusive     bool                   `json:"tax_inclusive" example:"false"`
+	TaxRounding      domain.TaxRounding     `json:"tax_rounding" example:"line"`
+	Taxes            []taxLineResponse      `json:"taxes"`
+	Products         []orderProductResponse `json:"products"`
+	PaymentType      paymentResponse        `json:"payment_type"`
+	Cashier          cashierResponse        `json:"cashier"`
+	CreatedAt        time.Time              `json:"created_at" example:"1970-01-01T00:00:00Z"`
+	UpdatedAt        time.Time              `json:"updated_at" example:"1970-01-01T00:00:00Z"`
 }
 
 // newOrderResponse is a helper function to create a response body for handling order data
 func newOrderResponse(order *domain.Order) orderResponse {
 	return orderResponse{
-		ID:           order.ID,
-		UserID:       order.UserID,
-		PaymentID:    order.PaymentID,
-		CustomerName: order.CustomerName,
-		TotalPrice:   order.TotalPrice,
-		TotalPaid:    order.TotalPaid,
-		TotalReturn:  order.TotalReturn,
-		ReceiptCode:  order.ReceiptCode.String(),
-		Products:     newOrderProductResponse(order.Products),
-		PaymentType:  newPaymentResponse(order.Payment),
-		CreatedAt:    order.CreatedAt,
-		UpdatedAt:    order.UpdatedAt,
-	}
-}
-
-// orderProductResponse represents an order product response body
+		ID:               order.ID,
+		UserID:           order.UserID,
+		PaymentID:        order.PaymentID,
+		ShiftID:          order.ShiftID,
+		CustomerName:     order.CustomerName,
+		TotalNormalPrice: order.TotalNormalPrice,
+		Discount:         order.Discount,
+		TotalPrice:       order.TotalPrice,
+		TotalPaid:        order.TotalPaid,
+		TotalReturn:      order.TotalReturn,
+		ReceiptCode:      order.ReceiptCode.String(),
+		Status:           order.Status,
+		CancelledBy:      order.CancelledBy,
+		CancelledAt:      order.CancelledAt,
+		CancelReason:     order.CancelReason,
+		PromotionID:      order.PromotionID,
+		Promotion:        newPromotionSummary(order.Promotion),
+		TaxAmount:        order.TaxAmount,
+		TaxInclusive:     order.TaxInclusive,
+		TaxRounding:      order.TaxRounding,
+		Taxes:            newTaxLineResponses(order),
+		Products:         newOrderProductResponse(order.Products),
+		PaymentType:      newPaymentResponse(order.Payment),
+		Cashier:          newCashierResponse(order.User),
+		CreatedAt:        order.CreatedAt,
+		UpdatedAt:        order.UpdatedAt,
+	}
+}
+
+// newOrderResponses is a helper function to create a response body for handling a list of orders
+func newOrderResponses(orders []domain.Order) []orderResponse {
+	orderResponses := make([]orderResponse, 0, len(orders))
+
+	for i := range orders {
+		orderResponses = append(orderResponses, newOrderResponse(&orders[i]))
+	}
+
+	return orderResponses
+}
+
+// cashierResponse represents the user who created an order
+type cashierResponse struct {
+	ID   uint64          `json:"id" example:"1"`
+	Name string          `json:"name" example:"John Doe"`
+	Role domain.UserRole `json:"role" example:"cashier"`
+}
+
+// newCashierResponse is a helper function to create a response body for handling the cashier of an order
+func newCashierResponse(user *domain.User) cashierResponse {
+	if user == nil {
+		return cashierResponse{}
+	}
+	return cashierResponse{
+		ID:   user.ID,
+		Name: user.Name,
+		Role: user.Role,
+	}
+}
+
+// orderProductResponse represents an order product response body.
+// Price is the unit list price at the time of the order, TotalNormalPrice the line at that price
+// and TotalFinalPrice the line after its Discount, TaxAmount included.
 type orderProductResponse struct {
-	ID               uint64          `json:"id" example:"1"`
-	OrderID          uint64          `json:"order_id" example:"1"`
-	ProductID        uint64          `json:"product_id" example:"1"`
-	Quantity         int64           `json:"qty" example:"1"`
-	Price            float64         `json:"price" example:"100000"`
-	TotalNormalPrice float64         `json:"total_normal_price" example:"100000"`
-	TotalFinalPrice  float64         `json:"total_final_price" example:"100000"`
-	Product          productResponse `json:"product"`
-	CreatedAt        time.Time       `json:"created_at" example:"1970-01-01T00:00:00Z"`
-	UpdatedAt        time.Time       `json:"updated_at" example:"1970-01-01T00:00:00Z"`
+	ID               uint64             `json:"id" example:"1"`
+	OrderID          uint64             `json:"order_id" example:"1"`
+	ProductID        uint64             `json:"product_id" example:"1"`
+	Quantity         int64              `json:"qty" example:"1"`
+	RefundedQuantity int64              `json:"refunded_qty" example:"0"`
+	Price            float64            `json:"price" example:"110000"`
+	TotalNormalPrice float64            `json:"total_normal_price" example:"110000"`
+	Discount         float64            `json:"discount" example:"10000"`
+	TaxRate          float64            `json:"tax_rate" example:"11"`
+	TaxAmount        float64            `json:"tax_amount" example:"11000"`
+	TotalFinalPrice  float64            `json:"total_final_price" example:"111000"`
+	PromotionID      *uint64            `json:"promotion_id,omitempty" example:"1"`
+	Promotion        *promotionResponse `json:"promotion,omitempty"`
+	Product          productResponse    `json:"product"`
+	CreatedAt        time.Time          `json:"created_at" example:"1970-01-01T00:00:00Z"`
+	UpdatedAt        time.Time          `json:"updated_at" example:"1970-01-01T00:00:00Z"`
 }
 
 // newOrderProductResponse is a helper function to create a response body for handling order product data
@@ -205,14 +344,28 @@ func newOrderProductResponse(orderProduct []domain.OrderProduct) []orderProductR
 	var orderProductResponses []orderProductResponse
 
 	for _, orderProduct := range orderProduct {
+		var price float64
+		switch {
+		case orderProduct.Quantity > 0:
+			price = orderProduct.NormalPrice / float64(orderProduct.Quantity)
+		case orderProduct.Product != nil:
+			price = orderProduct.Product.Price
+		}
+
 		orderProductResponses = append(orderProductResponses, orderProductResponse{
 			ID:               orderProduct.ID,
 			OrderID:          orderProduct.OrderID,
 			ProductID:        orderProduct.ProductID,
 			Quantity:         orderProduct.Quantity,
-			Price:            orderProduct.Product.Price,
-			TotalNormalPrice: orderProduct.TotalPrice,
+			RefundedQuantity: orderProduct.RefundedQuantity,
+			Price:            price,
+			TotalNormalPrice: orderProduct.NormalPrice,
+			Discount:         orderProduct.Discount,
+			TaxRate:          orderProduct.TaxRate,
+			TaxAmount:        orderProduct.TaxAmount,
 			TotalFinalPrice:  orderProduct.TotalPrice,
+			PromotionID:      orderProduct.PromotionID,
+			Promotion:        newPromotionSummary(orderProduct.Promotion),
 			Product:          newProductResponse(orderProduct.Product),
 			CreatedAt:        orderProduct.CreatedAt,
 			UpdatedAt:        orderProduct.UpdatedAt,
@@ -222,6 +375,465 @@ func newOrderProductResponse(orderProduct []domain.OrderProduct) []orderProductR
 	return orderProductResponses
 }
 
+// promotionResponse represents a promotion response body
+type promotionResponse struct {
+	ID          uint64                `json:"id" example:"1"`
+	Name        string                `json:"name" example:"Weekend 10% off"`
+	Kind        domain.PromotionKind  `json:"kind" example:"percentage"`
+	Scope       domain.PromotionScope `json:"scope" example:"category"`
+	TargetID    *uint64               `json:"target_id,omitempty" example:"1"`
+	Value       float64               `json:"value" example:"10"`
+	BuyQuantity int64                 `json:"buy_qty,omitempty" example:"0"`
+	GetQuantity int64                 `json:"get_qty,omitempty" example:"0"`
+	MinSubtotal float64               `json:"min_subtotal,omitempty" example:"0"`
+	StartsAt    *time.Time            `json:"starts_at,omitempty" example:"1970-01-01T00:00:00Z"`
+	EndsAt      *time.Time            `json:"ends_at,omitempty" example:"1970-01-01T00:00:00Z"`
+	Active      bool                  `json:"active" example:"true"`
+	CreatedAt   *time.Time            `json:"created_at,omitempty" example:"1970-01-01T00:00:00Z"`
+	UpdatedAt   *time.Time            `json:"updated_at,omitempty" example:"1970-01-01T00:00:00Z"`
+}
+
+// newPromotionResponse is a helper function to create a response body for handling promotion data
+func newPromotionResponse(promotion *domain.Promotion) promotionResponse {
+	response := *newPromotionSummary(promotion)
+	response.CreatedAt = &promotion.CreatedAt
+	response.UpdatedAt = &promotion.UpdatedAt
+	return response
+}
+
+// newPromotionSummary is a helper function to create the promotion of an order or a line, nil when none applied
+func newPromotionSummary(promotion *domain.Promotion) *promotionResponse {
+	if promotion == nil {
+		return nil
+	}
+	return &promotionResponse{
+		ID:          promotion.ID,
+		Name:        promotion.Name,
+		Kind:        promotion.Kind,
+		Scope:       promotion.Scope,
+		TargetID:    promotion.TargetID,
+		Value:       promotion.Value,
+		BuyQuantity: promotion.BuyQuantity,
+		GetQuantity: promotion.GetQuantity,
+		MinSubtotal: promotion.MinSubtotal,
+		StartsAt:    promotion.StartsAt,
+		EndsAt:      promotion.EndsAt,
+		Active:      promotion.Active,
+	}
+}
+
+// newPromotionResponses is a helper function to create a response body for handling a list of promotions
+func newPromotionResponses(promotions []domain.Promotion) []promotionResponse {
+	promotionResponses := make([]promotionResponse, 0, len(promotions))
+
+	for i := range promotions {
+		promotionResponses = append(promotionResponses, newPromotionResponse(&promotions[i]))
+	}
+
+	return promotionResponses
+}
+
+// taxLineResponse represents the tax of an order at one rate
+type taxLineResponse struct {
+	Rate    float64 `json:"rate" example:"11"`
+	Taxable float64 `json:"taxable" example:"100000"`
+	Amount  float64 `json:"amount" example:"11000"`
+}
+
+// newTaxLineResponses is a helper function to create the tax breakdown of an order
+func newTaxLineResponses(order *domain.Order) []taxLineResponse {
+	breakdown := order.TaxBreakdown()
+	taxes := make([]taxLineResponse, 0, len(breakdown))
+
+	for _, tax := range breakdown {
+		taxes = append(taxes, taxLineResponse{
+			Rate:    tax.Rate,
+			Taxable: tax.Taxable,
+			Amount:  tax.Amount,
+		})
+	}
+
+	return taxes
+}
+
+// orderPreviewResponse represents the pricing of a cart before it is ordered
+type orderPreviewResponse struct {
+	TotalNormalPrice float64                `json:"total_normal_price" example:"110000"`
+	Discount         float64                `json:"discount" example:"10000"`
+	TaxAmount        float64                `json:"tax_amount" example:"11000"`
+	TaxInclusive     bool                   `json:"tax_inclusive" example:"false"`
+	Taxes            []taxLineResponse      `json:"taxes"`
+	TotalPrice       float64                `json:"total_price" example:"111000"`
+	PromotionID      *uint64                `json:"promotion_id,omitempty" example:"1"`
+	Promotion        *promotionResponse     `json:"promotion,omitempty"`
+	Products         []orderProductResponse `json:"products"`
+}
+
+// newOrderPreviewResponse is a helper function to create a response body for handling a priced cart
+func newOrderPreviewResponse(order *domain.Order) orderPreviewResponse {
+	return orderPreviewResponse{
+		TotalNormalPrice: order.TotalNormalPrice,
+		Discount:         order.Discount,
+		TaxAmount:        order.TaxAmount,
+		TaxInclusive:     order.TaxInclusive,
+		Taxes:            newTaxLineResponses(order),
+		TotalPrice:       order.TotalPrice,
+		PromotionID:      order.PromotionID,
+		Promotion:        newPromotionSummary(order.Promotion),
+		Products:         newOrderProductResponse(order.Products),
+	}
+}
+
+// heldOrderProductResponse represents a line of a held order response body
+type heldOrderProductResponse struct {
+	ID        uint64          `json:"id" example:"1"`
+	ProductID uint64          `json:"product_id" example:"1"`
+	Quantity  int64           `json:"qty" example:"1"`
+	Product   productResponse `json:"product"`
+}
+
+// heldOrderResponse represents a held order response body.
+// Cart prices the held lines at the current prices, promotions and tax rates and is left out of listings.
+type heldOrderResponse struct {
+	ID           uint64                     `json:"id" example:"1"`
+	UserID       uint64                     `json:"user_id" example:"1"`
+	Terminal     string                     `json:"terminal" example:"till-1"`
+	CustomerName string                     `json:"customer_name" example:"John Doe"`
+	Note         *string                    `json:"note,omitempty" example:"Fetching a second bag"`
+	Products     []heldOrderProductResponse `json:"products"`
+	Cart         *orderPreviewResponse      `json:"cart,omitempty"`
+	CreatedAt    time.Time                  `json:"created_at" example:"1970-01-01T00:00:00Z"`
+	UpdatedAt    time.Time                  `json:"updated_at" example:"1970-01-01T00:00:00Z"`
+	ExpiresAt    time.Time                  `json:"expires_at" example:"1970-01-01T08:00:00Z"`
+}
+
+// newHeldOrderResponse is a helper function to create a response body for handling held order data,
+// with the held lines priced as cart when it is not nil
+func newHeldOrderResponse(held *domain.HeldOrder, cart *domain.Order) heldOrderResponse {
+	products := make([]heldOrderProductResponse, 0, len(held.Products))
+	for _, line := range held.Products {
+		products = append(products, heldOrderProductResponse{
+			ID:        line.ID,
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+			Product:   newProductResponse(line.Product),
+		})
+	}
+
+	rsp := heldOrderResponse{
+		ID:           held.ID,
+		UserID:       held.UserID,
+		Terminal:     held.Terminal,
+		CustomerName: held.CustomerName,
+		Note:         held.Note,
+		Products:     products,
+		CreatedAt:    held.CreatedAt,
+		UpdatedAt:    held.UpdatedAt,
+		ExpiresAt:    held.ExpiresAt,
+	}
+	if cart != nil {
+		preview := newOrderPreviewResponse(cart)
+		rsp.Cart = &preview
+	}
+
+	return rsp
+}
+
+// newHeldOrderResponses is a helper function to create a response body for handling a list of held orders
+func newHeldOrderResponses(helds []domain.HeldOrder) []heldOrderResponse {
+	heldOrderResponses := make([]heldOrderResponse, 0, len(helds))
+
+	for i := range helds {
+		heldOrderResponses = append(heldOrderResponses, newHeldOrderResponse(&helds[i], nil))
+	}
+
+	return heldOrderResponses
+}
+
+// refundProductResponse represents a refunded order line response body
+type refundProductResponse struct {
+	ID             uint64  `json:"id" example:"1"`
+	OrderProductID uint64  `json:"order_product_id" example:"1"`
+	ProductID      uint64  `json:"product_id" example:"1"`
+	Quantity       int64   `json:"qty" example:"1"`
+	Amount         float64 `json:"amount" example:"5000"`
+}
+
+// refundResponse represents a refund response body
+type refundResponse struct {
+	ID        uint64                  `json:"id" example:"1"`
+	OrderID   uint64                  `json:"order_id" example:"1"`
+	UserID    uint64                  `json:"user_id" example:"1"`
+	ShiftID   *uint64                 `json:"shift_id,omitempty" example:"1"`
+	Reason    string                  `json:"reason" example:"Damaged item"`
+	Amount    float64                 `json:"amount" example:"5000"`
+	Products  []refundProductResponse `json:"products"`
+	Order     orderResponse           `json:"order"`
+	CreatedAt time.Time               `json:"created_at" example:"1970-01-01T00:00:00Z"`
+}
+
+// newRefundResponse is a helper function to create a response body for handling refund data
+func newRefundResponse(refund *domain.Refund) refundResponse {
+	products := make([]refundProductResponse, 0, len(refund.Products))
+	for _, product := range refund.Products {
+		products = append(products, refundProductResponse{
+			ID:             product.ID,
+			OrderProductID: product.OrderProductID,
+			ProductID:      product.ProductID,
+			Quantity:       product.Quantity,
+			Amount:         product.Amount,
+		})
+	}
+
+	rsp := refundResponse{
+		ID:        refund.ID,
+		OrderID:   refund.OrderID,
+		UserID:    refund.UserID,
+		ShiftID:   refund.ShiftID,
+		Reason:    refund.Reason,
+		Amount:    refund.Amount,
+		Products:  products,
+		CreatedAt: refund.CreatedAt,
+	}
+	if refund.Order != nil {
+		rsp.Order = newOrderResponse(refund.Order)
+	}
+	return rsp
+}
+
+// reportResponse represents a sales report response body
+type reportResponse struct {
+	From       string `json:"from" example:"2024-01-01"`
+	To         string `json:"to" example:"2024-01-31"`
+	Timezone   string `json:"timezone" example:"Asia/Jakarta"`
+	CategoryID uint64 `json:"category_id,omitempty" example:"1"`
+	Rows       any    `json:"rows"`
+}
+
+// periodSalesResponse represents the sales of a day, week or month
+type periodSalesResponse struct {
+	Period  string  `json:"period" example:"2024-01-01"`
+	Orders  int64   `json:"orders" example:"12"`
+	Items   int64   `json:"items" example:"30"`
+	Revenue float64 `json:"revenue" example:"150000"`
+}
+
+// newPeriodSalesResponses is a helper function to create a response body for handling sales per period
+func newPeriodSalesResponses(sales []domain.PeriodSales) []periodSalesResponse {
+	rsp := make([]periodSalesResponse, 0, len(sales))
+	for _, s := range sales {
+		rsp = append(rsp, periodSalesResponse(s))
+	}
+	return rsp
+}
+
+// productSalesResponse represents the sales of a product
+type productSalesResponse struct {
+	ProductID uint64  `json:"product_id" example:"1"`
+	Name      string  `json:"name" example:"Chiki Ball"`
+	Quantity  int64   `json:"qty" example:"30"`
+	Revenue   float64 `json:"revenue" example:"150000"`
+}
+
+// newProductSalesResponses is a helper function to create a response body for handling top products
+func newProductSalesResponses(sales []domain.ProductSales) []productSalesResponse {
+	rsp := make([]productSalesResponse, 0, len(sales))
+	for _, s := range sales {
+		rsp = append(rsp, productSalesResponse(s))
+	}
+	return rsp
+}
+
+// cashierSalesResponse represents the sales taken by a cashier
+type cashierSalesResponse struct {
+	UserID  uint64  `json:"user_id" example:"2"`
+	Name    string  `json:"name" example:"John Doe"`
+	Orders  int64   `json:"orders" example:"12"`
+	Items   int64   `json:"items" example:"30"`
+	Revenue float64 `json:"revenue" example:"150000"`
+}
+
+// newCashierSalesResponses is a helper function to create a response body for handling sales per cashier
+func newCashierSalesResponses(sales []domain.CashierSales) []cashierSalesResponse {
+	rsp := make([]cashierSalesResponse, 0, len(sales))
+	for _, s := range sales {
+		rsp = append(rsp, cashierSalesResponse(s))
+	}
+	return rsp
+}
+
+// paymentSalesResponse represents the sales paid with a payment type and their share of the revenue
+type paymentSalesResponse struct {
+	PaymentID uint64             `json:"payment_id" example:"1"`
+	Name      string             `json:"name" example:"Tunai"`
+	Type      domain.PaymentType `json:"type" example:"CASH"`
+	Orders    int64              `json:"orders" example:"12"`
+	Revenue   float64            `json:"revenue" example:"150000"`
+	Share     float64            `json:"share" example:"62.5"`
+}
+
+// newPaymentSalesResponses is a helper function to create a response body for handling the payment type mix
+func newPaymentSalesResponses(sales []domain.PaymentSales) []paymentSalesResponse {
+	var total float64
+	for _, s := range sales {
+		total += s.Revenue
+	}
+
+	rsp := make([]paymentSalesResponse, 0, len(sales))
+	for _, s := range sales {
+		var share float64
+		if total > 0 {
+			share = math.Round(s.Revenue/total*10000) / 100
+		}
+		rsp = append(rsp, paymentSalesResponse{
+			PaymentID: s.PaymentID,
+			Name:      s.Name,
+			Type:      s.Type,
+			Orders:    s.Orders,
+			Revenue:   s.Revenue,
+			Share:     share,
+		})
+	}
+	return rsp
+}
+
+// shiftResponse represents a cashier shift response body
+type shiftResponse struct {
+	ID           uint64     `json:"id" example:"1"`
+	UserID       uint64     `json:"user_id" example:"1"`
+	OpeningFloat float64    `json:"opening_float" example:"50000"`
+	ExpectedCash *float64   `json:"expected_cash,omitempty" example:"105000"`
+	CountedCash  *float64   `json:"counted_cash,omitempty" example:"104500"`
+	Variance     *float64   `json:"variance,omitempty" example:"-500"`
+	Note         *string    `json:"note,omitempty" example:"Short one 500 coin"`
+	Open         bool       `json:"open" example:"false"`
+	OpenedAt     time.Time  `json:"opened_at" example:"1970-01-01T00:00:00Z"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty" example:"1970-01-01T00:00:00Z"`
+	ClosedBy     *uint64    `json:"closed_by,omitempty" example:"1"`
+}
+
+// newShiftResponse is a helper function to create a response body for handling shift data
+func newShiftResponse(shift *domain.Shift) shiftResponse {
+	return shiftResponse{
+		ID:           shift.ID,
+		UserID:       shift.UserID,
+		OpeningFloat: shift.OpeningFloat,
+		ExpectedCash: shift.ExpectedCash,
+		CountedCash:  shift.CountedCash,
+		Variance:     shift.Variance,
+		Note:         shift.Note,
+		Open:         shift.IsOpen(),
+		OpenedAt:     shift.OpenedAt,
+		ClosedAt:     shift.ClosedAt,
+		ClosedBy:     shift.ClosedBy,
+	}
+}
+
+// paymentTotalResponse represents the sales and refunds of a payment type in a Z-report
+type paymentTotalResponse struct {
+	PaymentID uint64             `json:"payment_id" example:"1"`
+	Name      string             `json:"name" example:"Tunai"`
+	Type      domain.PaymentType `json:"type" example:"CASH"`
+	Orders    int64              `json:"orders" example:"12"`
+	Sales     float64            `json:"sales" example:"150000"`
+	Refunds   float64            `json:"refunds" example:"5000"`
+}
+
+// zReportResponse represents a Z-report response body
+type zReportResponse struct {
+	Date            string                 `json:"date,omitempty" example:"2024-01-01"`
+	Timezone        string                 `json:"timezone,omitempty" example:"Asia/Jakarta"`
+	Shifts          []shiftResponse        `json:"shifts"`
+	Orders          int64                  `json:"orders" example:"12"`
+	GrossSales      float64                `json:"gross_sales" example:"150000"`
+	Refunds         int64                  `json:"refunds" example:"1"`
+	RefundAmount    float64                `json:"refund_amount" example:"5000"`
+	NetSales        float64                `json:"net_sales" example:"145000"`
+	Cancelled       int64                  `json:"cancelled" example:"1"`
+	CancelledAmount float64                `json:"cancelled_amount" example:"2500"`
+	Payments        []paymentTotalResponse `json:"payments"`
+	OpeningFloat    float64                `json:"opening_float" example:"50000"`
+	CashSales       float64                `json:"cash_sales" example:"100000"`
+	CashRefunds     float64                `json:"cash_refunds" example:"5000"`
+	ExpectedCash    float64                `json:"expected_cash" example:"145000"`
+	CountedCash     *float64               `json:"counted_cash" example:"144500"`
+	Variance        *float64               `json:"variance" example:"-500"`
+}
+
+// newZReportResponse is a helper function to create a response body for handling a Z-report
+func newZReportResponse(report *domain.ZReport) zReportResponse {
+	shifts := make([]shiftResponse, 0, len(report.Shifts))
+	for i := range report.Shifts {
+		shifts = append(shifts, newShiftResponse(&report.Shifts[i]))
+	}
+
+	payments := make([]paymentTotalResponse, 0, len(report.Payments))
+	for _, p := range report.Payments {
+		payments = append(payments, paymentTotalResponse(p))
+	}
+
+	return zReportResponse{
+		Shifts:          shifts,
+		Orders:          report.Orders,
+		GrossSales:      report.GrossSales,
+		Refunds:         report.Refunds,
+		RefundAmount:    report.RefundAmount,
+		NetSales:        report.NetSales,
+		Cancelled:       report.Cancelled,
+		CancelledAmount: report.CancelledAmount,
+		Payments:        payments,
+		OpeningFloat:    report.OpeningFloat,
+		CashSales:       report.CashSales,
+		CashRefunds:     report.CashRefunds,
+		ExpectedCash:    report.ExpectedCash,
+		CountedCash:     report.CountedCash,
+		Variance:        report.Variance,
+	}
+}
+
+// stockMovementResponse represents a stock movement response body
+type stockMovementResponse struct {
+	ID         uint64             `json:"id" example:"1"`
+	ProductID  uint64             `json:"product_id" example:"1"`
+	Source     domain.StockSource `json:"source" example:"adjustment"`
+	Quantity   int64              `json:"quantity" example:"-2"`
+	StockAfter int64              `json:"stock_after" example:"98"`
+	UserID     *uint64            `json:"user_id,omitempty" example:"1"`
+	OrderID    *uint64            `json:"order_id,omitempty" example:"1"`
+	RefundID   *uint64            `json:"refund_id,omitempty" example:"1"`
+	Reference  *string            `json:"reference,omitempty" example:"DN-2024-0012"`
+	Reason     *string            `json:"reason,omitempty" example:"Damaged in storage"`
+	CreatedAt  time.Time          `json:"created_at" example:"1970-01-01T00:00:00Z"`
+}
+
+// newStockMovementResponse is a helper function to create a response body for handling stock movement data
+func newStockMovementResponse(movement *domain.StockMovement) stockMovementResponse {
+	return stockMovementResponse{
+		ID:         movement.ID,
+		ProductID:  movement.ProductID,
+		Source:     movement.Source,
+		Quantity:   movement.Quantity,
+		StockAfter: movement.StockAfter,
+		UserID:     movement.UserID,
+		OrderID:    movement.OrderID,
+		RefundID:   movement.RefundID,
+		Reference:  movement.Reference,
+		Reason:     movement.Reason,
+		CreatedAt:  movement.CreatedAt,
+	}
+}
+
+// newStockMovementResponses is a helper function to create a response body for handling a list of stock movements
+func newStockMovementResponses(movements []domain.StockMovement) []stockMovementResponse {
+	stockMovementResponses := make([]stockMovementResponse, 0, len(movements))
+
+	for i := range movements {
+		stockMovementResponses = append(stockMovementResponses, newStockMovementResponse(&movements[i]))
+	}
+
+	return stockMovementResponses
+}
+
 // errorStatusMap is a map of defined error messages and their corresponding http status codes
 var errorStatusMap = map[error]int{
 	port.ErrDataNotFound:               http.StatusNotFound,
@@ -234,9 +846,21 @@ var errorStatusMap = map[error]int{
 	port.ErrInvalidToken:               http.StatusUnauthorized,
 	port.ErrExpiredToken:               http.StatusUnauthorized,
 	port.ErrForbidden:                  http.StatusForbidden,
+	port.ErrTooManyRequests:            http.StatusTooManyRequests,
+	port.ErrNotAcceptable:              http.StatusNotAcceptable,
+	port.ErrInvalidOrderStatus:         http.StatusConflict,
+	port.ErrRefundExceedsQuantity:      http.StatusBadRequest,
+	port.ErrInvalidIdempotencyKey:      http.StatusBadRequest,
+	port.ErrIdempotencyKeyMismatch:     http.StatusUnprocessableEntity,
+	port.ErrIdempotencyKeyInUse:        http.StatusConflict,
+	port.ErrShiftAlreadyOpen:           http.StatusConflict,
+	port.ErrShiftClosed:                http.StatusConflict,
 	port.ErrNoUpdatedData:              http.StatusBadRequest,
 	port.ErrInsufficientStock:          http.StatusBadRequest,
 	port.ErrInsufficientPayment:        http.StatusBadRequest,
+	port.ErrInvalidCursor:              http.StatusBadRequest,
+	port.ErrImageTooLarge:              http.StatusRequestEntityTooLarge,
+	port.ErrUnsupportedImage:           http.StatusUnsupportedMediaType,
 }
 
 // validationError sends an error response for some specific request validation error
@@ -253,7 +877,7 @@ func handleAbort(ctx *gin.Context, err error, errorno string) {
 		statusCode = http.StatusInternalServerError
 	}
 
-	rsp := newErrorResponse(err.Error())
+	rsp := newErrorValidResponse([]string{err.Error()}, []string{errorno})
 	ctx.AbortWithStatusJSON(statusCode, rsp)
 }
 
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"gotemplate/config"
 	_ "gotemplate/docs"
+	"gotemplate/storage"
+	"gotemplate/token"
+	"strings"
 	"sync/atomic"
-	"github.com/gin-contrib/pprof"
 	//"io"
 	"net/http"
 	//"os"
@@ -13,8 +15,6 @@ import (
 	
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 // Router is a wrapper for HTTP router
@@ -47,11 +47,20 @@ func HealthCheckHandler(c *gin.Context) {
 // NewRouter creates a new HTTP router
 func NewRouter(
 	cfg config.Econfig,
+	tokenMaker *token.JWTMaker,
+	rateLimiter *RateLimiter,
+	idempotency *Idempotency,
+	authHandler AuthHandler,
 	userHandler UserHandler,
 	paymentHandler PaymentHandler,
 	categoryHandler CategoryHandler,
 	productHandler ProductHandler,
 	orderHandler OrderHandler,
+	receiptHandler ReceiptHandler,
+	reportHandler ReportHandler,
+	shiftHandler ShiftHandler,
+	promotionHandler PromotionHandler,
+	stockHandler StockHandler,
 	bagHander BagHandler,
 
 ) (*Router, error) {
@@ -68,13 +77,10 @@ func NewRouter(
 	}
 
 	// CORS
-	config := cors.DefaultConfig()
-
-	config.AllowAllOrigins = true
-	config.AllowCredentials = true
-	config.AllowHeaders = []string{"*"}
-	config.AllowBrowserExtensions = true
-	config.AllowMethods = []string{"*"}
+	corsConfig, err := newCorsConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	router := gin.New()
 	router.RedirectTrailingSlash = false
@@ -96,6 +102,8 @@ func NewRouter(
 
 	})
 
+	router.Use(cors.New(corsConfig), rateLimiter.PerIP())
+
 	//r.Use( ValidateContentType( []string{"application/json", "application/xml"}   )   )
 
 	//router.Use(gin.LoggerWithFormatter(customLogger), gin.Recovery(), cors.New(config))
@@ -133,27 +141,51 @@ func NewRouter(
 
 		}*/
 
-	// Swagger
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	router.GET("/healthz", HealthCheckHandler)
-	pprof.Register(router)
+
+	// Files of the local storage backend, other backends serving their own
+	if backend := cfg.StorageBackend(); (backend == "" || backend == storage.LocalBackend) && strings.HasPrefix(cfg.StoragePublicURL(), "/") {
+		router.Static(cfg.StoragePublicURL(), cfg.StorageLocalDir())
+	}
+
+	// Swagger and pprof are served by the admin router when AdminPort is set
+	if cfg.AdminPort() == "" {
+		registerDiagnostics(&router.RouterGroup, cfg, adminGuard(tokenMaker))
+	}
+
 	v1 := router.Group("/v1")
-	{ // @Router /users
-		user := v1.Group("/users")
+	{
+		auth := v1.Group("/auth")
+		auth.Use(rateLimiter.PerGroup(auth.BasePath()))
 		{
-			user.POST("/", userHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
 
-			authUser := user.Group("/")
+			session := auth.Group("/sessions").Use(authMiddleware(tokenMaker), rateLimiter.PerUser())
+			{
+				session.GET("/", authHandler.ListSessions)
+				session.DELETE("/:id", authHandler.RevokeSession)
+			}
+		}
+
+		// @Router /users
+		// Users are created by admins, the first admin coming from AdminEmail in the config
+		user := v1.Group("/users")
+		{
+			authUser := user.Group("/").Use(authMiddleware(tokenMaker), authorize(user.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(user.BasePath()))
 			{
+				authUser.POST("/", userHandler.Register)
 				authUser.GET("/", userHandler.ListUsers)
 				authUser.GET("/:id", userHandler.GetUser)
 				authUser.PUT("/:id", userHandler.UpdateUser)
 				authUser.DELETE("/:id", userHandler.DeleteUser)
+				authUser.DELETE("/:id/sessions", authHandler.RevokeUserSessions)
 
 			}
 		}
 
 		bag := v1.Group("/bags")
+		bag.Use(authMiddleware(tokenMaker), authorize(bag.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(bag.BasePath()))
 		{
 			bag.GET("/:id", bagHander.GetBag)
 			bag.GET("/", bagHander.ListBags)
@@ -170,16 +202,19 @@ func NewRouter(
 		}
 
 		payment := v1.Group("/payments")
+		payment.Use(authMiddleware(tokenMaker), authorize(payment.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(payment.BasePath()))
 		{
 			payment.GET("/", paymentHandler.ListPayments)
 			payment.GET("/:id", paymentHandler.GetPayment)
-			payment.POST("/", paymentHandler.CreatePayment)
+			payment.POST("/", idempotency.Middleware(), paymentHandler.CreatePayment)
 			payment.PUT("/:id", paymentHandler.UpdatePayment)
+			payment.POST("/:id/logo", paymentHandler.UploadPaymentLogo)
 			payment.DELETE("/:id", paymentHandler.DeletePayment)
 
 		}
 		// @Router /categories
 		category := v1.Group("/categories")
+		category.Use(authMiddleware(tokenMaker), authorize(category.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(category.BasePath()))
 		{
 			// @Router /v1/categories/ [get]
 			category.GET("/", categoryHandler.ListCategories)
@@ -191,19 +226,76 @@ func NewRouter(
 		}
 
 		product := v1.Group("/products")
+		product.Use(authMiddleware(tokenMaker), authorize(product.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(product.BasePath()))
 		{
 			product.GET("/", productHandler.ListProducts)
+			product.GET("/lookup/:code", productHandler.LookupProduct)
 			product.GET("/:id", productHandler.GetProduct)
 			product.POST("/", productHandler.CreateProduct)
 			product.PUT("/:id", productHandler.UpdateProduct)
+			product.POST("/:id/image", productHandler.UploadProductImage)
+			product.POST("/:id/stock", stockHandler.PostStockMovement)
+			product.GET("/:id/stock-movements", stockHandler.ListStockMovements)
 			product.DELETE("/:id", productHandler.DeleteProduct)
 
 		}
 		order := v1.Group("/orders")
+		order.Use(authMiddleware(tokenMaker), authorize(order.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(order.BasePath()))
 		{
-			order.POST("/", orderHandler.CreateOrder)
+			order.POST("/", idempotency.Middleware(), orderHandler.CreateOrder)
+			order.POST("/preview", orderHandler.PreviewOrder)
 			order.GET("/", orderHandler.ListOrders)
 			order.GET("/:id", orderHandler.GetOrder)
+			order.POST("/:id/cancel", orderHandler.CancelOrder)
+			order.POST("/:id/refunds", orderHandler.RefundOrder)
+			order.GET("/:id/receipt", receiptHandler.GetOrderReceipt)
+		}
+
+		heldOrder := v1.Group("/held-orders")
+		heldOrder.Use(authMiddleware(tokenMaker), authorize(heldOrder.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(heldOrder.BasePath()))
+		{
+			heldOrder.POST("/", orderHandler.HoldOrder)
+			heldOrder.GET("/", orderHandler.ListHeldOrders)
+			heldOrder.GET("/:id", orderHandler.GetHeldOrder)
+			heldOrder.PUT("/:id", orderHandler.UpdateHeldOrder)
+			heldOrder.DELETE("/:id", orderHandler.DeleteHeldOrder)
+			heldOrder.POST("/:id/complete", idempotency.Middleware(), orderHandler.CompleteHeldOrder)
+		}
+
+		receipt := v1.Group("/receipts")
+		receipt.Use(authMiddleware(tokenMaker), authorize(receipt.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(receipt.BasePath()))
+		{
+			receipt.GET("/:code", receiptHandler.GetReceipt)
+		}
+
+		report := v1.Group("/reports")
+		report.Use(authMiddleware(tokenMaker), authorize(report.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(report.BasePath()))
+		{
+			report.GET("/sales", reportHandler.SalesReport)
+			report.GET("/top-products", reportHandler.TopProductsReport)
+			report.GET("/cashiers", reportHandler.CashierReport)
+			report.GET("/payments", reportHandler.PaymentReport)
+			report.GET("/z", shiftHandler.DailyZReport)
+		}
+
+		shift := v1.Group("/shifts")
+		shift.Use(authMiddleware(tokenMaker), authorize(shift.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(shift.BasePath()))
+		{
+			shift.POST("/", shiftHandler.OpenShift)
+			shift.GET("/current", shiftHandler.GetCurrentShift)
+			shift.GET("/:id", shiftHandler.GetShift)
+			shift.POST("/:id/close", shiftHandler.CloseShift)
+			shift.GET("/:id/report", shiftHandler.GetShiftReport)
+		}
+
+		promotion := v1.Group("/promotions")
+		promotion.Use(authMiddleware(tokenMaker), authorize(promotion.BasePath()), rateLimiter.PerUser(), rateLimiter.PerGroup(promotion.BasePath()))
+		{
+			promotion.GET("/", promotionHandler.ListPromotions)
+			promotion.GET("/:id", promotionHandler.GetPromotion)
+			promotion.POST("/", promotionHandler.CreatePromotion)
+			promotion.PUT("/:id", promotionHandler.UpdatePromotion)
+			promotion.DELETE("/:id", promotionHandler.DeletePromotion)
 		}
 	}
 	//}
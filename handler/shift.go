@@ -0,0 +1,426 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gotemplate/config"
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+	"gotemplate/receipt"
+	repo "gotemplate/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+// zReportMIMETypes maps the Accept header types to Z-report formats
+var zReportMIMETypes = map[string]string{
+	gin.MIMEJSON:    receiptFormatJSON,
+	gin.MIMEPlain:   receiptFormatText,
+	mimeOctetStream: receiptFormatESCPOS,
+}
+
+// ShiftHandler represents the HTTP handler for cashier shift requests
+type ShiftHandler struct {
+	svc      repo.ShiftRepository
+	log      *logger.Logger
+	vs       *ValidatorService
+	store    receipt.Store
+	timezone string
+}
+
+// NewShiftHandler creates a new ShiftHandler instance printing the store details from config
+// and computing daily Z-reports in the ReportTimezone from config
+func NewShiftHandler(svc repo.ShiftRepository, log *logger.Logger, vs *ValidatorService, cfg config.Econfig) *ShiftHandler {
+	timezone := cfg.ReportTimezone()
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	return &ShiftHandler{
+		svc,
+		log,
+		vs,
+		receipt.Store{
+			Name:  cfg.ReceiptStoreName(),
+			Width: cfg.ReceiptWidth(),
+		},
+		timezone,
+	}
+}
+
+// canAccess reports whether the caller may see or close shift, writing the error response when not.
+// Cashiers only have access to their own shifts.
+func canAccess(ctx *gin.Context, shift *domain.Shift) bool {
+	payload := getAuthPayload(ctx, authorizationPayloadKey)
+	if payload.Role != domain.Admin && payload.UserID != shift.UserID {
+		handleAbort(ctx, port.ErrForbidden, "SFT1")
+		return false
+	}
+	return true
+}
+
+// openShiftRequest represents a request body for opening a shift
+type openShiftRequest struct {
+	OpeningFloat *float64 `json:"opening_float" validate:"required,min=0" example:"50000"`
+}
+
+// OpenShift godoc
+//
+//	@Summary		Open a shift
+//	@Description	Open a shift for the current user with the starting cash float in the drawer.
+//	@Description	Orders and refunds are tied to the open shift of the user who takes them.
+//	@Tags			Shifts
+//	@Accept			json
+//	@Produce		json
+//	@Param			openShiftRequest	body		openShiftRequest	true	"Open shift request"
+//	@Success		200					{object}	shiftResponse		"Shift opened"
+//	@Failure		400					{object}	errorValidResponse	"Validation error"
+//	@Failure		401					{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		409					{object}	errorValidResponse	"A shift is already open"
+//	@Failure		500					{object}	errorValidResponse	"Internal server error"
+//	@Router			/shifts [post]
+//	@Security		BearerAuth
+func (sh *ShiftHandler) OpenShift(ctx *gin.Context) {
+	var req openShiftRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if !sh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	shift := domain.Shift{
+		UserID:       authPayload.UserID,
+		OpeningFloat: *req.OpeningFloat,
+	}
+
+	_, err := sh.svc.OpenShift(ctx, &shift)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, newShiftResponse(&shift))
+}
+
+// GetCurrentShift godoc
+//
+//	@Summary		Get the current shift
+//	@Description	Get the open shift of the current user with its running totals
+//	@Tags			Shifts
+//	@Produce		json
+//	@Success		200	{object}	zReportResponse		"Shift displayed"
+//	@Failure		401	{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404	{object}	errorValidResponse	"No open shift"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/shifts/current [get]
+//	@Security		BearerAuth
+func (sh *ShiftHandler) GetCurrentShift(ctx *gin.Context) {
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	shift, err := sh.svc.GetOpenShift(ctx, authPayload.UserID)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+
+	report, err := sh.svc.ShiftReport(ctx, shift.ID)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, newZReportResponse(report))
+}
+
+// getShiftRequest represents a request for a shift
+type getShiftRequest struct {
+	ID uint64 `uri:"id" validate:"required,min=1" example:"1"`
+}
+
+// GetShift godoc
+//
+//	@Summary		Get a shift
+//	@Description	Get a shift by id. Cashiers can only get their own shifts.
+//	@Tags			Shifts
+//	@Produce		json
+//	@Param			id	path		uint64				true	"Shift ID"
+//	@Success		200	{object}	shiftResponse		"Shift displayed"
+//	@Failure		400	{object}	errorValidResponse	"Validation error"
+//	@Failure		401	{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403	{object}	errorValidResponse	"Shift of another cashier"
+//	@Failure		404	{object}	errorValidResponse	"Data not found error"
+//	@Failure		500	{object}	errorValidResponse	"Internal server error"
+//	@Router			/shifts/{id} [get]
+//	@Security		BearerAuth
+func (sh *ShiftHandler) GetShift(ctx *gin.Context) {
+	var req getShiftRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if !sh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	shift, err := sh.svc.GetShiftByID(ctx, req.ID)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+	if !canAccess(ctx, shift) {
+		return
+	}
+
+	handleSuccess(ctx, newShiftResponse(shift))
+}
+
+// closeShiftRequest represents a request body for closing a shift
+type closeShiftRequest struct {
+	CountedCash *float64 `json:"counted_cash" validate:"required,min=0" example:"104500"`
+	Note        string   `json:"note" validate:"omitempty,max=255" example:"Short one 500 coin"`
+}
+
+// CloseShift godoc
+//
+//	@Summary		Close a shift
+//	@Description	Close a shift with the cash counted in the drawer and return its Z-report.
+//	@Description	The expected cash is the opening float plus cash sales less cash refunds;
+//	@Description	the variance is the counted cash less the expected cash, positive when over and negative when short.
+//	@Tags			Shifts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		uint64				true	"Shift ID"
+//	@Param			closeShiftRequest	body		closeShiftRequest	true	"Close shift request"
+//	@Success		200					{object}	zReportResponse		"Shift closed"
+//	@Failure		400					{object}	errorValidResponse	"Validation error"
+//	@Failure		401					{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403					{object}	errorValidResponse	"Shift of another cashier"
+//	@Failure		404					{object}	errorValidResponse	"Data not found error"
+//	@Failure		409					{object}	errorValidResponse	"Shift already closed"
+//	@Failure		500					{object}	errorValidResponse	"Internal server error"
+//	@Router			/shifts/{id}/close [post]
+//	@Security		BearerAuth
+func (sh *ShiftHandler) CloseShift(ctx *gin.Context) {
+	var uri getShiftRequest
+	var req closeShiftRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if !sh.vs.handleValidation(ctx, uri) || !sh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	shift, err := sh.svc.GetShiftByID(ctx, uri.ID)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+	if !canAccess(ctx, shift) {
+		return
+	}
+
+	var note *string
+	if req.Note != "" {
+		note = &req.Note
+	}
+
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	report, err := sh.svc.CloseShift(ctx, shift.ID, authPayload.UserID, *req.CountedCash, note)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, newZReportResponse(report))
+}
+
+// getShiftReportRequest represents a request for the Z-report of a shift
+type getShiftReportRequest struct {
+	ID     uint64 `uri:"id" validate:"required,min=1" example:"1"`
+	Format string `form:"format" validate:"omitempty,oneof=json text escpos" example:"text"`
+}
+
+// GetShiftReport godoc
+//
+//	@Summary		Print the Z-report of a shift
+//	@Description	Render the Z-report of a shift as JSON, plain text for thermal printers or ESC/POS printer commands.
+//	@Description	An open shift is printed as an X-report with its running totals.
+//	@Description	The format query parameter takes precedence over the Accept header.
+//	@Tags			Shifts
+//	@Produce		json,plain,octet-stream
+//	@Param			id		path		uint64				true	"Shift ID"
+//	@Param			format	query		string				false	"Report format"	Enums(json, text, escpos)
+//	@Success		200		{object}	zReportResponse		"Z-report displayed"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403		{object}	errorValidResponse	"Shift of another cashier"
+//	@Failure		404		{object}	errorValidResponse	"Data not found error"
+//	@Failure		406		{object}	errorValidResponse	"No acceptable report format"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/shifts/{id}/report [get]
+//	@Security		BearerAuth
+func (sh *ShiftHandler) GetShiftReport(ctx *gin.Context) {
+	var req getShiftReportRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if !sh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	shift, err := sh.svc.GetShiftByID(ctx, req.ID)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+	if !canAccess(ctx, shift) {
+		return
+	}
+
+	report, err := sh.svc.ShiftReport(ctx, shift.ID)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+
+	title := fmt.Sprintf("Z-REPORT SHIFT #%d", shift.ID)
+	if shift.IsOpen() {
+		title = fmt.Sprintf("X-REPORT SHIFT #%d", shift.ID)
+	}
+
+	sh.render(ctx, req.Format, fmt.Sprintf("shift-%d", shift.ID), title, report, newZReportResponse(report))
+}
+
+// dailyZReportRequest represents the query of a daily Z-report
+type dailyZReportRequest struct {
+	Date     string `form:"date" validate:"required,datetime=2006-01-02" example:"2024-01-01"`
+	Timezone string `form:"tz" validate:"omitempty,timezone" example:"Asia/Jakarta"`
+	Format   string `form:"format" validate:"omitempty,oneof=json text escpos" example:"text"`
+}
+
+// DailyZReport godoc
+//
+//	@Summary		Daily Z-report
+//	@Description	Render the Z-report of every shift opened on a day as JSON, plain text or ESC/POS printer commands.
+//	@Description	Counted cash and variance only cover the closed shifts.
+//	@Tags			Reports
+//	@Produce		json,plain,octet-stream
+//	@Param			date	query		string				true	"Day (YYYY-MM-DD)"
+//	@Param			tz		query		string				false	"IANA timezone, ReportTimezone from config by default"
+//	@Param			format	query		string				false	"Report format"	Enums(json, text, escpos)
+//	@Success		200		{object}	zReportResponse		"Z-report displayed"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		403		{object}	errorValidResponse	"Forbidden error"
+//	@Failure		406		{object}	errorValidResponse	"No acceptable report format"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/reports/z [get]
+//	@Security		BearerAuth
+func (sh *ShiftHandler) DailyZReport(ctx *gin.Context) {
+	var req dailyZReportRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if !sh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	if req.Timezone == "" {
+		req.Timezone = sh.timezone
+	}
+	loc, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		sh.log.Error(err.Error())
+		handleAbort(ctx, err, "SFT2")
+		return
+	}
+	// The layout was checked by the datetime validation
+	from, _ := time.ParseInLocation(reportDateLayout, req.Date, loc)
+
+	report, err := sh.svc.DailyReport(ctx, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+
+	rsp := newZReportResponse(report)
+	rsp.Date = req.Date
+	rsp.Timezone = req.Timezone
+
+	sh.render(ctx, req.Format, "day-"+req.Date, "Z-REPORT "+req.Date, report, rsp)
+}
+
+// render writes a Z-report in the requested format,
+// negotiating it from the Accept header when format is empty
+func (sh *ShiftHandler) render(ctx *gin.Context, format, name, title string, report *domain.ZReport, rsp zReportResponse) {
+	if format == "" {
+		format = zReportMIMETypes[ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain, mimeOctetStream)]
+	}
+
+	z := receipt.NewZReport(sh.store, title, report)
+
+	var buf bytes.Buffer
+	var contentType string
+	var err error
+
+	switch format {
+	case receiptFormatJSON:
+		handleSuccess(ctx, rsp)
+		return
+	case receiptFormatText:
+		contentType = "text/plain; charset=utf-8"
+		err = z.WriteText(&buf)
+	case receiptFormatESCPOS:
+		contentType = mimeOctetStream
+		err = z.WriteESCPOS(&buf)
+	default:
+		handleAbort(ctx, port.ErrNotAcceptable, "RCP1")
+		return
+	}
+
+	if err != nil {
+		sh.log.Error(err.Error())
+		handleAbort(ctx, err, "RCP2")
+		return
+	}
+
+	if format == receiptFormatESCPOS {
+		ctx.Header("Content-Disposition", `attachment; filename="z-report-`+name+`.bin"`)
+	}
+
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
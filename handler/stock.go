@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/logger"
+	repo "gotemplate/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StockHandler represents the HTTP handler for the stock ledger of products
+type StockHandler struct {
+	svc repo.StockRepository
+	log *logger.Logger
+	vs  *ValidatorService
+}
+
+// NewStockHandler creates a new StockHandler instance
+func NewStockHandler(svc repo.StockRepository, log *logger.Logger, vs *ValidatorService) *StockHandler {
+	return &StockHandler{
+		svc,
+		log,
+		vs,
+	}
+}
+
+// stockProductRequest represents the product of a stock ledger request
+type stockProductRequest struct {
+	ID uint64 `uri:"id" validate:"required,min=1" example:"1"`
+}
+
+// stockMovementRequest represents a request body for posting a manual stock movement.
+// Adjustments and receivings change the stock by quantity; a stocktake sets it to the counted quantity.
+type stockMovementRequest struct {
+	Source    domain.StockSource `json:"source" validate:"required,oneof=adjustment stocktake receiving" example:"adjustment"`
+	Quantity  *int64             `json:"quantity" example:"-2"`
+	Counted   *int64             `json:"counted" validate:"omitempty,min=0" example:"48"`
+	Reference string             `json:"reference" validate:"omitempty,max=100" example:"DN-2024-0012"`
+	Reason    string             `json:"reason" validate:"omitempty,max=500" example:"Damaged in storage"`
+}
+
+// movement checks the rules between the fields of the request and converts it to a movement of productID,
+// returning the validation messages with their tags when it is inconsistent
+func (req stockMovementRequest) movement(productID, userID uint64) (domain.StockMovement, []string, []string) {
+	var messages, tags []string
+	fail := func(message, tag string) {
+		messages = append(messages, message)
+		tags = append(tags, tag)
+	}
+
+	movement := domain.StockMovement{
+		ProductID: productID,
+		Source:    req.Source,
+		UserID:    &userID,
+	}
+
+	switch req.Source {
+	case domain.StockStocktake:
+		if req.Counted == nil {
+			fail("counted is required for stocktakes", "required_if")
+		} else {
+			movement.StockAfter = *req.Counted
+		}
+		if req.Quantity != nil {
+			fail("quantity must be empty for stocktakes, the counted stock setting it", "excluded_if")
+		}
+	case domain.StockAdjustment:
+		if req.Quantity == nil || *req.Quantity == 0 {
+			fail("quantity is required and must not be 0 for adjustments", "ne")
+		}
+		if req.Reason == "" {
+			fail("reason is required for adjustments", "required_if")
+		}
+	case domain.StockReceiving:
+		if req.Quantity == nil || *req.Quantity <= 0 {
+			fail("quantity must be above 0 for receivings", "gt")
+		}
+	}
+	if req.Source != domain.StockStocktake && req.Counted != nil {
+		fail("counted must be empty for adjustments and receivings", "excluded_if")
+	}
+
+	if req.Quantity != nil {
+		movement.Quantity = *req.Quantity
+	}
+	if req.Reference != "" {
+		movement.Reference = &req.Reference
+	}
+	if req.Reason != "" {
+		movement.Reason = &req.Reason
+	}
+
+	return movement, messages, tags
+}
+
+// PostStockMovement godoc
+//
+//	@Summary		Post a stock movement
+//	@Description	Adjust the stock of a product by a quantity, set it to the quantity counted by a stocktake
+//	@Description	or add the goods received from a supplier. The movement is recorded in the stock ledger of the product
+//	@Description	with the user who posted it; adjustments need a reason. A movement leaving the stock negative is refused.
+//	@Tags			Products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id						path		uint64					true	"Product ID"
+//	@Param			stockMovementRequest	body		stockMovementRequest	true	"Stock movement request"
+//	@Success		200						{object}	stockMovementResponse	"Stock movement posted"
+//	@Failure		400						{object}	errorValidResponse		"Validation error or insufficient stock"
+//	@Failure		401						{object}	errorValidResponse		"Unauthorized error"
+//	@Failure		403						{object}	errorValidResponse		"Forbidden error"
+//	@Failure		404						{object}	errorValidResponse		"Data not found error"
+//	@Failure		500						{object}	errorValidResponse		"Internal server error"
+//	@Router			/products/{id}/stock [post]
+//	@Security		BearerAuth
+func (sh *StockHandler) PostStockMovement(ctx *gin.Context) {
+	var uri stockProductRequest
+	var req stockMovementRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if !sh.vs.handleValidation(ctx, uri) || !sh.vs.handleValidation(ctx, req) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx, authorizationPayloadKey)
+
+	movement, messages, tags := req.movement(uri.ID, authPayload.UserID)
+	if len(messages) > 0 {
+		codes := make([]string, len(tags))
+		for i, tag := range tags {
+			codes[i] = sh.vs.tagToNumber[tag]
+		}
+		ctx.JSON(http.StatusBadRequest, newErrorValidResponse(messages, codes))
+		return
+	}
+
+	_, err := sh.svc.PostStockMovement(ctx, &movement)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, newStockMovementResponse(&movement))
+}
+
+// listStockMovementsRequest represents the query of the stock ledger of a product
+type listStockMovementsRequest struct {
+	pageRequest
+	Source string `form:"source" validate:"omitempty,oneof=opening sale refund cancellation adjustment stocktake receiving" example:"sale"`
+	From   string `form:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2024-01-01T00:00:00Z"`
+	To     string `form:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2024-02-01T00:00:00Z"`
+}
+
+// filter converts the request to a stock movement filter of productID, checking that the range is not inverted,
+// returning the validation messages with their tags when it is
+func (req listStockMovementsRequest) filter(productID uint64) (domain.StockMovementFilter, []string, []string) {
+	var messages, tags []string
+	filter := domain.StockMovementFilter{
+		ProductID: productID,
+		Source:    domain.StockSource(req.Source),
+	}
+
+	// The layouts were checked by the datetime validation
+	if req.From != "" {
+		from, _ := time.Parse(orderTimeLayout, req.From)
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, _ := time.Parse(orderTimeLayout, req.To)
+		filter.To = &to
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		messages = append(messages, "to must be after from")
+		tags = append(tags, "gtfield")
+	}
+
+	return filter, messages, tags
+}
+
+// ListStockMovements godoc
+//
+//	@Summary		List the stock movements of a product
+//	@Description	List the stock ledger of a product, the most recent movement first.
+//	@Description	Every change of the stock is recorded with its source, quantity, the stock it left, the user and the order, refund or document behind it.
+//	@Description	The created_at range includes from and excludes to.
+//	@Tags			Products
+//	@Produce		json
+//	@Param			id		path		uint64				true	"Product ID"
+//	@Param			cursor	query		string				false	"Cursor of the next or previous page"
+//	@Param			limit	query		uint64				false	"Page size, 20 by default"
+//	@Param			total	query		string				false	"Count the matching rows"	Enums(exact, estimate)
+//	@Param			source	query		string				false	"Movement source"	Enums(opening, sale, refund, cancellation, adjustment, stocktake, receiving)
+//	@Param			from	query		string				false	"Created at or after (RFC 3339)"
+//	@Param			to		query		string				false	"Created before (RFC 3339)"
+//	@Success		200		{object}	meta				"Stock movements displayed"
+//	@Failure		400		{object}	errorValidResponse	"Validation error"
+//	@Failure		401		{object}	errorValidResponse	"Unauthorized error"
+//	@Failure		404		{object}	errorValidResponse	"Data not found error"
+//	@Failure		500		{object}	errorValidResponse	"Internal server error"
+//	@Router			/products/{id}/stock-movements [get]
+//	@Security		BearerAuth
+func (sh *StockHandler) ListStockMovements(ctx *gin.Context) {
+	var uri stockProductRequest
+	var req listStockMovementsRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		sh.vs.handleError(ctx, err)
+		return
+	}
+	if !sh.vs.handleValidation(ctx, uri) || !sh.vs.handleValidation(ctx, req) {
+		return
+	}
+	filter, messages, tags := req.filter(uri.ID)
+	if len(messages) > 0 {
+		codes := make([]string, len(tags))
+		for i, tag := range tags {
+			codes[i] = sh.vs.tagToNumber[tag]
+		}
+		ctx.JSON(http.StatusBadRequest, newErrorValidResponse(messages, codes))
+		return
+	}
+
+	params := req.params()
+	movements, page, err := sh.svc.ListStockMovements(ctx, filter, params)
+	if err != nil {
+		sh.log.Error(err.Error())
+		sh.vs.handledbError(ctx, err)
+		return
+	}
+
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
+	rsp := toMap(meta, newStockMovementResponses(movements), "stock_movements")
+
+	handleSuccess(ctx, rsp)
+}
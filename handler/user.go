@@ -2,6 +2,8 @@ package handler
 
 import (
 	//"database/sql"
+	"context"
+	"errors"
 
 	"gotemplate/core/domain"
 
@@ -20,6 +22,7 @@ import (
 	//"github.com/volatiletech/null"
 
 	//"gotemplate/core/port"
+	"gotemplate/password"
 	repo "gotemplate/repo/postgres"
 
 	"github.com/gin-gonic/gin"
@@ -70,11 +73,18 @@ func (uh *UserHandler) Register(ctx *gin.Context) {
 
 	//uh.log.Debug("req:", req)
 
+	hashedPassword, err := password.Hash(req.Password)
+	if err != nil {
+		uh.log.Error(err.Error())
+		handleAbort(ctx, err, "PWD1")
+		return
+	}
+
 	user := domain.User{
 
 		Name:     req.Name,
 		Email:    req.Email,
-		Password: req.Password,
+		Password: hashedPassword,
 		//CreatedAt: null.StringFrom(req.CreatedAt),
 		//CreatedAt: null.From(req.CreatedAt),
 
@@ -83,7 +93,7 @@ func (uh *UserHandler) Register(ctx *gin.Context) {
 		CreatedAt: zero.StringFrom(req.CreatedAt),
 		//CreatedAt: req.CreatedAt,
 		//CreatedTime: dtime.NewFromStrFormat(req.CreatedTime, "h:i"),
-		//Role:     req.Role,
+		Role: domain.Cashier,
 	}
 
 	//var user domain.User
@@ -114,13 +124,46 @@ func (uh *UserHandler) Register(ctx *gin.Context) {
 	// copier.Copy(&u1, &u)
 	// rsp := newUserResponse(&u1)
 
-	handleSuccess(ctx, u)
+	u1 := UserResponse{}
+	copier.Copy(&u1, &u)
+	rsp := newUserResponse(&u1)
+
+	handleSuccess(ctx, rsp)
+}
+
+// CreateInitialAdmin creates the admin account configured with email and password while there is no admin,
+// so that a new deployment has someone to create the other users. An empty email skips it.
+func (uh *UserHandler) CreateInitialAdmin(ctx context.Context, email, plain string) error {
+	if email == "" {
+		return nil
+	}
+	if len(plain) < 8 {
+		return errors.New("AdminPassword must be at least 8 characters")
+	}
+
+	hashedPassword, err := password.Hash(plain)
+	if err != nil {
+		return err
+	}
+
+	user := domain.User{
+		Name:     "Administrator",
+		Email:    email,
+		Password: hashedPassword,
+	}
+	created, err := uh.svc.CreateInitialAdmin(ctx, &user)
+	if err != nil {
+		return err
+	}
+	if created {
+		uh.log.Info("created the initial admin %s", email)
+	}
+	return nil
 }
 
 // listUsersRequest represents the request body for listing users
 type listUsersRequest struct {
-	Skip  uint64 `form:"skip" validate:"required,min=0" example:"0"`
-	Limit uint64 `form:"limit" validate:"required,min=5" example:"5"`
+	pageRequest
 }
 
 func (uh *UserHandler) ListUsers(ctx *gin.Context) {
@@ -135,16 +178,24 @@ func (uh *UserHandler) ListUsers(ctx *gin.Context) {
 		return
 	}
 
-	users, err := uh.svc.ListUsers(ctx, req.Skip, req.Limit)
+	params := req.params()
+	users, page, err := uh.svc.ListUsers(ctx, params)
 	if err != nil {
 		uh.log.Error(err.Error())
 		uh.vs.handledbError(ctx, err)
 		return
 	}
 
-	total := uint64(len(users))
-	meta := newMeta(total, req.Limit, req.Skip)
-	rsp := toMap(meta, users, "users")
+	usersList := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		u := UserResponse{}
+		copier.Copy(&u, &user)
+		usersList = append(usersList, newUserResponse(&u))
+	}
+
+	meta := newMeta(page, params.Limit)
+	setLinkHeader(ctx, page)
+	rsp := toMap(meta, usersList, "users")
 
 	handleSuccess(ctx, rsp)
 }
@@ -205,7 +256,7 @@ type updateUserRequest struct {
 	Name     string          `json:"name" validate:"omitempty,required" example:"John Doe"`
 	Email    string          `json:"email" validate:"omitempty,required,email" example:"test@example.com"`
 	Password string          `json:"password" validate:"omitempty,required,min=8" example:"12345678"`
-	Role     domain.UserRole `json:"role" validate:"omitempty,required" example:"admin"`
+	Role     domain.UserRole `json:"role" validate:"omitempty,required,oneof=admin cashier" example:"admin"`
 }
 
 func (uh *UserHandler) UpdateUser(ctx *gin.Context) {
@@ -227,21 +278,29 @@ func (uh *UserHandler) UpdateUser(ctx *gin.Context) {
 	}
 
 	user := domain.User{
-		ID:       id,
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: req.Password,
-		//Role:     req.Role,
+		ID:    id,
+		Name:  req.Name,
+		Email: req.Email,
+		Role:  req.Role,
+	}
+
+	if req.Password != "" {
+		user.Password, err = password.Hash(req.Password)
+		if err != nil {
+			uh.log.Error(err.Error())
+			handleAbort(ctx, err, "PWD1")
+			return
+		}
 	}
 
-	_, err = uh.svc.UpdateUser(ctx, &user)
+	updated, err := uh.svc.UpdateUser(ctx, &user)
 	if err != nil {
 		uh.log.Error(err.Error())
 		uh.vs.handledbError(ctx, err)
 		return
 	}
 	u := UserResponse{}
-	copier.Copy(&u, &user)
+	copier.Copy(&u, updated)
 	rsp := newUserResponse(&u)
 
 	handleSuccess(ctx, rsp)
@@ -656,6 +656,14 @@ func (vs *ValidatorService) handleError(ctx *gin.Context, err error) {
 
 func (vs *ValidatorService) handledbError(ctx *gin.Context, err error) {
 	statusCode := 500
+
+	for knownErr, code := range errorStatusMap {
+		if errors.Is(err, knownErr) {
+			errRsps := newErrordbResponse([]string{knownErr.Error()}, []string{"POTH01"})
+			ctx.JSON(code, errRsps)
+			return
+		}
+	}
 	//statusCode, _ := errorStatusMap[err]
 	// if !ok {
 
@@ -3,6 +3,10 @@
 // @description This is a  Demo Template API with Swagger documentation
 // @host localhost:8080
 // @BasePath /v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the access token.
 
 package main
 
@@ -119,6 +123,22 @@ func main() {
 		}
 	}()
 
+	// Diagnostics (Swagger, pprof) get their own server when AdminPort is set
+	var adminSrv *http.Server
+	if adminRouter := handler.NewAdminRouter(c); adminRouter != nil {
+		adminSrv = &http.Server{
+			Addr:    ":" + c.AdminPort(),
+			Handler: adminRouter,
+		}
+		log.Info("Starting the admin HTTP server: %s", adminSrv.Addr)
+
+		go func() {
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Info("admin listen: %s\n", err.Error())
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-quit
@@ -129,6 +149,11 @@ func main() {
 		if err := srv.Shutdown(shutdownctx); err != nil {
 			log.Error("Server Shutdown error:", err.Error())
 		}
+		if adminSrv != nil {
+			if err := adminSrv.Shutdown(shutdownctx); err != nil {
+				log.Error("Admin server Shutdown error:", err.Error())
+			}
+		}
 	}()
 	wg.Wait()
 	db.Close()
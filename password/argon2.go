@@ -0,0 +1,115 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// params holds the argon2id cost parameters recorded in every encoded hash
+type params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	keyLen  uint32
+	saltLen uint32
+}
+
+// defaultParams are the parameters used for newly hashed passwords.
+// Raising them makes Verify report existing hashes as needing a rehash.
+var defaultParams = params{
+	memory:  64 * 1024,
+	time:    1,
+	threads: 4,
+	keyLen:  32,
+	saltLen: 16,
+}
+
+// ErrInvalidHash is returned when an encoded hash is not in the expected format
+var ErrInvalidHash = errors.New("encoded password hash is not in the correct format")
+
+// ErrIncompatibleVersion is returned when an encoded hash uses a different argon2 version
+var ErrIncompatibleVersion = errors.New("incompatible version of argon2")
+
+// Hash derives an argon2id hash of the password and encodes it in PHC string format:
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+func Hash(password string) (string, error) {
+	p := defaultParams
+
+	salt := make([]byte, p.saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, p.keyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.memory,
+		p.time,
+		p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify compares a password with an encoded hash.
+// needsRehash is true when the hash was created with parameters other than the current defaults.
+func Verify(password, encoded string) (match bool, needsRehash bool, err error) {
+	p, salt, key, err := decode(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, p.keyLen)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash = p.memory != defaultParams.memory ||
+		p.time != defaultParams.time ||
+		p.threads != defaultParams.threads ||
+		p.keyLen != defaultParams.keyLen ||
+		uint32(len(salt)) != defaultParams.saltLen
+
+	return true, needsRehash, nil
+}
+
+// decode parses the parameters, salt and key out of an encoded hash
+func decode(encoded string) (p params, salt, key []byte, err error) {
+	vals := strings.Split(encoded, "$")
+	if len(vals) != 6 || vals[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(vals[2], "v=%d", &version); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return p, nil, nil, ErrIncompatibleVersion
+	}
+
+	if _, err = fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	salt, err = base64.RawStdEncoding.Strict().DecodeString(vals[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.saltLen = uint32(len(salt))
+
+	key, err = base64.RawStdEncoding.Strict().DecodeString(vals[5])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.keyLen = uint32(len(key))
+
+	return p, salt, key, nil
+}
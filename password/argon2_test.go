@@ -0,0 +1,57 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	encoded, err := Hash("12345678")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=1,p=4$") {
+		t.Errorf("Hash() = %q, want argon2id PHC string with default params", encoded)
+	}
+
+	match, needsRehash, err := Verify("12345678", encoded)
+	if err != nil || !match || needsRehash {
+		t.Errorf("Verify(correct) = %v, %v, %v; want true, false, nil", match, needsRehash, err)
+	}
+
+	match, _, err = Verify("wrong-password", encoded)
+	if err != nil || match {
+		t.Errorf("Verify(wrong) = %v, %v; want false, nil", match, err)
+	}
+}
+
+func TestVerifyNeedsRehash(t *testing.T) {
+	old := defaultParams
+	defaultParams.time = 1
+	defaultParams.memory = 8 * 1024
+	encoded, err := Hash("12345678")
+	defaultParams = old
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	match, needsRehash, err := Verify("12345678", encoded)
+	if err != nil || !match || !needsRehash {
+		t.Errorf("Verify() = %v, %v, %v; want true, true, nil", match, needsRehash, err)
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	tests := []string{
+		"",
+		"12345678",
+		"$2a$10$abcdefghijklmnopqrstuv",
+		"$argon2id$v=18$m=65536,t=1,p=4$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=4$c2FsdA$a2V5",
+	}
+	for _, encoded := range tests {
+		if _, _, err := Verify("12345678", encoded); err == nil {
+			t.Errorf("Verify(%q) error = nil, want error", encoded)
+		}
+	}
+}
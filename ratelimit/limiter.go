@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotemplate/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store names accepted by the RateLimitStore config value
+const (
+	MemoryStore = "memory"
+	RedisStore  = "redis"
+)
+
+// Rate is a token bucket holding Limit tokens that refills completely every Period
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRate parses a rate in the "<limit>/<period>" form, e.g. "100/1m".
+// An empty string returns a zero Rate, which disables limiting.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Rate{}, nil
+	}
+
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q: expected <limit>/<period>", s)
+	}
+
+	l, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || l <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: limit must be a positive integer", s)
+	}
+
+	p, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || p <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: period must be a positive duration", s)
+	}
+
+	return Rate{Limit: l, Period: p}, nil
+}
+
+// Disabled reports whether the rate does not limit anything
+func (r Rate) Disabled() bool {
+	return r.Limit <= 0 || r.Period <= 0
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// newResult builds a Result from the tokens left in a bucket of the given rate
+func newResult(allowed bool, tokens float64, rate Rate) Result {
+	perToken := rate.Period / time.Duration(rate.Limit)
+
+	res := Result{
+		Allowed:    allowed,
+		Limit:      rate.Limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(rate.Limit) - tokens) * float64(perToken)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	return res
+}
+
+// Limiter takes tokens from the bucket stored under a key
+type Limiter interface {
+	Allow(ctx context.Context, key string, rate Rate) (Result, error)
+}
+
+// NewLimiter creates the limiter selected by the RateLimitStore config value
+func NewLimiter(cfg config.Econfig) (Limiter, error) {
+	switch cfg.RateLimitStore() {
+	case "", MemoryStore:
+		return NewMemoryLimiter(), nil
+	case RedisStore:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisServer(),
+			Password: cfg.RedisPassword(),
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := client.Ping(ctx).Err(); err != nil {
+			return nil, fmt.Errorf("unable to connect to redis at %s: %w", cfg.RedisServer(), err)
+		}
+		return NewRedisLimiter(client), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.RateLimitStore())
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("100/1m")
+	if err != nil || rate.Limit != 100 || rate.Period != time.Minute {
+		t.Errorf("ParseRate(100/1m) = %+v, %v; want {100 1m}, nil", rate, err)
+	}
+
+	rate, err = ParseRate("")
+	if err != nil || !rate.Disabled() {
+		t.Errorf("ParseRate(\"\") = %+v, %v; want disabled rate, nil", rate, err)
+	}
+
+	for _, s := range []string{"100", "0/1m", "x/1m", "10/abc", "10/-1s"} {
+		if _, err := ParseRate(s); err == nil {
+			t.Errorf("ParseRate(%q) error = nil, want error", s)
+		}
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	ml := NewMemoryLimiter()
+	ml.now = func() time.Time { return now }
+	rate := Rate{Limit: 2, Period: 2 * time.Second}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		res, _ := ml.Allow(ctx, "ip:1", rate)
+		if !res.Allowed || res.Remaining != 1-i {
+			t.Fatalf("Allow() #%d = %+v; want allowed with %d remaining", i, res, 1-i)
+		}
+	}
+
+	res, _ := ml.Allow(ctx, "ip:1", rate)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Errorf("Allow() over limit = %+v; want blocked with 1s retry", res)
+	}
+
+	res, _ = ml.Allow(ctx, "ip:2", rate)
+	if !res.Allowed {
+		t.Errorf("Allow() other key = %+v; want allowed", res)
+	}
+
+	now = now.Add(time.Second)
+	res, _ = ml.Allow(ctx, "ip:1", rate)
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Allow() after refill = %+v; want allowed with 0 remaining", res)
+	}
+}
+
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	rl := NewRedisLimiter(client)
+	rate := Rate{Limit: 3, Period: time.Minute}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		res, err := rl.Allow(ctx, "user:1", rate)
+		if err != nil || !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("Allow() #%d = %+v, %v; want allowed with %d remaining", i, res, err, 2-i)
+		}
+	}
+
+	res, err := rl.Allow(ctx, "user:1", rate)
+	if err != nil || res.Allowed || res.RetryAfter <= 0 {
+		t.Errorf("Allow() over limit = %+v, %v; want blocked with a retry delay", res, err)
+	}
+
+	if ttl := mr.TTL(keyPrefix + "user:1"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("bucket TTL = %v, want within the rate period", ttl)
+	}
+}
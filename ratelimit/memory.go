@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped from a MemoryLimiter
+const sweepInterval = time.Minute
+
+// bucket is the state of one in-memory token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+	period time.Duration
+}
+
+/**
+ * MemoryLimiter keeps token buckets in process memory.
+ * It only limits a single instance of the service.
+ */
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter creates a new in-memory limiter instance
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow takes a token from the bucket stored under key
+func (ml *MemoryLimiter) Allow(ctx context.Context, key string, rate Rate) (Result, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	now := ml.now()
+	ml.sweep(now)
+
+	capacity := float64(rate.Limit)
+	b, ok := ml.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		ml.buckets[key] = b
+	}
+	b.period = rate.Period
+
+	elapsed := now.Sub(b.last)
+	if elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed.Seconds()*capacity/rate.Period.Seconds())
+		b.last = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return newResult(allowed, b.tokens, rate), nil
+}
+
+// sweep drops buckets that have been idle long enough to refill completely
+func (ml *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(ml.lastSweep) < sweepInterval {
+		return
+	}
+	ml.lastSweep = now
+
+	for key, b := range ml.buckets {
+		if now.Sub(b.last) >= b.period {
+			delete(ml.buckets, key)
+		}
+	}
+}
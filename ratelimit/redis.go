@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces the rate limit buckets in redis
+const keyPrefix = "ratelimit:"
+
+// tokenBucketScript refills and takes a token from a bucket atomically.
+// The redis clock is used so every instance sees the same time.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * capacity / period)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], period)
+
+return {allowed, tostring(tokens)}
+`)
+
+/**
+ * RedisLimiter keeps token buckets in redis
+ * so the limits are shared by every instance of the service
+ */
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter creates a new redis limiter instance
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{
+		client,
+	}
+}
+
+// Allow takes a token from the bucket stored under key
+func (rl *RedisLimiter) Allow(ctx context.Context, key string, rate Rate) (Result, error) {
+	values, err := tokenBucketScript.Run(ctx, rl.client, []string{keyPrefix + key}, rate.Limit, rate.Period.Milliseconds()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(string)
+
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(allowed == 1, tokens, rate), nil
+}
@@ -0,0 +1,63 @@
+package receipt
+
+import (
+	"io"
+
+	"gotemplate/receipt/escpos"
+)
+
+// ESC/POS layout settings
+const (
+	qrModuleSize  = 6
+	barcodeWidth  = 1
+	barcodeHeight = 80
+	feedBeforeCut = 3
+)
+
+// WriteESCPOS renders the receipt as ESC/POS commands for a thermal printer:
+// a bold store name, the text layout, the footer and the receipt code as a
+// QR code or barcode, followed by a paper cut.
+func (r *Receipt) WriteESCPOS(w io.Writer) error {
+	e := escpos.NewEncoder(w)
+	t := r.newTextWriter(e)
+	t.bold = e.SetBold
+
+	e.Init()
+	e.SetAlign(escpos.AlignCenter)
+	e.SetBold(true)
+	e.SetSize(escpos.SizeDoubleHeight)
+	for _, l := range wrapText(r.StoreName, t.width) {
+		e.Line(l)
+	}
+	e.SetSize(escpos.SizeNormal)
+	e.SetBold(false)
+	e.SetAlign(escpos.AlignLeft)
+
+	t.rule()
+	r.writeBody(t)
+	t.rule()
+
+	e.SetAlign(escpos.AlignCenter)
+	for _, l := range wrapText(r.Footer, t.width) {
+		e.Line(l)
+	}
+
+	switch r.symbol {
+	case SymbolNone:
+	case SymbolBarcode:
+		e.Feed(1)
+		e.Barcode(r.ReceiptCode, barcodeWidth, barcodeHeight)
+	default:
+		e.Feed(1)
+		e.QRCode(r.ReceiptCode, qrModuleSize, escpos.QRCorrectionM)
+	}
+
+	e.SetAlign(escpos.AlignLeft)
+	e.Feed(feedBeforeCut)
+	e.Cut()
+
+	if t.err != nil {
+		return t.err
+	}
+	return e.Flush()
+}
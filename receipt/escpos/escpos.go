@@ -0,0 +1,213 @@
+// Package escpos encodes ESC/POS commands understood by most thermal receipt printers
+package escpos
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ESC/POS command prefixes
+const (
+	esc = 0x1b
+	gs  = 0x1d
+	lf  = 0x0a
+)
+
+// Alignment is the horizontal justification of printed text and symbols
+type Alignment byte
+
+// Alignment values
+const (
+	AlignLeft   Alignment = 0
+	AlignCenter Alignment = 1
+	AlignRight  Alignment = 2
+)
+
+// Size values for SetSize, combinable with |
+const (
+	SizeNormal       byte = 0x00
+	SizeDoubleHeight byte = 0x01
+	SizeDoubleWidth  byte = 0x10
+)
+
+// QRErrorCorrection is the error correction level of a QR code
+type QRErrorCorrection byte
+
+// QRErrorCorrection values
+const (
+	QRCorrectionL QRErrorCorrection = 48
+	QRCorrectionM QRErrorCorrection = 49
+	QRCorrectionQ QRErrorCorrection = 50
+	QRCorrectionH QRErrorCorrection = 51
+)
+
+// maxQRData is the largest payload the QR symbol storage area accepts
+const maxQRData = 7089
+
+// maxBarcodeData is the largest CODE128 payload, including the code set selector
+const maxBarcodeData = 255
+
+var (
+	// ErrQRDataLength is returned when QR code data is empty or too long
+	ErrQRDataLength = errors.New("escpos: QR code data must be between 1 and 7089 bytes")
+	// ErrBarcodeData is returned when barcode data is empty, too long or not printable ASCII
+	ErrBarcodeData = errors.New("escpos: barcode data must be between 1 and 253 printable ASCII characters")
+	// ErrBarcodeWidth is returned when the barcode module width is out of range
+	ErrBarcodeWidth = errors.New("escpos: barcode module width must be between 1 and 6")
+	// ErrQRSize is returned when the QR module size is out of range
+	ErrQRSize = errors.New("escpos: QR module size must be between 1 and 16")
+)
+
+// Encoder writes ESC/POS commands and text to an underlying writer.
+// The first error is kept and returned by Flush; later writes are skipped.
+type Encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewEncoder creates an encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// write sends raw bytes to the printer
+func (e *Encoder) write(b ...byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+// fail records err unless an error was already recorded
+func (e *Encoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// Init resets the printer to its power-on settings
+func (e *Encoder) Init() {
+	e.write(esc, '@')
+}
+
+// SetAlign sets the justification of the following lines
+func (e *Encoder) SetAlign(a Alignment) {
+	e.write(esc, 'a', byte(a))
+}
+
+// SetBold turns emphasized printing on or off
+func (e *Encoder) SetBold(on bool) {
+	var n byte
+	if on {
+		n = 1
+	}
+	e.write(esc, 'E', n)
+}
+
+// SetSize sets the character size, see the Size values
+func (e *Encoder) SetSize(size byte) {
+	e.write(gs, '!', size)
+}
+
+// WriteString writes text, replacing characters outside printable ASCII with '?'
+// so every character takes exactly one column on the printer
+func (e *Encoder) WriteString(s string) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b = append(b, lf)
+		case r < 0x20 || r > 0x7e:
+			b = append(b, '?')
+		default:
+			b = append(b, byte(r))
+		}
+	}
+
+	var n int
+	n, e.err = e.w.Write(b)
+	return n, e.err
+}
+
+// Line writes text followed by a line feed
+func (e *Encoder) Line(s string) {
+	_, _ = e.WriteString(s)
+	e.write(lf)
+}
+
+// Feed prints the buffer and feeds n lines
+func (e *Encoder) Feed(n byte) {
+	e.write(esc, 'd', n)
+}
+
+// Cut feeds the paper to the cutter and fully cuts it
+func (e *Encoder) Cut() {
+	e.write(gs, 'V', 'A', 0)
+}
+
+// QRCode prints data as a model 2 QR code with the given module size
+func (e *Encoder) QRCode(data string, size byte, correction QRErrorCorrection) {
+	if len(data) == 0 || len(data) > maxQRData {
+		e.fail(ErrQRDataLength)
+		return
+	}
+	if size < 1 || size > 16 {
+		e.fail(ErrQRSize)
+		return
+	}
+
+	// Select model 2, set module size and error correction
+	e.write(gs, '(', 'k', 4, 0, '1', 'A', '2', 0)
+	e.write(gs, '(', 'k', 3, 0, '1', 'C', size)
+	e.write(gs, '(', 'k', 3, 0, '1', 'E', byte(correction))
+
+	// Store the data, then print the stored symbol
+	n := len(data) + 3
+	e.write(gs, '(', 'k', byte(n), byte(n>>8), '1', 'P', '0')
+	e.write([]byte(data)...)
+	e.write(gs, '(', 'k', 3, 0, '1', 'Q', '0')
+}
+
+// Barcode prints data as a CODE128 barcode with its text printed below.
+// moduleWidth is the width in dots of the narrowest bar, between 1 and 6.
+func (e *Encoder) Barcode(data string, moduleWidth, height byte) {
+	if moduleWidth < 1 || moduleWidth > 6 {
+		e.fail(ErrBarcodeWidth)
+		return
+	}
+	for i := 0; i < len(data); i++ {
+		if data[i] < 0x20 || data[i] > 0x7e {
+			e.fail(ErrBarcodeData)
+			return
+		}
+	}
+
+	// A literal { is written as {{ since { starts a code set selector
+	data = strings.ReplaceAll(data, "{", "{{")
+	if len(data) == 0 || len(data)+2 > maxBarcodeData {
+		e.fail(ErrBarcodeData)
+		return
+	}
+
+	e.write(gs, 'h', height)
+	e.write(gs, 'w', moduleWidth)
+	e.write(gs, 'H', 2)
+
+	// Function B, CODE128 with code set B selected by the {B prefix
+	e.write(gs, 'k', 73, byte(len(data)+2), '{', 'B')
+	e.write([]byte(data)...)
+}
+
+// Flush writes any buffered bytes and returns the first error encountered
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
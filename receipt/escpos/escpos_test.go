@@ -0,0 +1,100 @@
+package escpos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encode runs fn against a new encoder and returns the flushed bytes
+func encode(t *testing.T, fn func(e *Encoder)) []byte {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	fn(e)
+	require.NoError(t, e.Flush())
+	return buf.Bytes()
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(e *Encoder)
+		want []byte
+	}{
+		{"init", func(e *Encoder) { e.Init() }, []byte{0x1b, 0x40}},
+		{"align center", func(e *Encoder) { e.SetAlign(AlignCenter) }, []byte{0x1b, 0x61, 0x01}},
+		{"bold on", func(e *Encoder) { e.SetBold(true) }, []byte{0x1b, 0x45, 0x01}},
+		{"bold off", func(e *Encoder) { e.SetBold(false) }, []byte{0x1b, 0x45, 0x00}},
+		{"double size", func(e *Encoder) { e.SetSize(SizeDoubleHeight | SizeDoubleWidth) }, []byte{0x1d, 0x21, 0x11}},
+		{"feed", func(e *Encoder) { e.Feed(3) }, []byte{0x1b, 0x64, 0x03}},
+		{"cut", func(e *Encoder) { e.Cut() }, []byte{0x1d, 0x56, 0x41, 0x00}},
+		{"line", func(e *Encoder) { e.Line("Café") }, []byte("Caf?\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, encode(t, tt.fn))
+		})
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	got := encode(t, func(e *Encoder) { e.QRCode("abc", 6, QRCorrectionM) })
+
+	want := []byte{
+		0x1d, 0x28, 0x6b, 0x04, 0x00, 0x31, 0x41, 0x32, 0x00,
+		0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x43, 0x06,
+		0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x45, 0x31,
+		0x1d, 0x28, 0x6b, 0x06, 0x00, 0x31, 0x50, 0x30, 'a', 'b', 'c',
+		0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x51, 0x30,
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestQRCodeLongData(t *testing.T) {
+	got := encode(t, func(e *Encoder) { e.QRCode(strings.Repeat("x", 300), 4, QRCorrectionL) })
+
+	// 303 bytes stored: pL = 0x2f, pH = 0x01
+	assert.Equal(t, []byte{0x1d, 0x28, 0x6b, 0x2f, 0x01, 0x31, 0x50, 0x30}, got[25:33])
+}
+
+func TestBarcode(t *testing.T) {
+	got := encode(t, func(e *Encoder) { e.Barcode("A{1", 2, 80) })
+
+	want := []byte{
+		0x1d, 0x68, 80,
+		0x1d, 0x77, 0x02,
+		0x1d, 0x48, 0x02,
+		0x1d, 0x6b, 73, 6, '{', 'B', 'A', '{', '{', '1',
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestEncoderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(e *Encoder)
+		err  error
+	}{
+		{"empty qr", func(e *Encoder) { e.QRCode("", 6, QRCorrectionM) }, ErrQRDataLength},
+		{"qr size", func(e *Encoder) { e.QRCode("abc", 17, QRCorrectionM) }, ErrQRSize},
+		{"empty barcode", func(e *Encoder) { e.Barcode("", 2, 80) }, ErrBarcodeData},
+		{"barcode not ascii", func(e *Encoder) { e.Barcode("é", 2, 80) }, ErrBarcodeData},
+		{"barcode width", func(e *Encoder) { e.Barcode("abc", 7, 80) }, ErrBarcodeWidth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := NewEncoder(&buf)
+			tt.fn(e)
+			e.Cut()
+
+			assert.ErrorIs(t, e.Flush(), tt.err)
+			assert.Empty(t, buf.Bytes())
+		})
+	}
+}
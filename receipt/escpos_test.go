@@ -0,0 +1,64 @@
+package receipt
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotemplate/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites the golden files: go test ./receipt -update
+var update = flag.Bool("update", false, "update golden files")
+
+// assertGolden compares got with testdata/name, rewriting the file when -update is set
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestWriteESCPOSGolden(t *testing.T) {
+	refunded := testOrder()
+	refunded.Status = domain.OrderPartiallyRefunded
+	refunded.Products[0].RefundedQuantity = 1
+
+	tests := []struct {
+		golden string
+		store  Store
+		order  *domain.Order
+	}{
+		{"escpos_qr.golden", Store{Name: "Go POS", Footer: "Thank you for shopping with us"}, testOrder()},
+		{"escpos_barcode_80mm.golden", Store{Name: "Go POS", Width: 48, Symbol: SymbolBarcode}, testOrder()},
+		{"escpos_refunded.golden", Store{Name: "Go POS", Symbol: SymbolNone}, refunded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, New(tt.store, tt.order).WriteESCPOS(&buf))
+
+			assertGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
+
+func TestWriteESCPOSInvalidCode(t *testing.T) {
+	r := New(Store{Name: "Go POS", Symbol: SymbolBarcode}, testOrder())
+	r.ReceiptCode = "é"
+
+	var buf bytes.Buffer
+	assert.Error(t, r.WriteESCPOS(&buf))
+}
@@ -0,0 +1,79 @@
+package receipt
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"gotemplate/core/domain"
+)
+
+// htmlTemplate lays the receipt out as a standalone, printable HTML page
+var htmlTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"money":  money,
+	"tax":    taxLabel,
+	"date":   func(r *Receipt) string { return r.CreatedAt.Format(timeLayout) },
+	"status": statusLabel,
+	"paid":   func(s domain.OrderStatus) bool { return s == domain.OrderPaid },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.StoreName}} - Receipt {{.ReceiptCode}}</title>
+<style>
+body { font-family: monospace; max-width: 22em; margin: 1em auto; }
+h1 { font-size: 1.2em; text-align: center; }
+table { width: 100%; border-collapse: collapse; }
+td.amount { text-align: right; }
+.status, footer { text-align: center; }
+.totals { border-top: 1px dashed; }
+</style>
+</head>
+<body>
+<h1>{{.StoreName}}</h1>
+<p>Order #{{.OrderID}}<br>{{date .}}<br>Customer: {{.CustomerName}}<br><small>{{.ReceiptCode}}</small></p>
+{{- if not (paid .Status)}}
+<p class="status"><strong>{{status .Status}}</strong></p>
+{{- end}}
+<table>
+{{- range .Lines}}
+<tr><td colspan="2">{{.Name}}</td></tr>
+<tr><td>{{.Quantity}} x {{money .UnitPrice}}{{if .RefundedQuantity}} (refunded {{.RefundedQuantity}}){{end}}</td><td class="amount">{{money .NormalTotal}}</td></tr>
+{{- if .Discount}}
+<tr><td>{{.DiscountLabel}}</td><td class="amount">-{{money .Discount}}</td></tr>
+{{- end}}
+{{- end}}
+{{- if .Discount}}
+<tr><td>You saved</td><td class="amount">{{money .Discount}}</td></tr>
+{{- end}}
+{{- if and .Taxes (not .TaxInclusive)}}
+<tr><td>Subtotal</td><td class="amount">{{money .Subtotal}}</td></tr>
+{{- range .Taxes}}
+<tr><td>{{tax .Rate}}</td><td class="amount">{{money .Amount}}</td></tr>
+{{- end}}
+{{- end}}
+<tr class="totals"><td>Total</td><td class="amount">{{money .TotalPrice}}</td></tr>
+{{- if .TaxInclusive}}
+{{- range .Taxes}}
+<tr><td>incl. {{tax .Rate}}</td><td class="amount">{{money .Amount}}</td></tr>
+{{- end}}
+{{- end}}
+<tr><td>Paid{{if .PaymentName}} {{.PaymentName}}{{end}}</td><td class="amount">{{money .TotalPaid}}</td></tr>
+<tr><td>Change</td><td class="amount">{{money .Change}}</td></tr>
+</table>
+{{- if .Footer}}
+<footer>{{.Footer}}</footer>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML renders the receipt as an HTML page
+func (r *Receipt) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// statusLabel is the printed form of an order status, like PARTIALLY REFUNDED
+func statusLabel(status domain.OrderStatus) string {
+	return strings.ToUpper(strings.ReplaceAll(string(status), "_", " "))
+}
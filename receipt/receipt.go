@@ -0,0 +1,156 @@
+// Package receipt builds order receipts and renders them as text and HTML
+package receipt
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"gotemplate/core/domain"
+)
+
+// DefaultWidth is the number of characters per line of a 58mm thermal printer
+const DefaultWidth = 32
+
+// minWidth is the narrowest layout a text receipt can be rendered in
+const minWidth = 24
+
+// Symbol is how the receipt code is printed on ESC/POS receipts
+type Symbol string
+
+// Symbol values
+const (
+	SymbolQR      Symbol = "qr"
+	SymbolBarcode Symbol = "barcode"
+	SymbolNone    Symbol = "none"
+)
+
+// Store holds the shop details printed on every receipt
+type Store struct {
+	Name   string
+	Footer string
+	Width  int
+	// Symbol defaults to a QR code; a barcode of the receipt code needs 80mm paper
+	Symbol Symbol
+}
+
+// Line is a purchased product printed on a receipt.
+// UnitPrice is the list price; Total is after the Discount of the line, its share of an order promotion included,
+// and before any tax added on top of the prices.
+type Line struct {
+	ProductID        uint64  `json:"product_id" example:"1"`
+	Name             string  `json:"name" example:"Chiki Ball"`
+	Quantity         int64   `json:"qty" example:"2"`
+	RefundedQuantity int64   `json:"refunded_qty" example:"0"`
+	UnitPrice        float64 `json:"unit_price" example:"5000"`
+	Discount         float64 `json:"discount,omitempty" example:"1000"`
+	Promotion        string  `json:"promotion,omitempty" example:"Buy 2 get 1 free"`
+	Total            float64 `json:"total" example:"9000"`
+}
+
+// NormalTotal is the line at list price, before its discount
+func (l Line) NormalTotal() float64 {
+	return math.Round((l.Total+l.Discount)*100) / 100
+}
+
+// DiscountLabel names the discount of a line after its promotion, if it had its own
+func (l Line) DiscountLabel() string {
+	if l.Promotion != "" {
+		return l.Promotion
+	}
+	return "Discount"
+}
+
+// Receipt is everything printed on the receipt of an order
+type Receipt struct {
+	StoreName    string             `json:"store_name" example:"Go POS"`
+	Footer       string             `json:"footer" example:"Thank you for shopping with us"`
+	ReceiptCode  string             `json:"receipt_code" example:"4979cf6e-d215-4ff8-9d0d-b3e99bcc7750"`
+	OrderID      uint64             `json:"order_id" example:"1"`
+	CustomerName string             `json:"customer_name" example:"John Doe"`
+	Status       domain.OrderStatus `json:"status" example:"paid"`
+	Lines        []Line             `json:"lines"`
+	Discount     float64            `json:"discount,omitempty" example:"1000"`
+	TaxInclusive bool               `json:"tax_inclusive,omitempty" example:"false"`
+	Taxes        []domain.TaxLine   `json:"taxes,omitempty"`
+	TotalPrice   float64            `json:"total_price" example:"10000"`
+	TotalPaid    float64            `json:"total_paid" example:"20000"`
+	Change       float64            `json:"change" example:"10000"`
+	PaymentName  string             `json:"payment_name" example:"Cash"`
+	PaymentType  domain.PaymentType `json:"payment_type" example:"CASH"`
+	CreatedAt    time.Time          `json:"created_at" example:"1970-01-01T00:00:00Z"`
+
+	width  int
+	symbol Symbol
+}
+
+// New builds the receipt of an order. Unit prices come from the order lines,
+// so later product price changes do not alter a printed receipt.
+func New(store Store, order *domain.Order) *Receipt {
+	r := &Receipt{
+		StoreName:    store.Name,
+		Footer:       store.Footer,
+		ReceiptCode:  order.ReceiptCode.String(),
+		OrderID:      order.ID,
+		CustomerName: order.CustomerName,
+		Status:       order.Status,
+		Lines:        make([]Line, 0, len(order.Products)),
+		Discount:     order.Discount,
+		TaxInclusive: order.TaxInclusive,
+		Taxes:        order.TaxBreakdown(),
+		TotalPrice:   order.TotalPrice,
+		TotalPaid:    order.TotalPaid,
+		Change:       order.TotalReturn,
+		CreatedAt:    order.CreatedAt,
+		width:        store.Width,
+		symbol:       store.Symbol,
+	}
+
+	if order.Payment != nil {
+		r.PaymentName = order.Payment.Name
+		r.PaymentType = order.Payment.Type
+	}
+
+	for _, orderProduct := range order.Products {
+		line := Line{
+			ProductID:        orderProduct.ProductID,
+			Name:             fmt.Sprintf("Product #%d", orderProduct.ProductID),
+			Quantity:         orderProduct.Quantity,
+			RefundedQuantity: orderProduct.RefundedQuantity,
+			Discount:         orderProduct.Discount,
+			Total:            orderProduct.TotalPrice,
+		}
+		if !order.TaxInclusive {
+			line.Total = math.Round((orderProduct.TotalPrice-orderProduct.TaxAmount)*100) / 100
+		}
+		if orderProduct.Quantity > 0 {
+			line.UnitPrice = math.Round(line.NormalTotal()/float64(orderProduct.Quantity)*100) / 100
+		}
+		if orderProduct.Promotion != nil {
+			line.Promotion = orderProduct.Promotion.Name
+		}
+		if orderProduct.Product != nil {
+			line.Name = orderProduct.Product.Name
+		}
+		r.Lines = append(r.Lines, line)
+	}
+
+	return r
+}
+
+// Subtotal is the total before the taxes added on top of the prices
+func (r *Receipt) Subtotal() float64 {
+	subtotal := r.TotalPrice
+	if !r.TaxInclusive {
+		for _, tax := range r.Taxes {
+			subtotal -= tax.Amount
+		}
+	}
+	return math.Round(subtotal*100) / 100
+}
+
+// taxLabel names a tax rate, such as "Tax 11%"
+func taxLabel(rate float64) string {
+	return "Tax " + strconv.FormatFloat(rate, 'f', -1, 64) + "%"
+}
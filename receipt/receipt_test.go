@@ -0,0 +1,184 @@
+package receipt
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"gotemplate/core/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testOrder is a paid order of two lines paid in cash
+func testOrder() *domain.Order {
+	return &domain.Order{
+		ID:           42,
+		CustomerName: "John Doe",
+		TotalPrice:   12500,
+		TotalPaid:    20000,
+		TotalReturn:  7500,
+		ReceiptCode:  uuid.MustParse("4979cf6e-d215-4ff8-9d0d-b3e99bcc7750"),
+		Status:       domain.OrderPaid,
+		CreatedAt:    time.Date(2024, 3, 1, 14, 5, 0, 0, time.UTC),
+		Payment:      &domain.Payment{Name: "Cash", Type: domain.Cash},
+		Products: []domain.OrderProduct{
+			{ProductID: 1, Quantity: 3, TotalPrice: 7500, Product: &domain.Product{Name: "Chiki Ball"}},
+			{ProductID: 2, Quantity: 1, TotalPrice: 5000, Product: &domain.Product{Name: "Extra long product name that cannot fit on one line"}},
+		},
+	}
+}
+
+func TestNewReceipt(t *testing.T) {
+	order := testOrder()
+	order.Products = append(order.Products, domain.OrderProduct{ProductID: 9, Quantity: 2, TotalPrice: 3})
+
+	r := New(Store{Name: "Go POS", Footer: "Thanks"}, order)
+
+	assert.Equal(t, "Go POS", r.StoreName)
+	assert.Equal(t, "4979cf6e-d215-4ff8-9d0d-b3e99bcc7750", r.ReceiptCode)
+	assert.Equal(t, domain.Cash, r.PaymentType)
+	assert.Equal(t, 7500.0, r.Change)
+	require.Len(t, r.Lines, 3)
+	assert.Equal(t, 2500.0, r.Lines[0].UnitPrice)
+	assert.Equal(t, "Product #9", r.Lines[2].Name)
+	assert.Equal(t, 1.5, r.Lines[2].UnitPrice)
+}
+
+func TestWriteTextFitsWidth(t *testing.T) {
+	for _, width := range []int{0, 10, 32, 48} {
+		r := New(Store{Name: "Go POS", Footer: "Thank you for shopping with us, see you again soon", Width: width}, testOrder())
+
+		text := r.String()
+
+		want := width
+		if want == 0 {
+			want = DefaultWidth
+		}
+		if want < minWidth {
+			want = minWidth
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			assert.LessOrEqual(t, utf8.RuneCountInString(line), want, "width %d: %q", width, line)
+		}
+		assert.Contains(t, text, "Go POS")
+		assert.Contains(t, text, "7500.00")
+		assert.Contains(t, text, "PAID Cash")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	r := New(Store{Name: "Go POS", Footer: "Thanks"}, testOrder())
+
+	want := `             Go POS
+--------------------------------
+Order                        #42
+Date            2024-03-01 14:05
+Customer                John Doe
+4979cf6e-d215-4ff8-9d0d-b3e99bcc
+7750
+--------------------------------
+Chiki Ball
+  3 x 2500.00            7500.00
+Extra long product name that
+cannot fit on one line
+  1 x 5000.00            5000.00
+--------------------------------
+TOTAL                   12500.00
+PAID Cash               20000.00
+CHANGE                   7500.00
+--------------------------------
+             Thanks
+`
+	assert.Equal(t, want, r.String())
+}
+
+func TestWriteTextStatus(t *testing.T) {
+	order := testOrder()
+	order.Status = domain.OrderPartiallyRefunded
+	order.Products[0].RefundedQuantity = 1
+
+	text := New(Store{Name: "Go POS"}, order).String()
+
+	assert.Contains(t, text, "*** PARTIALLY REFUNDED ***")
+	assert.Contains(t, text, "  refunded 1\n")
+}
+
+func TestWriteTextDiscounts(t *testing.T) {
+	order := testOrder()
+	order.Discount = 3000
+	order.TotalPrice = 9500
+	order.TotalReturn = 10500
+	order.Products[0].Discount = 2500
+	order.Products[0].TotalPrice = 5000
+	order.Products[0].Promotion = &domain.Promotion{Name: "Buy 2 get 1"}
+	order.Products[1].Discount = 500
+	order.Products[1].TotalPrice = 4500
+
+	r := New(Store{Name: "Go POS"}, order)
+	assert.Equal(t, 2500.0, r.Lines[0].UnitPrice)
+	assert.Equal(t, 5000.0, r.Lines[0].Total)
+
+	text := r.String()
+	assert.Contains(t, text, "  3 x 2500.00            7500.00\n  Buy 2 get 1           -2500.00\n")
+	assert.Contains(t, text, "  1 x 5000.00            5000.00\n  Discount               -500.00\n")
+	assert.Contains(t, text, "YOU SAVED                3000.00\nTOTAL                    9500.00\n")
+
+	var sb strings.Builder
+	require.NoError(t, r.WriteHTML(&sb))
+	assert.Contains(t, sb.String(), `<tr><td>Buy 2 get 1</td><td class="amount">-2500.00</td></tr>`)
+}
+
+func TestWriteTextTaxes(t *testing.T) {
+	order := testOrder()
+	order.TaxAmount = 825
+	order.TotalPrice = 13325
+	order.TotalReturn = 6675
+	order.Products[0].TaxRate = 11
+	order.Products[0].TaxAmount = 825
+	order.Products[0].TotalPrice = 8325
+
+	r := New(Store{Name: "Go POS"}, order)
+	assert.Equal(t, 7500.0, r.Lines[0].Total)
+	assert.Equal(t, 12500.0, r.Subtotal())
+
+	text := r.String()
+	assert.Contains(t, text, "  3 x 2500.00            7500.00\n")
+	assert.Contains(t, text, "Subtotal                12500.00\nTax 11%                   825.00\nTOTAL                   13325.00\n")
+
+	// Inclusive prices already hold the tax, which is only shown under the total
+	order.TaxInclusive = true
+	order.TaxAmount = 743.24
+	order.TotalPrice = 12500
+	order.Products[0].TaxAmount = 743.24
+	order.Products[0].TotalPrice = 7500
+
+	text = New(Store{Name: "Go POS"}, order).String()
+	assert.NotContains(t, text, "Subtotal")
+	assert.Contains(t, text, "TOTAL                   12500.00\n  incl. Tax 11%           743.24\n")
+}
+
+func TestWriteHTMLEscapes(t *testing.T) {
+	order := testOrder()
+	order.CustomerName = "<script>alert(1)</script>"
+
+	var sb strings.Builder
+	require.NoError(t, New(Store{Name: "Go & POS", Footer: "Thanks"}, order).WriteHTML(&sb))
+
+	page := sb.String()
+	assert.Contains(t, page, "<h1>Go &amp; POS</h1>")
+	assert.Contains(t, page, "&lt;script&gt;")
+	assert.NotContains(t, page, "<script>")
+	assert.Contains(t, page, "<footer>Thanks</footer>")
+	assert.NotContains(t, page, `class="status"`)
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, []string{"abc def", "ghi"}, wrapText("abc def ghi", 7))
+	assert.Equal(t, []string{"abcdefg", "hij"}, wrapText("abcdefghij", 7))
+	assert.Equal(t, []string{"ab", "cdefghi", "j"}, wrapText("ab cdefghij", 7))
+	assert.Nil(t, wrapText("   ", 7))
+}
@@ -0,0 +1,199 @@
+package receipt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"gotemplate/core/domain"
+)
+
+// timeLayout is how the order time is printed on a receipt
+const timeLayout = "2006-01-02 15:04"
+
+// WriteText renders the receipt as plain text laid out for a narrow thermal printer.
+// Every line fits the store width, long names and footers are wrapped.
+func (r *Receipt) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	t := r.newTextWriter(bw)
+
+	t.center(r.StoreName)
+	t.rule()
+	r.writeBody(t)
+
+	if r.Footer != "" {
+		t.rule()
+		t.center(r.Footer)
+	}
+
+	if t.err != nil {
+		return t.err
+	}
+	return bw.Flush()
+}
+
+// newTextWriter creates a textWriter using the store width
+func (r *Receipt) newTextWriter(w io.StringWriter) *textWriter {
+	return newTextWriter(w, r.width)
+}
+
+// newTextWriter creates a textWriter of the given width, DefaultWidth when zero
+func newTextWriter(w io.StringWriter, width int) *textWriter {
+	if width == 0 {
+		width = DefaultWidth
+	}
+	if width < minWidth {
+		width = minWidth
+	}
+	return &textWriter{w: w, width: width}
+}
+
+// writeBody writes the order details, the lines and the totals shared by every text layout
+func (r *Receipt) writeBody(t *textWriter) {
+	t.pair("Order", fmt.Sprintf("#%d", r.OrderID))
+	t.pair("Date", r.CreatedAt.Format(timeLayout))
+	t.pair("Customer", r.CustomerName)
+	t.wrap(r.ReceiptCode)
+	if r.Status != domain.OrderPaid {
+		t.center("*** " + statusLabel(r.Status) + " ***")
+	}
+	t.rule()
+
+	for _, line := range r.Lines {
+		t.wrap(line.Name)
+		t.pair(fmt.Sprintf("  %d x %s", line.Quantity, money(line.UnitPrice)), money(line.NormalTotal()))
+		if line.Discount > 0 {
+			t.pair("  "+line.DiscountLabel(), deducted(line.Discount))
+		}
+		if line.RefundedQuantity > 0 {
+			t.pair(fmt.Sprintf("  refunded %d", line.RefundedQuantity), "")
+		}
+	}
+	t.rule()
+
+	if r.Discount > 0 {
+		t.pair("YOU SAVED", money(r.Discount))
+	}
+	if !r.TaxInclusive && len(r.Taxes) > 0 {
+		t.pair("Subtotal", money(r.Subtotal()))
+		for _, tax := range r.Taxes {
+			t.pair(taxLabel(tax.Rate), money(tax.Amount))
+		}
+	}
+	t.emphasize(true)
+	t.pair("TOTAL", money(r.TotalPrice))
+	t.emphasize(false)
+	if r.TaxInclusive {
+		for _, tax := range r.Taxes {
+			t.pair("  incl. "+taxLabel(tax.Rate), money(tax.Amount))
+		}
+	}
+	payment := "PAID"
+	if r.PaymentName != "" {
+		payment = "PAID " + r.PaymentName
+	}
+	t.pair(payment, money(r.TotalPaid))
+	t.pair("CHANGE", money(r.Change))
+}
+
+// String renders the receipt as plain text
+func (r *Receipt) String() string {
+	var sb strings.Builder
+	_ = r.WriteText(&sb)
+	return sb.String()
+}
+
+// money formats an amount with two decimals
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// textWriter writes fixed width receipt lines, keeping the first write error
+type textWriter struct {
+	w     io.StringWriter
+	width int
+	err   error
+	// bold switches emphasized printing when the output supports it
+	bold func(on bool)
+}
+
+// emphasize turns bold text on or off, a no-op for plain text
+func (t *textWriter) emphasize(on bool) {
+	if t.bold != nil {
+		t.bold(on)
+	}
+}
+
+// line writes one line of text
+func (t *textWriter) line(s string) {
+	if t.err != nil {
+		return
+	}
+	_, t.err = t.w.WriteString(strings.TrimRight(s, " ") + "\n")
+}
+
+// rule writes a horizontal separator
+func (t *textWriter) rule() {
+	t.line(strings.Repeat("-", t.width))
+}
+
+// wrap writes s left aligned, wrapped to the line width
+func (t *textWriter) wrap(s string) {
+	for _, l := range wrapText(s, t.width) {
+		t.line(l)
+	}
+}
+
+// center writes s centered, wrapped to the line width
+func (t *textWriter) center(s string) {
+	for _, l := range wrapText(s, t.width) {
+		t.line(strings.Repeat(" ", (t.width-utf8.RuneCountInString(l))/2) + l)
+	}
+}
+
+// pair writes left and right aligned text on one line,
+// or the left text wrapped above the right text when both do not fit
+func (t *textWriter) pair(left, right string) {
+	gap := t.width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	if gap >= 1 {
+		t.line(left + strings.Repeat(" ", gap) + right)
+		return
+	}
+
+	t.wrap(left)
+	if right != "" {
+		for _, l := range wrapText(right, t.width) {
+			t.line(strings.Repeat(" ", t.width-utf8.RuneCountInString(l)) + l)
+		}
+	}
+}
+
+// wrapText splits s into lines of at most width runes, breaking words longer than a line
+func wrapText(s string, width int) []string {
+	var lines []string
+	var current []rune
+
+	for _, word := range strings.Fields(s) {
+		runes := []rune(word)
+		if len(current) > 0 && len(current)+1+len(runes) > width {
+			lines = append(lines, string(current))
+			current = nil
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		for len(current)+len(runes) > width {
+			n := width - len(current)
+			lines = append(lines, string(append(current, runes[:n]...)))
+			current, runes = nil, runes[n:]
+		}
+		current = append(current, runes...)
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+
+	return lines
+}
@@ -0,0 +1,147 @@
+package receipt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"gotemplate/core/domain"
+	"gotemplate/receipt/escpos"
+)
+
+// ZReport is the printout of the sales and cash reconciliation of one shift or one day
+type ZReport struct {
+	StoreName string
+	Title     string
+	Report    *domain.ZReport
+
+	width int
+}
+
+// NewZReport builds the printout of report under the given title, such as "Z-REPORT SHIFT #12"
+func NewZReport(store Store, title string, report *domain.ZReport) *ZReport {
+	return &ZReport{
+		StoreName: store.Name,
+		Title:     title,
+		Report:    report,
+		width:     store.Width,
+	}
+}
+
+// WriteText renders the Z-report as plain text laid out for a narrow thermal printer
+func (z *ZReport) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	t := newTextWriter(bw, z.width)
+
+	t.center(z.StoreName)
+	t.center(z.Title)
+	t.rule()
+	z.writeBody(t)
+
+	if t.err != nil {
+		return t.err
+	}
+	return bw.Flush()
+}
+
+// WriteESCPOS renders the Z-report as ESC/POS commands followed by a paper cut
+func (z *ZReport) WriteESCPOS(w io.Writer) error {
+	e := escpos.NewEncoder(w)
+	t := newTextWriter(e, z.width)
+	t.bold = e.SetBold
+
+	e.Init()
+	e.SetAlign(escpos.AlignCenter)
+	e.SetBold(true)
+	for _, l := range wrapText(z.StoreName, t.width) {
+		e.Line(l)
+	}
+	for _, l := range wrapText(z.Title, t.width) {
+		e.Line(l)
+	}
+	e.SetBold(false)
+	e.SetAlign(escpos.AlignLeft)
+
+	t.rule()
+	z.writeBody(t)
+
+	e.Feed(feedBeforeCut)
+	e.Cut()
+
+	if t.err != nil {
+		return t.err
+	}
+	return e.Flush()
+}
+
+// writeBody writes the shifts, the sales, the payment totals and the cash reconciliation
+func (z *ZReport) writeBody(t *textWriter) {
+	r := z.Report
+
+	for _, shift := range r.Shifts {
+		closed := "OPEN"
+		if shift.ClosedAt != nil {
+			closed = shift.ClosedAt.Format(timeLayout)
+		}
+		t.pair(fmt.Sprintf("Shift #%d", shift.ID), fmt.Sprintf("cashier #%d", shift.UserID))
+		t.pair("  Opened", shift.OpenedAt.Format(timeLayout))
+		t.pair("  Closed", closed)
+	}
+	if len(r.Shifts) == 0 {
+		t.center("No shifts")
+	}
+	t.rule()
+
+	t.pair("Orders", fmt.Sprintf("%d", r.Orders))
+	t.pair("Gross sales", money(r.GrossSales))
+	t.pair(fmt.Sprintf("Refunds (%d)", r.Refunds), deducted(r.RefundAmount))
+	t.emphasize(true)
+	t.pair("NET SALES", money(r.NetSales))
+	t.emphasize(false)
+	if r.Cancelled > 0 {
+		t.pair(fmt.Sprintf("Cancelled (%d)", r.Cancelled), money(r.CancelledAmount))
+	}
+	t.rule()
+
+	for _, p := range r.Payments {
+		t.wrap(fmt.Sprintf("%s (%s)", p.Name, p.Type))
+		t.pair(fmt.Sprintf("  %d orders", p.Orders), money(p.Sales))
+		if p.Refunds != 0 {
+			t.pair("  refunds", deducted(p.Refunds))
+		}
+	}
+	if len(r.Payments) > 0 {
+		t.rule()
+	}
+
+	t.pair("Opening float", money(r.OpeningFloat))
+	t.pair("Cash sales", money(r.CashSales))
+	t.pair("Cash refunds", deducted(r.CashRefunds))
+	t.emphasize(true)
+	t.pair("EXPECTED CASH", money(r.ExpectedCash))
+	t.emphasize(false)
+	if r.CountedCash != nil && r.Variance != nil {
+		t.pair("Counted cash", money(*r.CountedCash))
+		t.pair(varianceLabel(*r.Variance), money(*r.Variance))
+	}
+}
+
+// deducted formats an amount taken off a total, with a minus sign unless zero
+func deducted(amount float64) string {
+	if amount == 0 {
+		return money(0)
+	}
+	return money(-amount)
+}
+
+// varianceLabel names a cash variance: over when the drawer holds more than expected
+func varianceLabel(variance float64) string {
+	switch {
+	case variance > 0:
+		return "OVER"
+	case variance < 0:
+		return "SHORT"
+	default:
+		return "BALANCED"
+	}
+}
@@ -0,0 +1,107 @@
+package receipt
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotemplate/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testZReport is a closed shift with cash and e-wallet sales, a cash refund and a short drawer
+func testZReport() *domain.ZReport {
+	closedAt := time.Date(2024, 3, 1, 17, 0, 0, 0, time.UTC)
+	counted, variance := 104500.0, -500.0
+
+	return &domain.ZReport{
+		Shifts: []domain.Shift{{
+			ID:           12,
+			UserID:       3,
+			OpeningFloat: 50000,
+			OpenedAt:     time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+			ClosedAt:     &closedAt,
+		}},
+		Orders:          5,
+		GrossSales:      80000,
+		Cancelled:       1,
+		CancelledAmount: 2500,
+		Refunds:         1,
+		RefundAmount:    5000,
+		NetSales:        75000,
+		Payments: []domain.PaymentTotal{
+			{PaymentID: 1, Name: "Cash", Type: domain.Cash, Orders: 3, Sales: 60000, Refunds: 5000},
+			{PaymentID: 2, Name: "OVO", Type: domain.EWallet, Orders: 2, Sales: 20000},
+		},
+		OpeningFloat: 50000,
+		CashSales:    60000,
+		CashRefunds:  5000,
+		ExpectedCash: 105000,
+		CountedCash:  &counted,
+		Variance:     &variance,
+	}
+}
+
+func TestZReportWriteText(t *testing.T) {
+	z := NewZReport(Store{Name: "Go POS"}, "Z-REPORT SHIFT #12", testZReport())
+
+	var buf bytes.Buffer
+	require.NoError(t, z.WriteText(&buf))
+
+	want := `             Go POS
+       Z-REPORT SHIFT #12
+--------------------------------
+Shift #12             cashier #3
+  Opened        2024-03-01 08:00
+  Closed        2024-03-01 17:00
+--------------------------------
+Orders                         5
+Gross sales             80000.00
+Refunds (1)             -5000.00
+NET SALES               75000.00
+Cancelled (1)            2500.00
+--------------------------------
+Cash (CASH)
+  3 orders              60000.00
+  refunds               -5000.00
+OVO (E-WALLET)
+  2 orders              20000.00
+--------------------------------
+Opening float           50000.00
+Cash sales              60000.00
+Cash refunds            -5000.00
+EXPECTED CASH          105000.00
+Counted cash           104500.00
+SHORT                    -500.00
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestZReportOpenShift(t *testing.T) {
+	report := &domain.ZReport{
+		Shifts:       []domain.Shift{{ID: 7, UserID: 2, OpeningFloat: 1000, OpenedAt: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)}},
+		OpeningFloat: 1000,
+		ExpectedCash: 1000,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewZReport(Store{Name: "Go POS"}, "X-REPORT", report).WriteText(&buf))
+
+	text := buf.String()
+	assert.Contains(t, text, "OPEN")
+	assert.Contains(t, text, "Refunds (0)                 0.00")
+	assert.NotContains(t, text, "Counted cash")
+	assert.NotContains(t, text, "-0.00")
+}
+
+func TestZReportWriteESCPOS(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewZReport(Store{Name: "Go POS", Width: 48}, "Z-REPORT", testZReport()).WriteESCPOS(&buf))
+
+	out := buf.Bytes()
+	assert.True(t, bytes.HasPrefix(out, []byte{0x1b, '@'}))
+	assert.True(t, bytes.HasSuffix(out, []byte{0x1d, 'V', 'A', 0}))
+	assert.Contains(t, buf.String(), "EXPECTED CASH")
+}
@@ -54,7 +54,8 @@ func (br *BagRepository) GetBagByID(gctx *gin.Context, id uint64) (*domain.Bag1,
 	return SelectOne(ctx, br.Db, query, pgx.RowToAddrOfStructByName[domain.Bag1], br.log)
 }
 
-func (br *BagRepository) GetBags(gctx *gin.Context, skip, limit uint64) ([]domain.Bag, error) {
+// GetBags retrieves a page of bags with their articles and phones in bag id order
+func (br *BagRepository) GetBags(gctx *gin.Context, params PageParams) ([]domain.Bag, Page, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -67,24 +68,21 @@ func (br *BagRepository) GetBags(gctx *gin.Context, skip, limit uint64) ([]domai
 		From("public.bag b").
 		LeftJoin("public.articles a ON b.bagid = a.bagid").
 		LeftJoin("public.user_phones p ON b.bagid = p.bagid").
-		Limit(limit).
-		Offset((skip - 1) * limit).
 		GroupBy("b.bagid, b.bagname, b.bagweight")
 
-	//sm := psql.Select(BagTableColumns.Bagid, BagTableColumns.Bagname, BagTableColumns.Bagweight).From("bag")
-	//sb := psql.Select("*").From("bag")
-	return SelectRows(ctx, br.Db, sb, pgx.RowToStructByNameLax[domain.Bag], br.log)
-	// t, err := SelectRows1(ctx, br.Db, sb, pgx.RowToStructByNameLax[domain.Bag1], br.log)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// jsonData, _ := json.Marshal(t)
-
-	// // Convert the JSON to a struct
-	// var structData []domain.Bag1
-	// json.Unmarshal(jsonData, &structData)
+	var bags []domain.Bag
+	var page Page
+	err := br.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		bags, page, err = selectPage(ctx, tx, sb, idKeyset("b.bagid"), params, pgx.RowToStructByNameLax[domain.Bag],
+			func(bag *domain.Bag) []any { return []any{bag.BagID} })
+		return err
+	})
+	if err != nil {
+		return nil, Page{}, err
+	}
 
-	// return structData, nil
+	return bags, page, nil
 }
 
 func (br *BagRepository) Insertbag(gctx *gin.Context, bag domain.Bag1) (domain.Bag1, error) {
@@ -30,6 +30,29 @@ func NewCategoryRepository(Db *DB,log *logger.Logger) *CategoryRepository {
 	}
 }
 
+// categoryColumns lists the categories columns read by scanCategory
+const categoryColumns = "id, name, tax_rate, created_at, updated_at"
+
+// scanCategory scans a row selected with categoryColumns into category
+func scanCategory(row pgx.Row, category *domain.Category) error {
+	return row.Scan(
+		&category.ID,
+		&category.Name,
+		&category.TaxRate,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+}
+
+// taxRateExpr sets a nullable tax rate column to rate, keeping the current rate when rate is nil
+// and clearing it when clear is set
+func taxRateExpr(rate *float64, clear bool) sq.Sqlizer {
+	if clear {
+		return sq.Expr("NULL")
+	}
+	return sq.Expr("COALESCE(?, tax_rate)", rate)
+}
+
 // CreateCategory creates a new category record in the database
 func (cr *CategoryRepository) CreateCategory(gctx *gin.Context, category *domain.Category) (*domain.Category, error) {
 	
@@ -37,24 +60,16 @@ func (cr *CategoryRepository) CreateCategory(gctx *gin.Context, category *domain
 	defer cancel()
 	
 	query := psql.Insert("categories").
-		Columns("name").
-		Values(category.Name).
-		Suffix("RETURNING *")
+		Columns("name", "tax_rate").
+		Values(category.Name, category.TaxRate).
+		Suffix("RETURNING " + categoryColumns)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-cr.Db.QueryRow(ctx, sql, args...)
-
-
-	err = cr.Db.QueryRow(ctx, sql, args...).Scan(
-		&category.ID,
-		&category.Name,
-		&category.CreatedAt,
-		&category.UpdatedAt,
-	)
+	err = scanCategory(cr.Db.QueryRow(ctx, sql, args...), category)
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +85,7 @@ func (cr *CategoryRepository) GetCategoryByID(gctx *gin.Context, id uint64) (*do
 	
 	var category domain.Category
 
-	query := psql.Select("*").
+	query := psql.Select(categoryColumns).
 		From("categories").
 		Where(sq.Eq{"id": id}).
 		Limit(1)
@@ -80,12 +95,7 @@ func (cr *CategoryRepository) GetCategoryByID(gctx *gin.Context, id uint64) (*do
 		return nil, err
 	}
 
-	err = cr.Db.QueryRow(ctx, sql, args...).Scan(
-		&category.ID,
-		&category.Name,
-		&category.CreatedAt,
-		&category.UpdatedAt,
-	)
+	err = scanCategory(cr.Db.QueryRow(ctx, sql, args...), &category)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, port.ErrDataNotFound
@@ -96,69 +106,52 @@ func (cr *CategoryRepository) GetCategoryByID(gctx *gin.Context, id uint64) (*do
 	return &category, nil
 }
 
-// ListCategories retrieves a list of categories from the database
-func (cr *CategoryRepository) ListCategories(gctx *gin.Context, skip, limit uint64) ([]domain.Category, error) {
+// ListCategories retrieves a page of the categories matching lq from the database in its sort order
+func (cr *CategoryRepository) ListCategories(gctx *gin.Context, lq ListQuery, params PageParams) ([]domain.Category, Page, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	
-	var category domain.Category
-	var categories []domain.Category
 
-	query := psql.Select("*").
-		From("categories").
-		OrderBy("id").
-		Limit(limit).
-		Offset((skip - 1) * limit)
+	query := psql.Select(categoryColumns).
+		From("categories")
 
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := cr.Db.Query(ctx, sql, args...)
+	var categories []domain.Category
+	var page Page
+	err := cr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		categories, page, err = selectList(ctx, tx, query, CategorySchema, lq, params,
+			func(row pgx.CollectableRow) (domain.Category, error) {
+				var category domain.Category
+				err := scanCategory(row, &category)
+				return category, err
+			})
+		return err
+	})
 	if err != nil {
-		return nil, err
-	}
-
-	for rows.Next() {
-		err := rows.Scan(
-			&category.ID,
-			&category.Name,
-			&category.CreatedAt,
-			&category.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		categories = append(categories, category)
+		return nil, Page{}, err
 	}
 
-	return categories, nil
+	return categories, page, nil
 }
 
-// UpdateCategory updates a category record in the database
-func (cr *CategoryRepository) UpdateCategory(gctx *gin.Context, category *domain.Category) (*domain.Category, error) {
+// UpdateCategory updates a category record in the database.
+// A nil tax rate keeps the current one unless clearTaxRate is set.
+func (cr *CategoryRepository) UpdateCategory(gctx *gin.Context, category *domain.Category, clearTaxRate bool) (*domain.Category, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	
 	query := psql.Update("categories").
 		Set("name", category.Name).
+		Set("tax_rate", taxRateExpr(category.TaxRate, clearTaxRate)).
 		Set("updated_at", time.Now()).
 		Where(sq.Eq{"id": category.ID}).
-		Suffix("RETURNING *")
+		Suffix("RETURNING " + categoryColumns)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	err = cr.Db.QueryRow(ctx, sql, args...).Scan(
-		&category.ID,
-		&category.Name,
-		&category.CreatedAt,
-		&category.UpdatedAt,
-	)
+	err = scanCategory(cr.Db.QueryRow(ctx, sql, args...), category)
 	if err != nil {
 		return nil, err
 	}
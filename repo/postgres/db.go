@@ -7,9 +7,11 @@ import (
 
 	//"os"
 	"gotemplate/config"
+	"math/rand"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -72,6 +74,9 @@ func (db *DB) Close() {
 	db.Pool.Close()
 }
 
+// WithTx runs fn in a read-write transaction. fn is run again, in a new transaction,
+// when the transaction fails with a serialization failure or a deadlock, so it must
+// not keep state between attempts.
 func (db *DB) WithTx(ctx context.Context, fn func(tx pgx.Tx) error, levels ...pgx.TxIsoLevel) error {
 	var level pgx.TxIsoLevel
 	if len(levels) > 0 {
@@ -79,7 +84,44 @@ func (db *DB) WithTx(ctx context.Context, fn func(tx pgx.Tx) error, levels ...pg
 	} else {
 		level = pgx.ReadCommitted // Default value
 	}
-	return db.inTx(ctx, level, "", fn)
+
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = db.inTx(ctx, level, "", fn)
+		if err == nil || !isRetryableTxError(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		backoff := time.Duration(attempt) * txRetryBackoff
+		backoff += time.Duration(rand.Int63n(int64(txRetryBackoff)))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+	return err
+}
+
+const (
+	// maxTxAttempts is how many times WithTx runs a transaction that keeps failing with a retryable error
+	maxTxAttempts = 3
+	// txRetryBackoff is the base delay between transaction attempts
+	txRetryBackoff = 10 * time.Millisecond
+
+	// serializationFailure and deadlockDetected are the SQLSTATE codes of retryable transaction failures
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// isRetryableTxError reports whether a transaction failed with a serialization failure or a deadlock
+func isRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
 }
 
 func (db *DB) ReadTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
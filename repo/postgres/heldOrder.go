@@ -0,0 +1,415 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// heldOrderColumns lists the held_orders columns read by scanHeldOrder
+const heldOrderColumns = "id, user_id, terminal, customer_name, note, created_at, updated_at, expires_at"
+
+// scanHeldOrder scans a row selected with heldOrderColumns into held
+func scanHeldOrder(row pgx.Row, held *domain.HeldOrder) error {
+	return row.Scan(
+		&held.ID,
+		&held.UserID,
+		&held.Terminal,
+		&held.CustomerName,
+		&held.Note,
+		&held.CreatedAt,
+		&held.UpdatedAt,
+		&held.ExpiresAt,
+	)
+}
+
+// heldOrderKeyset lists the held orders the most recently held first
+var heldOrderKeyset = keyset{columns: []keyColumn{{name: "updated_at", sqlType: "timestamptz", desc: true}}, id: "id", desc: true}
+
+// unexpiredHeldOrders selects the held orders that have not expired yet
+func unexpiredHeldOrders() sq.SelectBuilder {
+	return psql.Select(heldOrderColumns).
+		From("held_orders").
+		Where(sq.Expr("expires_at > now()"))
+}
+
+// HoldOrder parks the lines of a cart without taking them out of stock or taking payment.
+// The products must exist; their stock is only checked when the held order is completed.
+func (or *OrderRepository) HoldOrder(gctx *gin.Context, held *domain.HeldOrder) (*domain.HeldOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := held.Products
+
+	err := or.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		query := psql.Insert("held_orders").
+			Columns("user_id", "terminal", "customer_name", "note", "expires_at").
+			Values(held.UserID, held.Terminal, held.CustomerName, held.Note, held.ExpiresAt).
+			Suffix("RETURNING " + heldOrderColumns)
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+
+		err = scanHeldOrder(tx.QueryRow(ctx, sql, args...), held)
+		if err != nil {
+			return err
+		}
+
+		held.Products = lines
+		return insertHeldOrderProducts(ctx, tx, held)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return held, nil
+}
+
+// insertHeldOrderProducts checks that the products of the lines of held exist, inserts the lines in tx
+// and loads their products
+func insertHeldOrderProducts(ctx context.Context, tx pgx.Tx, held *domain.HeldOrder) error {
+	productIDs := make([]uint64, 0, len(held.Products))
+	for _, line := range held.Products {
+		productIDs = append(productIDs, line.ProductID)
+	}
+
+	products, err := selectPricedProducts(ctx, tx, productIDs, false)
+	if err != nil {
+		return err
+	}
+
+	for i := range held.Products {
+		line := &held.Products[i]
+		product, ok := products[line.ProductID]
+		if !ok {
+			return port.ErrDataNotFound
+		}
+
+		query := psql.Insert("held_order_products").
+			Columns("held_order_id", "product_id", "quantity").
+			Values(held.ID, line.ProductID, line.Quantity).
+			Suffix("RETURNING id")
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, sql, args...).Scan(&line.ID)
+		if err != nil {
+			return err
+		}
+		line.HeldOrderID = held.ID
+		line.Product = product
+	}
+
+	return nil
+}
+
+// GetHeldOrderByID retrieves an unexpired held order with its lines and their products by id
+func (or *OrderRepository) GetHeldOrderByID(gctx *gin.Context, id uint64) (*domain.HeldOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var held domain.HeldOrder
+	err := or.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		return selectHeldOrder(ctx, tx, id, false, &held)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &held, nil
+}
+
+// selectHeldOrder selects an unexpired held order with its lines and their products by id,
+// locking it when forUpdate is set
+func selectHeldOrder(ctx context.Context, tx pgx.Tx, id uint64, forUpdate bool, held *domain.HeldOrder) error {
+	query := unexpiredHeldOrders().
+		Where(sq.Eq{"id": id})
+	if forUpdate {
+		query = query.Suffix("FOR UPDATE")
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	err = scanHeldOrder(tx.QueryRow(ctx, sql, args...), held)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return port.ErrDataNotFound
+		}
+		return err
+	}
+
+	helds := []domain.HeldOrder{*held}
+	if err := hydrateHeldOrders(ctx, tx, helds); err != nil {
+		return err
+	}
+
+	*held = helds[0]
+	return nil
+}
+
+// ListHeldOrders retrieves a page of the unexpired held orders matching filter, the most recently held first
+func (or *OrderRepository) ListHeldOrders(gctx *gin.Context, filter domain.HeldOrderFilter, params PageParams) ([]domain.HeldOrder, Page, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := unexpiredHeldOrders()
+	if filter.UserID != 0 {
+		query = query.Where(sq.Eq{"user_id": filter.UserID})
+	}
+	if filter.Terminal != "" {
+		query = query.Where(sq.Eq{"terminal": filter.Terminal})
+	}
+
+	var helds []domain.HeldOrder
+	var page Page
+	err := or.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		helds, page, err = selectPage(ctx, tx, query, heldOrderKeyset, params,
+			func(row pgx.CollectableRow) (domain.HeldOrder, error) {
+				var held domain.HeldOrder
+				err := scanHeldOrder(row, &held)
+				return held, err
+			},
+			func(held *domain.HeldOrder) []any { return []any{held.UpdatedAt, held.ID} })
+		if err != nil {
+			return err
+		}
+
+		return hydrateHeldOrders(ctx, tx, helds)
+	})
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	return helds, page, nil
+}
+
+// hydrateHeldOrders loads the lines of held orders and their products in two queries
+func hydrateHeldOrders(ctx context.Context, tx pgx.Tx, helds []domain.HeldOrder) error {
+	if len(helds) == 0 {
+		return nil
+	}
+
+	heldIDs := make([]uint64, 0, len(helds))
+	byID := make(map[uint64]*domain.HeldOrder, len(helds))
+	for i := range helds {
+		helds[i].Products = nil
+		heldIDs = append(heldIDs, helds[i].ID)
+		byID[helds[i].ID] = &helds[i]
+	}
+
+	linesQuery := psql.Select("id", "held_order_id", "product_id", "quantity").
+		From("held_order_products").
+		Where("held_order_id = ANY(?)", heldIDs).
+		OrderBy("held_order_id", "id")
+
+	var productIDs []uint64
+	err := scanRows(ctx, tx, linesQuery, func(rows pgx.Rows) error {
+		var line domain.HeldOrderProduct
+		if err := rows.Scan(&line.ID, &line.HeldOrderID, &line.ProductID, &line.Quantity); err != nil {
+			return err
+		}
+
+		held := byID[line.HeldOrderID]
+		held.Products = append(held.Products, line)
+		productIDs = append(productIDs, line.ProductID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	products, err := selectPricedProducts(ctx, tx, productIDs, false)
+	if err != nil {
+		return err
+	}
+
+	for i := range helds {
+		for j := range helds[i].Products {
+			helds[i].Products[j].Product = products[helds[i].Products[j].ProductID]
+		}
+	}
+
+	return nil
+}
+
+// UpdateHeldOrder replaces the details and the lines of an unexpired held order,
+// holding it again until its new ExpiresAt
+func (or *OrderRepository) UpdateHeldOrder(gctx *gin.Context, held *domain.HeldOrder) (*domain.HeldOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := held.Products
+
+	err := or.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		query := psql.Update("held_orders").
+			Set("terminal", held.Terminal).
+			Set("customer_name", held.CustomerName).
+			Set("note", held.Note).
+			Set("expires_at", held.ExpiresAt).
+			Set("updated_at", time.Now()).
+			Where(sq.Eq{"id": held.ID}).
+			Where(sq.Expr("expires_at > now()")).
+			Suffix("RETURNING " + heldOrderColumns)
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+
+		err = scanHeldOrder(tx.QueryRow(ctx, sql, args...), held)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return port.ErrDataNotFound
+			}
+			return err
+		}
+
+		deleteQuery := psql.Delete("held_order_products").
+			Where(sq.Eq{"held_order_id": held.ID})
+
+		sql, args, err = deleteQuery.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+
+		held.Products = lines
+		return insertHeldOrderProducts(ctx, tx, held)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return held, nil
+}
+
+// DeleteHeldOrder discards an unexpired held order by id
+func (or *OrderRepository) DeleteHeldOrder(gctx *gin.Context, id uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Delete("held_orders").
+		Where(sq.Eq{"id": id}).
+		Where(sq.Expr("expires_at > now()"))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	tag, err := or.Db.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return port.ErrDataNotFound
+	}
+
+	return nil
+}
+
+// CompleteHeldOrder converts an unexpired held order to an order created from its lines as CreateOrder does,
+// and deletes the held order in the same transaction. The payment, cashier and tax settings are read from order;
+// the customer name of the held order is used when order has none.
+// When idem is set, the key and the rendered response are stored in the same transaction.
+func (or *OrderRepository) CompleteHeldOrder(gctx *gin.Context, id uint64, order *domain.Order, idem *domain.IdempotencyKey) (*domain.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	customerName := order.CustomerName
+
+	err := or.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := claimIdempotencyKey(ctx, tx, idem); err != nil {
+			return err
+		}
+
+		// The lock keeps the held order from being completed twice
+		var held domain.HeldOrder
+		if err := selectHeldOrder(ctx, tx, id, true, &held); err != nil {
+			return err
+		}
+
+		order.CustomerName = customerName
+		if order.CustomerName == "" {
+			order.CustomerName = held.CustomerName
+		}
+		order.Products = held.OrderProducts()
+		if err := insertOrder(ctx, tx, order); err != nil {
+			return err
+		}
+
+		query := psql.Delete("held_orders").
+			Where(sq.Eq{"id": held.ID})
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+
+		return storeIdempotencyResponse(ctx, tx, idem)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// DeleteExpiredHeldOrders deletes the expired held orders and returns how many were deleted
+func (or *OrderRepository) DeleteExpiredHeldOrders(ctx context.Context) (int64, error) {
+	query := psql.Delete("held_orders").
+		Where(sq.Expr("expires_at <= now()"))
+
+	ct, err := Delete(ctx, or.Db, query, or.log)
+	if err != nil {
+		return 0, err
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+// RunHeldOrderCleanup deletes expired held orders every interval until ctx is done
+func (or *OrderRepository) RunHeldOrderCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			n, err := or.DeleteExpiredHeldOrders(cctx)
+			cancel()
+			if err != nil {
+				or.log.Error("deleting expired held orders: %s", err.Error())
+				continue
+			}
+			if n > 0 {
+				or.log.Debug("deleted %d expired held orders", n)
+			}
+		}
+	}
+}
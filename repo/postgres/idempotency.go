@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+/**
+ * IdempotencyRepository stores idempotency keys with the response
+ * of the request first made with them and provides an access to the postgres database
+ */
+type IdempotencyRepository struct {
+	Db  *DB
+	log *logger.Logger
+}
+
+// NewIdempotencyRepository creates a new idempotency repository instance
+func NewIdempotencyRepository(Db *DB, log *logger.Logger) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		Db,
+		log,
+	}
+}
+
+// GetIdempotencyKey gets the latest unexpired idempotency key of a user, whatever path it was used on
+func (ir *IdempotencyRepository) GetIdempotencyKey(gctx *gin.Context, userID uint64, key string) (*domain.IdempotencyKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var idem domain.IdempotencyKey
+
+	query := psql.Select("key", "user_id", "path", "request_hash", "coalesce(status_code, 0)", "response", "created_at", "expires_at").
+		From("idempotency_keys").
+		Where(sq.Eq{"user_id": userID, "key": key}).
+		Where(sq.Expr("expires_at > now()")).
+		OrderBy("created_at DESC").
+		Limit(1)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ir.Db.QueryRow(ctx, sql, args...).Scan(
+		&idem.Key,
+		&idem.UserID,
+		&idem.Path,
+		&idem.RequestHash,
+		&idem.StatusCode,
+		&idem.Response,
+		&idem.CreatedAt,
+		&idem.ExpiresAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, port.ErrDataNotFound
+		}
+		return nil, err
+	}
+
+	return &idem, nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes the expired idempotency keys and returns how many were deleted
+func (ir *IdempotencyRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	query := psql.Delete("idempotency_keys").
+		Where(sq.Expr("expires_at <= now()"))
+
+	ct, err := Delete(ctx, ir.Db, query, ir.log)
+	if err != nil {
+		return 0, err
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+// RunCleanup deletes expired idempotency keys every interval until ctx is done
+func (ir *IdempotencyRepository) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			n, err := ir.DeleteExpiredIdempotencyKeys(cctx)
+			cancel()
+			if err != nil {
+				ir.log.Error("deleting expired idempotency keys: %s", err.Error())
+				continue
+			}
+			if n > 0 {
+				ir.log.Debug("deleted %d expired idempotency keys", n)
+			}
+		}
+	}
+}
+
+// claimIdempotencyKey stores an idempotency key at the start of tx.
+// A concurrent request with the same key waits on the row lock and then gets ErrIdempotencyKeyInUse;
+// an expired key is taken over.
+func claimIdempotencyKey(ctx context.Context, tx pgx.Tx, idem *domain.IdempotencyKey) error {
+	if idem == nil {
+		return nil
+	}
+
+	query := psql.Insert("idempotency_keys").
+		Columns("key", "user_id", "path", "request_hash", "expires_at").
+		Values(idem.Key, idem.UserID, idem.Path, idem.RequestHash, idem.ExpiresAt).
+		Suffix(`ON CONFLICT (user_id, path, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status_code = NULL, response = NULL,
+				created_at = now(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= now()
+			RETURNING created_at`)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, sql, args...).Scan(&idem.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return port.ErrIdempotencyKeyInUse
+		}
+		return err
+	}
+	return nil
+}
+
+// storeIdempotencyResponse renders and stores the response of the request made with idem in tx
+func storeIdempotencyResponse(ctx context.Context, tx pgx.Tx, idem *domain.IdempotencyKey) error {
+	if idem == nil || idem.Render == nil {
+		return nil
+	}
+
+	statusCode, body, err := idem.Render()
+	if err != nil {
+		return err
+	}
+	idem.StatusCode, idem.Response = statusCode, body
+
+	query := psql.Update("idempotency_keys").
+		Set("status_code", statusCode).
+		Set("response", body).
+		Where(sq.Eq{"user_id": idem.UserID, "path": idem.Path, "key": idem.Key})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	return err
+}
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role varchar(20) NOT NULL DEFAULT 'cashier'
+        CONSTRAINT users_role_check CHECK (role IN ('admin', 'cashier'));
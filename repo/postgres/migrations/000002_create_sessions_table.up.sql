@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      bigint      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id    uuid        NOT NULL,
+    token_hash   char(64)    NOT NULL UNIQUE,
+    user_agent   text        NOT NULL DEFAULT '',
+    client_ip    text        NOT NULL DEFAULT '',
+    expires_at   timestamptz NOT NULL,
+    revoked_at   timestamptz,
+    replaced_by  uuid REFERENCES sessions (id) ON DELETE SET NULL,
+    last_used_at timestamptz,
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_active_idx ON sessions (user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS sessions_family_id_idx ON sessions (family_id);
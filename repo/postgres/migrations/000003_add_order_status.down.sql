@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS order_refund_products;
+DROP TABLE IF EXISTS order_refunds;
+
+ALTER TABLE order_products
+    DROP COLUMN IF EXISTS refunded_quantity;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS cancel_reason,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS cancelled_by,
+    DROP COLUMN IF EXISTS status;
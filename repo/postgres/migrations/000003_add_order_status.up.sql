@@ -0,0 +1,32 @@
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'paid'
+        CONSTRAINT orders_status_check CHECK (status IN ('pending', 'paid', 'cancelled', 'refunded', 'partially_refunded')),
+    ADD COLUMN IF NOT EXISTS cancelled_by bigint REFERENCES users (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS cancelled_at timestamptz,
+    ADD COLUMN IF NOT EXISTS cancel_reason text;
+
+ALTER TABLE order_products
+    ADD COLUMN IF NOT EXISTS refunded_quantity bigint NOT NULL DEFAULT 0
+        CONSTRAINT order_products_refunded_quantity_check CHECK (refunded_quantity BETWEEN 0 AND quantity);
+
+CREATE TABLE IF NOT EXISTS order_refunds (
+    id         bigserial PRIMARY KEY,
+    order_id   bigint         NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    user_id    bigint         REFERENCES users (id) ON DELETE SET NULL,
+    reason     text           NOT NULL,
+    amount     numeric(12, 2) NOT NULL,
+    created_at timestamptz    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_refunds_order_id_idx ON order_refunds (order_id);
+
+CREATE TABLE IF NOT EXISTS order_refund_products (
+    id               bigserial PRIMARY KEY,
+    refund_id        bigint         NOT NULL REFERENCES order_refunds (id) ON DELETE CASCADE,
+    order_product_id bigint         NOT NULL REFERENCES order_products (id) ON DELETE CASCADE,
+    product_id       bigint         NOT NULL,
+    quantity         bigint         NOT NULL CHECK (quantity > 0),
+    amount           numeric(12, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_refund_products_refund_id_idx ON order_refund_products (refund_id);
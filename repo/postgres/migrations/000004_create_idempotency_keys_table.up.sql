@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          varchar(255) NOT NULL,
+    user_id      bigint       NOT NULL,
+    path         text         NOT NULL,
+    request_hash char(64)     NOT NULL,
+    status_code  integer,
+    response     bytea,
+    created_at   timestamptz  NOT NULL DEFAULT now(),
+    expires_at   timestamptz  NOT NULL,
+    PRIMARY KEY (user_id, path, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
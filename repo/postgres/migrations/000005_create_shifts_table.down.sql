@@ -0,0 +1,7 @@
+ALTER TABLE order_refunds
+    DROP COLUMN IF EXISTS shift_id;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shift_id;
+
+DROP TABLE IF EXISTS shifts;
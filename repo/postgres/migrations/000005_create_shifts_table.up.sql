@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS shifts (
+    id            bigserial PRIMARY KEY,
+    user_id       bigint         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    opening_float numeric(12, 2) NOT NULL CHECK (opening_float >= 0),
+    expected_cash numeric(12, 2),
+    counted_cash  numeric(12, 2) CHECK (counted_cash >= 0),
+    variance      numeric(12, 2),
+    note          text,
+    opened_at     timestamptz    NOT NULL DEFAULT now(),
+    closed_at     timestamptz,
+    closed_by     bigint REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- A cashier has at most one open shift
+CREATE UNIQUE INDEX IF NOT EXISTS shifts_user_id_open_idx ON shifts (user_id) WHERE closed_at IS NULL;
+CREATE INDEX IF NOT EXISTS shifts_opened_at_idx ON shifts (opened_at);
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS shift_id bigint REFERENCES shifts (id) ON DELETE SET NULL;
+
+ALTER TABLE order_refunds
+    ADD COLUMN IF NOT EXISTS shift_id bigint REFERENCES shifts (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS orders_shift_id_idx ON orders (shift_id);
+CREATE INDEX IF NOT EXISTS order_refunds_shift_id_idx ON order_refunds (shift_id);
@@ -0,0 +1,11 @@
+ALTER TABLE order_products
+    DROP COLUMN IF EXISTS promotion_id,
+    DROP COLUMN IF EXISTS discount,
+    DROP COLUMN IF EXISTS normal_price;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS promotion_id,
+    DROP COLUMN IF EXISTS discount,
+    DROP COLUMN IF EXISTS total_normal_price;
+
+DROP TABLE IF EXISTS promotions;
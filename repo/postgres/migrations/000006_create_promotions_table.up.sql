@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS promotions (
+    id           bigserial PRIMARY KEY,
+    name         varchar(255)   NOT NULL,
+    kind         varchar(20)    NOT NULL
+        CONSTRAINT promotions_kind_check CHECK (kind IN ('percentage', 'fixed', 'buy_x_get_y')),
+    scope        varchar(20)    NOT NULL
+        CONSTRAINT promotions_scope_check CHECK (scope IN ('product', 'category', 'order')),
+    target_id    bigint,
+    value        numeric(12, 2) NOT NULL DEFAULT 0 CHECK (value >= 0),
+    buy_qty      bigint         NOT NULL DEFAULT 0 CHECK (buy_qty >= 0),
+    get_qty      bigint         NOT NULL DEFAULT 0 CHECK (get_qty >= 0),
+    min_subtotal numeric(12, 2) NOT NULL DEFAULT 0 CHECK (min_subtotal >= 0),
+    starts_at    timestamptz,
+    ends_at      timestamptz,
+    active       boolean        NOT NULL DEFAULT true,
+    created_at   timestamptz    NOT NULL DEFAULT now(),
+    updated_at   timestamptz    NOT NULL DEFAULT now(),
+    CONSTRAINT promotions_target_check CHECK ((scope = 'order') = (target_id IS NULL)),
+    CONSTRAINT promotions_buy_x_get_y_check CHECK (kind <> 'buy_x_get_y' OR (scope <> 'order' AND buy_qty > 0 AND get_qty > 0)),
+    CONSTRAINT promotions_window_check CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS promotions_active_idx ON promotions (scope, target_id) WHERE active;
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS total_normal_price numeric(12, 2),
+    ADD COLUMN IF NOT EXISTS discount numeric(12, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS promotion_id bigint REFERENCES promotions (id) ON DELETE SET NULL;
+
+UPDATE orders SET total_normal_price = total_price WHERE total_normal_price IS NULL;
+
+ALTER TABLE orders
+    ALTER COLUMN total_normal_price SET NOT NULL;
+
+ALTER TABLE order_products
+    ADD COLUMN IF NOT EXISTS normal_price numeric(12, 2),
+    ADD COLUMN IF NOT EXISTS discount numeric(12, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS promotion_id bigint REFERENCES promotions (id) ON DELETE SET NULL;
+
+UPDATE order_products SET normal_price = total_price WHERE normal_price IS NULL;
+
+ALTER TABLE order_products
+    ALTER COLUMN normal_price SET NOT NULL;
@@ -0,0 +1,14 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS tax_rounding,
+    DROP COLUMN IF EXISTS tax_inclusive,
+    DROP COLUMN IF EXISTS tax_amount;
+
+ALTER TABLE order_products
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS tax_rate;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS tax_rate;
+
+ALTER TABLE categories
+    DROP COLUMN IF EXISTS tax_rate;
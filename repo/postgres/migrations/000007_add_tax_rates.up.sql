@@ -0,0 +1,18 @@
+-- Tax rates in percent; a product without its own rate uses the rate of its category
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS tax_rate numeric(5, 2) CHECK (tax_rate >= 0 AND tax_rate <= 100);
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS tax_rate numeric(5, 2) CHECK (tax_rate >= 0 AND tax_rate <= 100);
+
+-- The tax of every line, included in its total_price either way
+ALTER TABLE order_products
+    ADD COLUMN IF NOT EXISTS tax_rate numeric(5, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_amount numeric(12, 2) NOT NULL DEFAULT 0;
+
+-- How the order was taxed: tax_inclusive when the prices already included it
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS tax_amount numeric(12, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_inclusive boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS tax_rounding varchar(10) NOT NULL DEFAULT 'line'
+        CONSTRAINT orders_tax_rounding_check CHECK (tax_rounding IN ('line', 'invoice'));
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS held_order_products;
+DROP TABLE IF EXISTS held_orders;
@@ -0,0 +1,24 @@
+-- Carts parked at a terminal to be resumed later; their lines reserve no stock
+CREATE TABLE IF NOT EXISTS held_orders (
+    id            bigserial PRIMARY KEY,
+    user_id       bigint       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    terminal      varchar(50)  NOT NULL DEFAULT '',
+    customer_name varchar(255) NOT NULL DEFAULT '',
+    note          text,
+    created_at    timestamptz  NOT NULL DEFAULT now(),
+    updated_at    timestamptz  NOT NULL DEFAULT now(),
+    expires_at    timestamptz  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS held_orders_user_id_idx ON held_orders (user_id);
+CREATE INDEX IF NOT EXISTS held_orders_terminal_idx ON held_orders (terminal);
+CREATE INDEX IF NOT EXISTS held_orders_expires_at_idx ON held_orders (expires_at);
+
+CREATE TABLE IF NOT EXISTS held_order_products (
+    id            bigserial PRIMARY KEY,
+    held_order_id bigint NOT NULL REFERENCES held_orders (id) ON DELETE CASCADE,
+    product_id    bigint NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    quantity      bigint NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS held_order_products_held_order_id_idx ON held_order_products (held_order_id);
@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS products_name_trgm_idx;
+DROP INDEX IF EXISTS products_search_vector_idx;
+
+DROP TRIGGER IF EXISTS categories_search_vector_update ON categories;
+DROP FUNCTION IF EXISTS categories_search_vector_update();
+
+DROP TRIGGER IF EXISTS products_search_vector_update ON products;
+DROP FUNCTION IF EXISTS products_search_vector_update();
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS product_search_vector(text, bigint, text, text);
+
+DROP INDEX IF EXISTS products_barcode_key;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS barcode;
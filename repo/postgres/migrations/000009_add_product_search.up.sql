@@ -0,0 +1,64 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Barcodes scanned at the till, looked up exactly like SKUs
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS barcode varchar(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS products_barcode_key ON products (barcode) WHERE barcode IS NOT NULL;
+
+-- The words products are searched by: their name first, then their category name, SKU and barcode.
+-- The simple configuration keeps product names unstemmed.
+CREATE OR REPLACE FUNCTION product_search_vector(product_name text, product_category_id bigint, product_sku text, product_barcode text)
+    RETURNS tsvector
+    LANGUAGE sql
+    STABLE
+AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(product_name, '')), 'A')
+        || setweight(to_tsvector('simple', coalesce((SELECT c.name FROM categories c WHERE c.id = product_category_id), '')), 'B')
+        || setweight(to_tsvector('simple', coalesce(product_sku, '') || ' ' || coalesce(product_barcode, '')), 'C')
+$$;
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+UPDATE products SET search_vector = product_search_vector(name, category_id, sku::text, barcode);
+
+CREATE OR REPLACE FUNCTION products_search_vector_update()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    NEW.search_vector := product_search_vector(NEW.name, NEW.category_id, NEW.sku::text, NEW.barcode);
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS products_search_vector_update ON products;
+CREATE TRIGGER products_search_vector_update
+    BEFORE INSERT OR UPDATE OF name, category_id, sku, barcode ON products
+    FOR EACH ROW
+EXECUTE FUNCTION products_search_vector_update();
+
+-- Renaming a category changes the search vector of its products
+CREATE OR REPLACE FUNCTION categories_search_vector_update()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE products
+    SET search_vector = product_search_vector(name, category_id, sku::text, barcode)
+    WHERE category_id = NEW.id;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS categories_search_vector_update ON categories;
+CREATE TRIGGER categories_search_vector_update
+    AFTER UPDATE OF name ON categories
+    FOR EACH ROW
+    WHEN (OLD.name IS DISTINCT FROM NEW.name)
+EXECUTE FUNCTION categories_search_vector_update();
+
+CREATE INDEX IF NOT EXISTS products_search_vector_idx ON products USING gin (search_vector);
+-- Trigram index for the typo tolerant matches of product names
+CREATE INDEX IF NOT EXISTS products_name_trgm_idx ON products USING gin (name gin_trgm_ops);
@@ -0,0 +1,5 @@
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS logo_thumbnail;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS thumbnail;
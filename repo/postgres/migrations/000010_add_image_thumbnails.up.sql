@@ -0,0 +1,6 @@
+-- URLs of the thumbnails made of uploaded product images and payment logos, empty until one is uploaded
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS thumbnail varchar NOT NULL DEFAULT '';
+
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS logo_thumbnail varchar NOT NULL DEFAULT '';
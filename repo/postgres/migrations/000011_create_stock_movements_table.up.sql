@@ -0,0 +1,26 @@
+-- Ledger of every change of products.stock: quantity is the change, stock_after the stock it left.
+-- Movements of sales, refunds and cancellations reference their order, and refund for refunds.
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id          bigserial PRIMARY KEY,
+    product_id  bigint       NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    source      varchar(20)  NOT NULL
+        CONSTRAINT stock_movements_source_check
+            CHECK (source IN ('opening', 'sale', 'refund', 'cancellation', 'adjustment', 'stocktake', 'receiving')),
+    quantity    bigint       NOT NULL,
+    stock_after bigint       NOT NULL,
+    user_id     bigint       REFERENCES users (id) ON DELETE SET NULL,
+    order_id    bigint       REFERENCES orders (id) ON DELETE SET NULL,
+    refund_id   bigint       REFERENCES order_refunds (id) ON DELETE SET NULL,
+    reference   varchar(100),
+    reason      varchar(500),
+    created_at  timestamptz  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_product_id_idx ON stock_movements (product_id, id);
+CREATE INDEX IF NOT EXISTS stock_movements_order_id_idx ON stock_movements (order_id);
+
+-- The stock of existing products opens their ledger
+INSERT INTO stock_movements (product_id, source, quantity, stock_after, reason)
+SELECT id, 'opening', stock, stock, 'Stock before the ledger was started'
+FROM products
+WHERE NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.product_id = products.id);
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"gotemplate/core/domain"
@@ -10,6 +13,7 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -30,138 +34,324 @@ func NewOrderRepository(Db *DB,log *logger.Logger) *OrderRepository {
 	}
 }
 
-// CreateOrder creates a new order in the database
-func (or *OrderRepository) CreateOrder(gctx *gin.Context, order *domain.Order) (*domain.Order, error) {
+// orderColumns lists the orders columns read by scanOrder
+const orderColumns = "id, user_id, payment_id, shift_id, customer_name, total_normal_price, discount, promotion_id, tax_amount, tax_inclusive, tax_rounding, total_price, total_paid, total_return, receipt_code, status, cancelled_by, cancelled_at, cancel_reason, created_at, updated_at"
+
+// orderProductColumns lists the order_products columns read by scanOrderProduct
+const orderProductColumns = "id, order_id, product_id, quantity, refunded_quantity, normal_price, discount, promotion_id, tax_rate, tax_amount, total_price, created_at, updated_at"
+
+// scanOrder scans a row selected with orderColumns into order
+func scanOrder(row pgx.Row, order *domain.Order) error {
+	return row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.PaymentID,
+		&order.ShiftID,
+		&order.CustomerName,
+		&order.TotalNormalPrice,
+		&order.Discount,
+		&order.PromotionID,
+		&order.TaxAmount,
+		&order.TaxInclusive,
+		&order.TaxRounding,
+		&order.TotalPrice,
+		&order.TotalPaid,
+		&order.TotalReturn,
+		&order.ReceiptCode,
+		&order.Status,
+		&order.CancelledBy,
+		&order.CancelledAt,
+		&order.CancelReason,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+}
+
+// scanOrderProduct scans a row selected with orderProductColumns into orderProduct
+func scanOrderProduct(row pgx.Row, orderProduct *domain.OrderProduct) error {
+	return row.Scan(
+		&orderProduct.ID,
+		&orderProduct.OrderID,
+		&orderProduct.ProductID,
+		&orderProduct.Quantity,
+		&orderProduct.RefundedQuantity,
+		&orderProduct.NormalPrice,
+		&orderProduct.Discount,
+		&orderProduct.PromotionID,
+		&orderProduct.TaxRate,
+		&orderProduct.TaxAmount,
+		&orderProduct.TotalPrice,
+		&orderProduct.CreatedAt,
+		&orderProduct.UpdatedAt,
+	)
+}
+
+// CreateOrder prices and creates a new order in the database.
+// Line totals, the order total and the change due are computed from the current
+// product prices, promotions and tax rates inside the transaction; client supplied totals are ignored.
+// The tax mode and rounding are read from the order.
+// The ordered product rows are locked in id order so concurrent orders cannot
+// oversell the same stock, and the transaction is retried on serialization failures.
+// When idem is set, the key and the rendered response are stored in the same transaction.
+func (or *OrderRepository) CreateOrder(gctx *gin.Context, order *domain.Order, idem *domain.IdempotencyKey) (*domain.Order, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	var product domain.Product
+
+	lines := order.Products
+
+	err := or.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := claimIdempotencyKey(ctx, tx, idem); err != nil {
+			return err
+		}
+
+		order.Products = lines
+		if err := insertOrder(ctx, tx, order); err != nil {
+			return err
+		}
+
+		return storeIdempotencyResponse(ctx, tx, idem)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, err
+}
+
+// insertOrder prices order, checks its payment and stock, then inserts it with its lines in tx,
+// takes the ordered quantities out of stock and loads the order relations as CreateOrder describes
+func insertOrder(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	// Quantities per product, locked in ascending id order to avoid deadlocks
+	quantities := make(map[uint64]int64)
+	var productIDs []uint64
+	for _, orderProduct := range order.Products {
+		if _, ok := quantities[orderProduct.ProductID]; !ok {
+			productIDs = append(productIDs, orderProduct.ProductID)
+		}
+		quantities[orderProduct.ProductID] += orderProduct.Quantity
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	paymentQuery := psql.Select(paymentColumns).
+		From("payments").
+		Where(sq.Eq{"id": order.PaymentID}).
+		Limit(1)
+
+	var payment domain.Payment
 	var products []domain.OrderProduct
 
-	
+	sql, args, err := paymentQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	err = scanPayment(tx.QueryRow(ctx, sql, args...), &payment)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return port.ErrDataNotFound
+		}
+		return err
+	}
+
+	locked, err := selectPricedProducts(ctx, tx, productIDs, true)
+	if err != nil {
+		return err
+	}
+
+	// Check stock, then price every line from the current product price, promotions and tax rate
+	for _, id := range productIDs {
+		product, ok := locked[id]
+		if !ok {
+			return port.ErrDataNotFound
+		}
+		if product.Stock < quantities[id] {
+			return port.ErrInsufficientStock
+		}
+	}
+
+	for _, line := range order.Products {
+		line.Product = locked[line.ProductID]
+		products = append(products, line)
+	}
+
+	now := time.Now()
+	promotions, err := activePromotions(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+	order.Products = products
+	order.ApplyPromotions(promotions, now)
+	order.ApplyTaxes()
+
+	if order.TotalPaid < order.TotalPrice {
+		return port.ErrInsufficientPayment
+	}
+	order.TotalReturn = roundMoney(order.TotalPaid - order.TotalPrice)
+
+	// The order is taken in the open shift of its cashier, if any
+	shiftID, err := openShiftID(ctx, tx, order.UserID)
+	if err != nil {
+		return err
+	}
+
 	orderQuery := psql.Insert("orders").
-		Columns("user_id", "payment_id", "customer_name", "total_price", "total_paid", "total_return").
-		Values(order.UserID, order.PaymentID, order.CustomerName, order.TotalPrice, order.TotalPaid, order.TotalReturn).
-		Suffix("RETURNING *")
+		Columns("user_id", "payment_id", "shift_id", "customer_name", "total_normal_price", "discount", "promotion_id", "tax_amount", "tax_inclusive", "tax_rounding", "total_price", "total_paid", "total_return", "status").
+		Values(order.UserID, order.PaymentID, shiftID, order.CustomerName, order.TotalNormalPrice, order.Discount, order.PromotionID, order.TaxAmount, order.TaxInclusive, order.TaxRounding, order.TotalPrice, order.TotalPaid, order.TotalReturn, domain.OrderPaid).
+		Suffix("RETURNING " + orderColumns)
 
-	err := pgx.BeginFunc(ctx, or.Db, func(tx pgx.Tx) error {
-		sql, args, err := orderQuery.ToSql()
+	sql, args, err = orderQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	err = scanOrder(tx.QueryRow(ctx, sql, args...), order)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		orderProduct := &products[i]
+		orderProductQuery := psql.Insert("order_products").
+			Columns("order_id", "product_id", "quantity", "normal_price", "discount", "promotion_id", "tax_rate", "tax_amount", "total_price").
+			Values(order.ID, orderProduct.ProductID, orderProduct.Quantity, orderProduct.NormalPrice, orderProduct.Discount, orderProduct.PromotionID, orderProduct.TaxRate, orderProduct.TaxAmount, orderProduct.TotalPrice).
+			Suffix("RETURNING " + orderProductColumns)
+
+		sql, args, err := orderProductQuery.ToSql()
 		if err != nil {
 			return err
 		}
 
-		err = tx.QueryRow(ctx, sql, args...).Scan(
-			&order.ID,
-			&order.UserID,
-			&order.PaymentID,
-			&order.CustomerName,
-			&order.TotalPrice,
-			&order.TotalPaid,
-			&order.TotalReturn,
-			&order.ReceiptCode,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		)
+		err = scanOrderProduct(tx.QueryRow(ctx, sql, args...), orderProduct)
 		if err != nil {
 			return err
 		}
+	}
 
-		for _, orderProduct := range order.Products {
-			orderProductQuery := psql.Insert("order_products").
-				Columns("order_id", "product_id", "quantity", "total_price").
-				Values(order.ID, orderProduct.ProductID, orderProduct.Quantity, orderProduct.TotalPrice).
-				Suffix("RETURNING *")
-
-			sql, args, err := orderProductQuery.ToSql()
-			if err != nil {
-				return err
-			}
+	for _, id := range productIDs {
+		// The rows are locked, the stock check only guards against a broken invariant
+		movement := domain.StockMovement{
+			ProductID: id,
+			Source:    domain.StockSale,
+			Quantity:  -quantities[id],
+			UserID:    &order.UserID,
+			OrderID:   &order.ID,
+		}
+		if err := moveStock(ctx, tx, &movement); err != nil {
+			return err
+		}
+		locked[id].Stock = movement.StockAfter
+	}
 
-			err = tx.QueryRow(ctx, sql, args...).Scan(
-				&orderProduct.ID,
-				&orderProduct.OrderID,
-				&orderProduct.ProductID,
-				&orderProduct.Quantity,
-				&orderProduct.TotalPrice,
-				&orderProduct.CreatedAt,
-				&orderProduct.UpdatedAt,
-			)
-			if err != nil {
-				return err
-			}
+	return hydrateOrder(ctx, tx, order)
+}
 
-			products = append(products, orderProduct)
+// PriceOrder prices and taxes the lines of an unsaved order from the current product prices and active promotions,
+// without checking stock or taking payment. The tax mode and rounding are read from the order.
+func (or *OrderRepository) PriceOrder(gctx *gin.Context, order *domain.Order) (*domain.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-			productQuery := psql.Update("products").
-				Set("stock", sq.Expr("stock - ?", orderProduct.Quantity)).
-				Set("updated_at", time.Now()).
-				Where(sq.Eq{"id": orderProduct.ProductID}).
-				Suffix("RETURNING stock")
+	var productIDs []uint64
+	for _, line := range order.Products {
+		productIDs = append(productIDs, line.ProductID)
+	}
 
-			sql, args, err = productQuery.ToSql()
-			if err != nil {
-				return err
-			}
+	err := or.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		products, err := selectPricedProducts(ctx, tx, productIDs, false)
+		if err != nil {
+			return err
+		}
 
-			err = tx.QueryRow(ctx, sql, args...).Scan(
-				&product.Stock,
-			)
-			if err != nil {
-				return err
+		for i := range order.Products {
+			product, ok := products[order.Products[i].ProductID]
+			if !ok {
+				return port.ErrDataNotFound
 			}
+			order.Products[i].Product = product
+		}
 
-			if product.Stock < 0 {
-				return tx.Rollback(ctx)
-			}
+		now := time.Now()
+		promotions, err := activePromotions(ctx, tx, now)
+		if err != nil {
+			return err
 		}
+		order.ApplyPromotions(promotions, now)
+		order.ApplyTaxes()
 
-		order.Products = products
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// selectPricedProducts selects products by id with the tax rate of their category, as needed to price them,
+// locking them in id order when forUpdate is set
+func selectPricedProducts(ctx context.Context, tx pgx.Tx, ids []uint64, forUpdate bool) (map[uint64]*domain.Product, error) {
+	query := psql.Select(productColumns, "(SELECT c.tax_rate FROM categories c WHERE c.id = products.category_id)").
+		From("products").
+		Where("id = ANY(?)", ids).
+		OrderBy("id")
+	if forUpdate {
+		query = query.Suffix("FOR UPDATE")
+	}
 
+	products := make(map[uint64]*domain.Product, len(ids))
+	err := scanRows(ctx, tx, query, func(rows pgx.Rows) error {
+		var product domain.Product
+		var categoryTaxRate *float64
+		if err := scanProduct(rows, &product, &categoryTaxRate); err != nil {
+			return err
+		}
+
+		product.Category = &domain.Category{ID: product.CategoryID, TaxRate: categoryTaxRate}
+		products[product.ID] = &product
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return order, err
+	return products, nil
 }
 
-// GetOrderByID gets an order by ID from the database
+// roundMoney rounds an amount to two decimal places
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// GetOrderByID gets an order by ID with its lines, products, payment and cashier from the database
 func (or *OrderRepository) GetOrderByID(gctx *gin.Context, id uint64) (*domain.Order, error) {
-	
+	return or.getOrder(sq.Eq{"id": id})
+}
+
+// GetOrderByReceiptCode gets an order by receipt code with its lines, products, payment and cashier from the database
+func (or *OrderRepository) GetOrderByReceiptCode(gctx *gin.Context, code uuid.UUID) (*domain.Order, error) {
+	return or.getOrder(sq.Eq{"receipt_code": code})
+}
+
+// getOrder selects the order matching where and loads everything it references
+func (or *OrderRepository) getOrder(where sq.Eq) (*domain.Order, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	var order domain.Order
-	var orderProduct domain.OrderProduct
+	orders := make([]domain.Order, 1)
 
-	orderQuery := psql.Select("*").
+	orderQuery := psql.Select(orderColumns).
 		From("orders").
-		Where(sq.Eq{"id": id}).
+		Where(where).
 		Limit(1)
 
-	orderProductQuery := psql.Select("*").
-		From("order_products").
-		Where(sq.Eq{"order_id": id})
-
-	err := pgx.BeginFunc(ctx, or.Db, func(tx pgx.Tx) error {
+	err := or.Db.ReadTx(ctx, func(tx pgx.Tx) error {
 
 		sql, args, err := orderQuery.ToSql()
 		if err != nil {
 			return err
 		}
 
-		err = tx.QueryRow(ctx, sql, args...).Scan(
-			&order.ID,
-			&order.UserID,
-			&order.PaymentID,
-			&order.CustomerName,
-			&order.TotalPrice,
-			&order.TotalPaid,
-			&order.TotalReturn,
-			&order.ReceiptCode,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		)
+		err = scanOrder(tx.QueryRow(ctx, sql, args...), &orders[0])
 		if err != nil {
 			if err == pgx.ErrNoRows {
 				return port.ErrDataNotFound
@@ -169,34 +359,212 @@ func (or *OrderRepository) GetOrderByID(gctx *gin.Context, id uint64) (*domain.O
 			return err
 		}
 
-		sql, args, err = orderProductQuery.ToSql()
+		return hydrateOrders(ctx, tx, orders)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &orders[0], nil
+}
+
+// selectOrderProducts selects the lines of an order, locking them when forUpdate is set
+func selectOrderProducts(ctx context.Context, tx pgx.Tx, orderID uint64, forUpdate bool) ([]domain.OrderProduct, error) {
+	var orderProducts []domain.OrderProduct
+
+	query := psql.Select(orderProductColumns).
+		From("order_products").
+		Where(sq.Eq{"order_id": orderID}).
+		OrderBy("id")
+	if forUpdate {
+		query = query.Suffix("FOR UPDATE")
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderProduct domain.OrderProduct
+		if err := scanOrderProduct(rows, &orderProduct); err != nil {
+			return nil, err
+		}
+		orderProducts = append(orderProducts, orderProduct)
+	}
+
+	return orderProducts, rows.Err()
+}
+
+// orderKeysets maps the sort of an OrderFilter to the keyset orders are paginated in, id breaking ties
+var orderKeysets = map[domain.OrderSort]keyset{
+	domain.SortCreatedAsc:  {columns: []keyColumn{{name: "created_at", sqlType: "timestamptz"}}, id: "id"},
+	domain.SortCreatedDesc: {columns: []keyColumn{{name: "created_at", sqlType: "timestamptz", desc: true}}, id: "id", desc: true},
+	domain.SortTotalAsc:    {columns: []keyColumn{{name: "total_price", sqlType: "numeric"}}, id: "id"},
+	domain.SortTotalDesc:   {columns: []keyColumn{{name: "total_price", sqlType: "numeric", desc: true}}, id: "id", desc: true},
+}
+
+// likeEscaper escapes the LIKE wildcards of user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// filterOrders adds the conditions of filter to query
+func filterOrders(query sq.SelectBuilder, filter domain.OrderFilter) sq.SelectBuilder {
+	if filter.From != nil {
+		query = query.Where(sq.GtOrEq{"created_at": *filter.From})
+	}
+	if filter.To != nil {
+		query = query.Where(sq.Lt{"created_at": *filter.To})
+	}
+	if filter.UserID != 0 {
+		query = query.Where(sq.Eq{"user_id": filter.UserID})
+	}
+	if filter.PaymentID != 0 {
+		query = query.Where(sq.Eq{"payment_id": filter.PaymentID})
+	}
+	if filter.CustomerName != "" {
+		query = query.Where(sq.ILike{"customer_name": "%" + likeEscaper.Replace(filter.CustomerName) + "%"})
+	}
+	if filter.MinTotal != nil {
+		query = query.Where(sq.GtOrEq{"total_price": *filter.MinTotal})
+	}
+	if filter.MaxTotal != nil {
+		query = query.Where(sq.LtOrEq{"total_price": *filter.MaxTotal})
+	}
+	if filter.ReceiptCodePrefix != "" {
+		query = query.Where(sq.Like{"receipt_code::text": likeEscaper.Replace(strings.ToLower(filter.ReceiptCodePrefix)) + "%"})
+	}
+
+	return query
+}
+
+// ListOrders lists a page of the orders matching filter, in the order of its sort,
+// with their lines, products, payments and cashiers from the database
+func (or *OrderRepository) ListOrders(gctx *gin.Context, filter domain.OrderFilter, params PageParams) ([]domain.Order, Page, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ks, ok := orderKeysets[filter.Sort]
+	if !ok {
+		ks = idKeyset("id")
+	}
+	key := func(order *domain.Order) []any {
+		switch filter.Sort {
+		case domain.SortCreatedAsc, domain.SortCreatedDesc:
+			return []any{order.CreatedAt, order.ID}
+		case domain.SortTotalAsc, domain.SortTotalDesc:
+			return []any{order.TotalPrice, order.ID}
+		}
+		return []any{order.ID}
+	}
+
+	query := filterOrders(psql.Select(orderColumns).From("orders"), filter)
+
+	var orders []domain.Order
+	var page Page
+	err := or.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		orders, page, err = selectPage(ctx, tx, query, ks, params,
+			func(row pgx.CollectableRow) (domain.Order, error) {
+				var order domain.Order
+				err := scanOrder(row, &order)
+				return order, err
+			}, key)
 		if err != nil {
 			return err
 		}
 
-		rows, err := tx.Query(ctx, sql, args...)
+		return hydrateOrders(ctx, tx, orders)
+	})
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	return orders, page, nil
+}
+
+// lockOrder selects an order for update and checks that it may move to the next status
+func lockOrder(ctx context.Context, tx pgx.Tx, id uint64, next domain.OrderStatus, order *domain.Order) error {
+	query := psql.Select(orderColumns).
+		From("orders").
+		Where(sq.Eq{"id": id}).
+		Suffix("FOR UPDATE")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	err = scanOrder(tx.QueryRow(ctx, sql, args...), order)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return port.ErrDataNotFound
+		}
+		return err
+	}
+
+	if !order.Status.CanTransitionTo(next) {
+		return port.ErrInvalidOrderStatus
+	}
+	return nil
+}
+
+// CancelOrder cancels an order and puts every item not refunded yet back in stock
+func (or *OrderRepository) CancelOrder(gctx *gin.Context, id, userID uint64, reason string) (*domain.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var order domain.Order
+
+	err := or.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		err := lockOrder(ctx, tx, id, domain.OrderCancelled, &order)
 		if err != nil {
 			return err
 		}
 
-		for rows.Next() {
-			err = rows.Scan(
-				&orderProduct.ID,
-				&orderProduct.OrderID,
-				&orderProduct.ProductID,
-				&orderProduct.Quantity,
-				&orderProduct.TotalPrice,
-				&orderProduct.CreatedAt,
-				&orderProduct.UpdatedAt,
-			)
-			if err != nil {
-				return err
-			}
+		orderProducts, err := selectOrderProducts(ctx, tx, order.ID, true)
+		if err != nil {
+			return err
+		}
 
-			order.Products = append(order.Products, orderProduct)
+		quantities := make(map[uint64]int64)
+		for _, orderProduct := range orderProducts {
+			quantities[orderProduct.ProductID] += orderProduct.Quantity - orderProduct.RefundedQuantity
 		}
 
-		return nil
+		err = moveStocks(ctx, tx, quantities, domain.StockMovement{
+			Source:  domain.StockCancellation,
+			UserID:  &userID,
+			OrderID: &order.ID,
+			Reason:  &reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		cancelQuery := psql.Update("orders").
+			Set("status", domain.OrderCancelled).
+			Set("cancelled_by", userID).
+			Set("cancelled_at", time.Now()).
+			Set("cancel_reason", reason).
+			Set("updated_at", time.Now()).
+			Where(sq.Eq{"id": order.ID}).
+			Suffix("RETURNING " + orderColumns)
+
+		sql, args, err := cancelQuery.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if err := scanOrder(tx.QueryRow(ctx, sql, args...), &order); err != nil {
+			return err
+		}
+
+		return hydrateOrder(ctx, tx, &order)
 	})
 	if err != nil {
 		return nil, err
@@ -205,90 +573,343 @@ func (or *OrderRepository) GetOrderByID(gctx *gin.Context, id uint64) (*domain.O
 	return &order, nil
 }
 
-// ListOrders lists all orders from the database
-func (or *OrderRepository) ListOrders(gctx *gin.Context, skip, limit uint64) ([]domain.Order, error) {
-	
+// RefundOrder records a refund of order lines and puts the returned quantities back in stock.
+// A refund without products returns everything not refunded yet.
+func (or *OrderRepository) RefundOrder(gctx *gin.Context, refund *domain.Refund) (*domain.Refund, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	var order domain.Order
-	var orderProduct domain.OrderProduct
-	var orders []domain.Order
 
-	ordersQuery := psql.Select("*").
-		From("orders").
-		OrderBy("id").
-		Limit(limit).
-		Offset((skip - 1) * limit)
+	requested := refund.Products
 
-	err := pgx.BeginFunc(ctx, or.Db, func(tx pgx.Tx) error {
-		sql, args, err := ordersQuery.ToSql()
+	err := or.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		var order domain.Order
+
+		// Lock the order first; the final status is only known once the lines are checked
+		err := lockOrder(ctx, tx, refund.OrderID, domain.OrderPartiallyRefunded, &order)
 		if err != nil {
 			return err
 		}
 
-		rows, err := tx.Query(ctx, sql, args...)
+		orderProducts, err := selectOrderProducts(ctx, tx, order.ID, true)
 		if err != nil {
 			return err
 		}
 
-		for rows.Next() {
-			err := rows.Scan(
-				&order.ID,
-				&order.UserID,
-				&order.PaymentID,
-				&order.CustomerName,
-				&order.TotalPrice,
-				&order.TotalPaid,
-				&order.TotalReturn,
-				&order.ReceiptCode,
-				&order.CreatedAt,
-				&order.UpdatedAt,
-			)
-			if err != nil {
-				return err
+		lines := make(map[uint64]*domain.OrderProduct, len(orderProducts))
+		for i := range orderProducts {
+			lines[orderProducts[i].ID] = &orderProducts[i]
+		}
+
+		items := requested
+		if len(items) == 0 {
+			items = nil
+			for _, orderProduct := range orderProducts {
+				if remaining := orderProduct.Quantity - orderProduct.RefundedQuantity; remaining > 0 {
+					items = append(items, domain.RefundProduct{OrderProductID: orderProduct.ID, Quantity: remaining})
+				}
+			}
+			if len(items) == 0 {
+				return port.ErrInvalidOrderStatus
 			}
+		}
+
+		quantities := make(map[uint64]int64)
+		var amount float64
+		for i := range items {
+			item := &items[i]
+			line, ok := lines[item.OrderProductID]
+			if !ok {
+				return port.ErrDataNotFound
+			}
+			if item.Quantity > line.Quantity-line.RefundedQuantity {
+				return port.ErrRefundExceedsQuantity
+			}
+
+			line.RefundedQuantity += item.Quantity
+			item.ProductID = line.ProductID
+			item.Amount = roundMoney(line.TotalPrice * float64(item.Quantity) / float64(line.Quantity))
+			amount += item.Amount
+			quantities[line.ProductID] += item.Quantity
+		}
+
+		status := domain.OrderRefunded
+		for _, orderProduct := range orderProducts {
+			if orderProduct.RefundedQuantity < orderProduct.Quantity {
+				status = domain.OrderPartiallyRefunded
+				break
+			}
+		}
 
-			orders = append(orders, order)
+		// The refund is paid out of the open shift of the refunding user, if any
+		shiftID, err := openShiftID(ctx, tx, refund.UserID)
+		if err != nil {
+			return err
+		}
+
+		refundQuery := psql.Insert("order_refunds").
+			Columns("order_id", "user_id", "shift_id", "reason", "amount").
+			Values(order.ID, refund.UserID, shiftID, refund.Reason, roundMoney(amount)).
+			Suffix("RETURNING id, order_id, user_id, shift_id, reason, amount, created_at")
+
+		sql, args, err := refundQuery.ToSql()
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, sql, args...).Scan(
+			&refund.ID,
+			&refund.OrderID,
+			&refund.UserID,
+			&refund.ShiftID,
+			&refund.Reason,
+			&refund.Amount,
+			&refund.CreatedAt,
+		)
+		if err != nil {
+			return err
 		}
 
-		for i, order := range orders {
-			orderProductQuery := psql.Select("*").
-				From("order_products").
-				Where(sq.Eq{"order_id": order.ID})
+		for i := range items {
+			item := &items[i]
+			item.RefundID = refund.ID
 
-			sql, args, err := orderProductQuery.ToSql()
+			itemQuery := psql.Insert("order_refund_products").
+				Columns("refund_id", "order_product_id", "product_id", "quantity", "amount").
+				Values(item.RefundID, item.OrderProductID, item.ProductID, item.Quantity, item.Amount).
+				Suffix("RETURNING id")
+
+			sql, args, err := itemQuery.ToSql()
 			if err != nil {
 				return err
 			}
 
-			rows, err := tx.Query(ctx, sql, args...)
-			if err != nil {
+			if err := tx.QueryRow(ctx, sql, args...).Scan(&item.ID); err != nil {
 				return err
 			}
 
-			for rows.Next() {
-				err := rows.Scan(
-					&orderProduct.ID,
-					&orderProduct.OrderID,
-					&orderProduct.ProductID,
-					&orderProduct.Quantity,
-					&orderProduct.TotalPrice,
-					&orderProduct.CreatedAt,
-					&orderProduct.UpdatedAt,
-				)
-				if err != nil {
-					return err
-				}
+			lineQuery := psql.Update("order_products").
+				Set("refunded_quantity", sq.Expr("refunded_quantity + ?", item.Quantity)).
+				Set("updated_at", time.Now()).
+				Where(sq.Eq{"id": item.OrderProductID})
 
-				orders[i].Products = append(orders[i].Products, orderProduct)
+			if err := TxExec(ctx, tx, lineQuery, or.log); err != nil {
+				return err
 			}
 		}
 
-		return nil
+		err = moveStocks(ctx, tx, quantities, domain.StockMovement{
+			Source:   domain.StockRefund,
+			UserID:   &refund.UserID,
+			OrderID:  &order.ID,
+			RefundID: &refund.ID,
+			Reason:   &refund.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		statusQuery := psql.Update("orders").
+			Set("status", status).
+			Set("updated_at", time.Now()).
+			Where(sq.Eq{"id": order.ID}).
+			Suffix("RETURNING " + orderColumns)
+
+		sql, args, err = statusQuery.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if err := scanOrder(tx.QueryRow(ctx, sql, args...), &order); err != nil {
+			return err
+		}
+
+		refund.Order = &order
+		refund.Products = items
+		return hydrateOrder(ctx, tx, &order)
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return orders, nil
+	return refund, nil
+}
+
+// hydrateOrder loads everything an order references, see hydrateOrders
+func hydrateOrder(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	orders := []domain.Order{*order}
+	if err := hydrateOrders(ctx, tx, orders); err != nil {
+		return err
+	}
+
+	*order = orders[0]
+	return nil
+}
+
+// hydrateOrders loads the lines, the products with their categories, the payments,
+// the cashiers and the applied promotions of orders in five queries, whatever the number of orders
+func hydrateOrders(ctx context.Context, tx pgx.Tx, orders []domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	orderIDs := make([]uint64, 0, len(orders))
+	paymentIDs := make([]uint64, 0, len(orders))
+	userIDs := make([]uint64, 0, len(orders))
+	byID := make(map[uint64]*domain.Order, len(orders))
+	for i := range orders {
+		orders[i].Products = nil
+		orderIDs = append(orderIDs, orders[i].ID)
+		paymentIDs = append(paymentIDs, orders[i].PaymentID)
+		userIDs = append(userIDs, orders[i].UserID)
+		byID[orders[i].ID] = &orders[i]
+	}
+
+	var promotionIDs []uint64
+	for _, order := range orders {
+		if order.PromotionID != nil {
+			promotionIDs = append(promotionIDs, *order.PromotionID)
+		}
+	}
+
+	// Lines
+	linesQuery := psql.Select(orderProductColumns).
+		From("order_products").
+		Where("order_id = ANY(?)", orderIDs).
+		OrderBy("order_id", "id")
+
+	var productIDs []uint64
+	err := scanRows(ctx, tx, linesQuery, func(rows pgx.Rows) error {
+		var orderProduct domain.OrderProduct
+		if err := scanOrderProduct(rows, &orderProduct); err != nil {
+			return err
+		}
+
+		order := byID[orderProduct.OrderID]
+		order.Products = append(order.Products, orderProduct)
+		productIDs = append(productIDs, orderProduct.ProductID)
+		if orderProduct.PromotionID != nil {
+			promotionIDs = append(promotionIDs, *orderProduct.PromotionID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Products with their categories
+	productsQuery := psql.Select(
+		"p.id", "p.category_id", "p.sku", "p.barcode", "p.name", "p.stock", "p.price", "p.image", "p.thumbnail", "p.tax_rate", "p.created_at", "p.updated_at",
+		"c.id", "c.name", "c.tax_rate", "c.created_at", "c.updated_at",
+	).
+		From("products p").
+		LeftJoin("categories c ON c.id = p.category_id").
+		Where("p.id = ANY(?)", productIDs)
+
+	products := make(map[uint64]*domain.Product)
+	err = scanRows(ctx, tx, productsQuery, func(rows pgx.Rows) error {
+		var product domain.Product
+		var categoryID *uint64
+		var categoryName *string
+		var categoryTaxRate *float64
+		var categoryCreatedAt, categoryUpdatedAt *time.Time
+
+		err := scanProduct(rows, &product, &categoryID, &categoryName, &categoryTaxRate, &categoryCreatedAt, &categoryUpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if categoryID != nil {
+			product.Category = &domain.Category{
+				ID:        *categoryID,
+				Name:      *categoryName,
+				TaxRate:   categoryTaxRate,
+				CreatedAt: *categoryCreatedAt,
+				UpdatedAt: *categoryUpdatedAt,
+			}
+		}
+		products[product.ID] = &product
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Payments
+	paymentsQuery := psql.Select(paymentColumns).
+		From("payments").
+		Where("id = ANY(?)", paymentIDs)
+
+	payments := make(map[uint64]*domain.Payment)
+	err = scanRows(ctx, tx, paymentsQuery, func(rows pgx.Rows) error {
+		var payment domain.Payment
+		err := scanPayment(rows, &payment)
+		if err != nil {
+			return err
+		}
+
+		payments[payment.ID] = &payment
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Cashiers
+	usersQuery := psql.Select("id", "name", "email", "role").
+		From("users").
+		Where("id = ANY(?)", userIDs)
+
+	users := make(map[uint64]*domain.User)
+	err = scanRows(ctx, tx, usersQuery, func(rows pgx.Rows) error {
+		var user domain.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role); err != nil {
+			return err
+		}
+
+		users[user.ID] = &user
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Promotions
+	promotions := make(map[uint64]*domain.Promotion)
+	if len(promotionIDs) > 0 {
+		promotionsQuery := psql.Select(promotionColumns).
+			From("promotions").
+			Where("id = ANY(?)", promotionIDs)
+
+		err = scanRows(ctx, tx, promotionsQuery, func(rows pgx.Rows) error {
+			var promotion domain.Promotion
+			if err := scanPromotion(rows, &promotion); err != nil {
+				return err
+			}
+
+			promotions[promotion.ID] = &promotion
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range orders {
+		order := &orders[i]
+		order.Payment = payments[order.PaymentID]
+		order.User = users[order.UserID]
+		order.Promotion = nil
+		if order.PromotionID != nil {
+			order.Promotion = promotions[*order.PromotionID]
+		}
+		for j := range order.Products {
+			line := &order.Products[j]
+			line.Product = products[line.ProductID]
+			if line.PromotionID != nil {
+				line.Promotion = promotions[*line.PromotionID]
+			}
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gotemplate/core/port"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// TotalMode is how the total number of rows of a paginated list is counted
+type TotalMode string
+
+// TotalMode enum values
+const (
+	// TotalNone leaves the total out
+	TotalNone TotalMode = ""
+	// TotalExact counts the matching rows
+	TotalExact TotalMode = "exact"
+	// TotalEstimate reads the number of matching rows estimated by the query planner, cheap on large tables
+	TotalEstimate TotalMode = "estimate"
+)
+
+// PageParams selects a page of a list: the rows after, or before, the position of Cursor,
+// the first page when it is empty
+type PageParams struct {
+	Cursor string
+	Limit  uint64
+	Total  TotalMode
+}
+
+// Page describes a page of a list. Next and Prev are the opaque cursors of the next and previous pages,
+// empty when there is none. Total is the number of rows matching the list, set when PageParams asked for it.
+type Page struct {
+	Next      string
+	Prev      string
+	Total     *uint64
+	Estimated bool
+}
+
+// keyColumn is a column of a keyset, sqlType being the SQL type cursor values of the column are cast to
+type keyColumn struct {
+	name    string
+	sqlType string
+	desc    bool
+}
+
+// keyset is the order a list is paginated in: by its columns, then by the unique id column,
+// ascending or descending. The columns must not be nullable.
+type keyset struct {
+	columns []keyColumn
+	id      string
+	desc    bool
+}
+
+// idKeyset orders a list by its id column
+func idKeyset(id string) keyset {
+	return keyset{id: id}
+}
+
+// all returns the columns of the keyset, id last
+func (ks keyset) all() []keyColumn {
+	columns := append([]keyColumn(nil), ks.columns...)
+	return append(columns, keyColumn{name: ks.id, sqlType: "bigint", desc: ks.desc})
+}
+
+// name identifies the keyset in its cursors, so a cursor cannot be used with another order
+func (ks keyset) name() string {
+	var names []string
+	for _, column := range ks.all() {
+		name := column.name
+		if column.desc {
+			name = "-" + name
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+// orderBy is the ORDER BY clause of the keyset, reversed when reading backward
+func (ks keyset) orderBy(backward bool) []string {
+	var clauses []string
+	for _, column := range ks.all() {
+		direction := "ASC"
+		if column.desc != backward {
+			direction = "DESC"
+		}
+		clauses = append(clauses, column.name+" "+direction)
+	}
+	return clauses
+}
+
+// after is the condition selecting the rows past key, in the order of the keyset or before it when reading backward
+func (ks keyset) after(key []string, backward bool) sq.Sqlizer {
+	columns := ks.all()
+	op := func(column keyColumn) string {
+		if column.desc != backward {
+			return "<"
+		}
+		return ">"
+	}
+	cast := func(column keyColumn) string {
+		return "CAST(? AS " + column.sqlType + ")"
+	}
+
+	args := make([]any, len(key))
+	for i, value := range key {
+		args[i] = value
+	}
+
+	// A row comparison can use an index when all the columns go the same way
+	uniform := true
+	for _, column := range columns {
+		uniform = uniform && column.desc == ks.desc
+	}
+	if uniform {
+		names := make([]string, len(columns))
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			names[i] = column.name
+			values[i] = cast(column)
+		}
+		return sq.Expr(fmt.Sprintf("(%s) %s (%s)", strings.Join(names, ", "), op(columns[0]), strings.Join(values, ", ")), args...)
+	}
+
+	// Otherwise a row is past key when it is equal on the leading columns and past it on the next one
+	past := sq.Or{}
+	for i, column := range columns {
+		and := sq.And{}
+		for j := 0; j < i; j++ {
+			and = append(and, sq.Expr(columns[j].name+" = "+cast(columns[j]), args[j]))
+		}
+		past = append(past, append(and, sq.Expr(column.name+" "+op(column)+" "+cast(column), args[i])))
+	}
+	return past
+}
+
+// cursor is the position a page starts after, or ends before when Prev is set
+type cursor struct {
+	Keyset string   `json:"o"`
+	Key    []string `json:"k"`
+	Prev   bool     `json:"p,omitempty"`
+}
+
+// encodeCursor makes the opaque cursor of the position of key, the values of the keyset columns of a row
+func (ks keyset) encodeCursor(key []any, prev bool) string {
+	c := cursor{Keyset: ks.name(), Prev: prev}
+	for _, value := range key {
+		switch v := value.(type) {
+		case time.Time:
+			c.Key = append(c.Key, v.UTC().Format(time.RFC3339Nano))
+		default:
+			c.Key = append(c.Key, fmt.Sprint(v))
+		}
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor made by encodeCursor for the same keyset, nil when s is empty
+func (ks keyset) decodeCursor(s string) (*cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, port.ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, port.ErrInvalidCursor
+	}
+
+	if c.Keyset != ks.name() || len(c.Key) != len(ks.all()) {
+		return nil, port.ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// selectPage selects the page of query described by params in the order of ks, query having no ORDER BY or LIMIT.
+// scan reads a row and key returns the values of the keyset columns of a scanned row, id last.
+func selectPage[T any](ctx context.Context, tx pgx.Tx, query sq.SelectBuilder, ks keyset, params PageParams,
+	scan pgx.RowToFunc[T], key func(*T) []any) ([]T, Page, error) {
+	var page Page
+
+	c, err := ks.decodeCursor(params.Cursor)
+	if err != nil {
+		return nil, page, err
+	}
+
+	if params.Total != TotalNone {
+		total, err := countRows(ctx, tx, query, params.Total)
+		if err != nil {
+			return nil, page, err
+		}
+		page.Total = &total
+		page.Estimated = params.Total == TotalEstimate
+	}
+
+	backward := c != nil && c.Prev
+	pageQuery := query
+	if c != nil {
+		pageQuery = pageQuery.Where(ks.after(c.Key, backward))
+	}
+	// One more row than the limit tells whether there is a page after this one
+	pageQuery = pageQuery.
+		OrderBy(ks.orderBy(backward)...).
+		Limit(params.Limit + 1)
+
+	var items []T
+	err = scanRows(ctx, tx, pageQuery, func(rows pgx.Rows) error {
+		item, err := scan(rows)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, page, err
+	}
+
+	more := uint64(len(items)) > params.Limit
+	if more {
+		items = items[:params.Limit]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if len(items) > 0 {
+		// A cursor always points at a row, so there are rows on the side the page was reached from
+		if (backward && more) || (!backward && c != nil) {
+			page.Prev = ks.encodeCursor(key(&items[0]), true)
+		}
+		if (!backward && more) || backward {
+			page.Next = ks.encodeCursor(key(&items[len(items)-1]), false)
+		}
+	}
+
+	return items, page, nil
+}
+
+// countRows counts the rows of query, or reads the planner estimate of their number with TotalEstimate
+func countRows(ctx context.Context, tx pgx.Tx, query sq.SelectBuilder, mode TotalMode) (uint64, error) {
+	if mode == TotalEstimate {
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return 0, err
+		}
+
+		var plan []byte
+		if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+sql, args...).Scan(&plan); err != nil {
+			return 0, err
+		}
+
+		var explained []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal(plan, &explained); err != nil {
+			return 0, err
+		}
+		if len(explained) == 0 {
+			return 0, errors.New("query plan has no rows estimate")
+		}
+		return uint64(explained[0].Plan.Rows), nil
+	}
+
+	sql, args, err := psql.Select("count(*)").FromSelect(query, "page_rows").ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var total uint64
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -30,29 +30,50 @@ func NewPaymentRepository(Db *DB, log *logger.Logger) *PaymentRepository {
 	}
 }
 
-// CreatePayment creates a new payment record in the database
-func (pr *PaymentRepository) CreatePayment(gctx *gin.Context, payment *domain.Payment) (*domain.Payment, error) {
+// paymentColumns lists the payments columns read by scanPayment
+const paymentColumns = "id, name, type, logo, logo_thumbnail, created_at, updated_at"
+
+// scanPayment scans a row selected with paymentColumns into payment
+func scanPayment(row pgx.Row, payment *domain.Payment) error {
+	return row.Scan(
+		&payment.ID,
+		&payment.Name,
+		&payment.Type,
+		&payment.Logo,
+		&payment.LogoThumbnail,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+}
+
+// CreatePayment creates a new payment record in the database.
+// When idem is set, the key and the rendered response are stored in the same transaction.
+func (pr *PaymentRepository) CreatePayment(gctx *gin.Context, payment *domain.Payment, idem *domain.IdempotencyKey) (*domain.Payment, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	
 	query := psql.Insert("payments").
 		Columns("name", "type", "logo").
 		Values(payment.Name, payment.Type, payment.Logo).
-		Suffix("RETURNING *")
+		Suffix("RETURNING " + paymentColumns)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	err = pr.Db.QueryRow(ctx, sql, args...).Scan(
-		&payment.ID,
-		&payment.Name,
-		&payment.Type,
-		&payment.Logo,
-		&payment.CreatedAt,
-		&payment.UpdatedAt,
-	)
+	err = pr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := claimIdempotencyKey(ctx, tx, idem); err != nil {
+			return err
+		}
+
+		err := scanPayment(tx.QueryRow(ctx, sql, args...), payment)
+		if err != nil {
+			return err
+		}
+
+		return storeIdempotencyResponse(ctx, tx, idem)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +88,7 @@ func (pr *PaymentRepository) GetPaymentByID(gctx *gin.Context, id uint64) (*doma
 	defer cancel()
 	var payment domain.Payment
 
-	query := psql.Select("*").
+	query := psql.Select(paymentColumns).
 		From("payments").
 		Where(sq.Eq{"id": id}).
 		Limit(1)
@@ -77,14 +98,7 @@ func (pr *PaymentRepository) GetPaymentByID(gctx *gin.Context, id uint64) (*doma
 		return nil, err
 	}
 
-	err = pr.Db.QueryRow(ctx, sql, args...).Scan(
-		&payment.ID,
-		&payment.Name,
-		&payment.Type,
-		&payment.Logo,
-		&payment.CreatedAt,
-		&payment.UpdatedAt,
-	)
+	err = scanPayment(pr.Db.QueryRow(ctx, sql, args...), &payment)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, port.ErrDataNotFound
@@ -95,47 +109,31 @@ func (pr *PaymentRepository) GetPaymentByID(gctx *gin.Context, id uint64) (*doma
 	return &payment, nil
 }
 
-// ListPayments retrieves a list of payments from the database
-func (pr *PaymentRepository) ListPayments(gctx *gin.Context, skip, limit uint64) ([]domain.Payment, error) {
+// ListPayments retrieves a page of the payments matching lq from the database in its sort order
+func (pr *PaymentRepository) ListPayments(gctx *gin.Context, lq ListQuery, params PageParams) ([]domain.Payment, Page, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var payment domain.Payment
-	var payments []domain.Payment
-
-	query := psql.Select("*").
-		From("payments").
-		OrderBy("id").
-		Limit(limit).
-		Offset((skip - 1) * limit)
-
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return nil, err
-	}
+	query := psql.Select(paymentColumns).
+		From("payments")
 
-	rows, err := pr.Db.Query(ctx, sql, args...)
+	var payments []domain.Payment
+	var page Page
+	err := pr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		payments, page, err = selectList(ctx, tx, query, PaymentSchema, lq, params,
+			func(row pgx.CollectableRow) (domain.Payment, error) {
+				var payment domain.Payment
+				err := scanPayment(row, &payment)
+				return payment, err
+			})
+		return err
+	})
 	if err != nil {
-		return nil, err
-	}
-
-	for rows.Next() {
-		err := rows.Scan(
-			&payment.ID,
-			&payment.Name,
-			&payment.Type,
-			&payment.Logo,
-			&payment.CreatedAt,
-			&payment.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		payments = append(payments, payment)
+		return nil, Page{}, err
 	}
 
-	return payments, nil
+	return payments, page, nil
 }
 
 // UpdatePayment updates a payment record in the database
@@ -153,21 +151,18 @@ func (pr *PaymentRepository) UpdatePayment(gctx *gin.Context, payment *domain.Pa
 		Set("logo", sq.Expr("COALESCE(?, logo)", logo)).
 		Set("updated_at", time.Now()).
 		Where(sq.Eq{"id": payment.ID}).
-		Suffix("RETURNING *")
+		Suffix("RETURNING " + paymentColumns)
+	if payment.Logo != "" {
+		// The thumbnail of an uploaded logo does not show another one
+		query = query.Set("logo_thumbnail", sq.Expr("CASE WHEN logo = ? THEN logo_thumbnail ELSE '' END", payment.Logo))
+	}
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	err = pr.Db.QueryRow(ctx, sql, args...).Scan(
-		&payment.ID,
-		&payment.Name,
-		&payment.Type,
-		&payment.Logo,
-		&payment.CreatedAt,
-		&payment.UpdatedAt,
-	)
+	err = scanPayment(pr.Db.QueryRow(ctx, sql, args...), payment)
 	if err != nil {
 		return nil, err
 	}
@@ -175,6 +170,50 @@ func (pr *PaymentRepository) UpdatePayment(gctx *gin.Context, payment *domain.Pa
 	return payment, nil
 }
 
+// SetPaymentLogo replaces the logo and logo thumbnail URLs of a payment with those of payment, reading the payment
+// back into it. It returns the payment as it was before, whose files the new ones replace.
+func (pr *PaymentRepository) SetPaymentLogo(gctx *gin.Context, payment *domain.Payment) (*domain.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var previous domain.Payment
+	err := pr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		query := psql.Select(paymentColumns).
+			From("payments").
+			Where(sq.Eq{"id": payment.ID}).
+			Suffix("FOR UPDATE")
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+		if err := scanPayment(tx.QueryRow(ctx, sql, args...), &previous); err != nil {
+			return err
+		}
+
+		update := psql.Update("payments").
+			Set("logo", payment.Logo).
+			Set("logo_thumbnail", payment.LogoThumbnail).
+			Set("updated_at", time.Now()).
+			Where(sq.Eq{"id": payment.ID}).
+			Suffix("RETURNING " + paymentColumns)
+
+		sql, args, err = update.ToSql()
+		if err != nil {
+			return err
+		}
+		return scanPayment(tx.QueryRow(ctx, sql, args...), payment)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, port.ErrDataNotFound
+		}
+		return nil, err
+	}
+
+	return &previous, nil
+}
+
 // DeletePayment deletes a payment record from the database by id
 func (pr *PaymentRepository) DeletePayment(gctx *gin.Context, id uint64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
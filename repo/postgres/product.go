@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"regexp"
+	"strings"
 	"time"
 
 	"gotemplate/core/domain"
@@ -10,6 +12,7 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -30,31 +33,57 @@ func NewProductRepository(Db *DB,log *logger.Logger) *ProductRepository {
 	}
 }
 
-// CreateProduct creates a new product record in the database
-func (pr *ProductRepository) CreateProduct(gctx *gin.Context, product *domain.Product) (*domain.Product, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	query := psql.Insert("products").
-		Columns("category_id", "name", "image", "price", "stock").
-		Values(product.CategoryID, product.Name, product.Image, product.Price, product.Stock).
-		Suffix("RETURNING *")
-
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return nil, err
-	}
+// productColumns lists the products columns read by scanProduct
+const productColumns = "id, category_id, sku, barcode, name, stock, price, image, thumbnail, tax_rate, created_at, updated_at"
 
-	err = pr.Db.QueryRow(ctx, sql, args...).Scan(
+// scanProduct scans a row selected with productColumns into product,
+// followed by any extra columns into the extra destinations
+func scanProduct(row pgx.Row, product *domain.Product, extra ...any) error {
+	dest := []any{
 		&product.ID,
 		&product.CategoryID,
 		&product.SKU,
+		&product.Barcode,
 		&product.Name,
 		&product.Stock,
 		&product.Price,
 		&product.Image,
+		&product.Thumbnail,
+		&product.TaxRate,
 		&product.CreatedAt,
 		&product.UpdatedAt,
-	)
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// CreateProduct creates a new product record in the database,
+// recording its stock as the opening movement of its stock ledger, posted by userID
+func (pr *ProductRepository) CreateProduct(gctx *gin.Context, product *domain.Product, userID uint64) (*domain.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	query := psql.Insert("products").
+		Columns("category_id", "barcode", "name", "image", "price", "stock", "tax_rate").
+		Values(product.CategoryID, product.Barcode, product.Name, product.Image, product.Price, product.Stock, product.TaxRate).
+		Suffix("RETURNING " + productColumns)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = pr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := scanProduct(tx.QueryRow(ctx, sql, args...), product); err != nil {
+			return err
+		}
+
+		return insertStockMovement(ctx, tx, &domain.StockMovement{
+			ProductID:  product.ID,
+			Source:     domain.StockOpening,
+			Quantity:   product.Stock,
+			StockAfter: product.Stock,
+			UserID:     &userID,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +97,7 @@ func (pr *ProductRepository) GetProductByID(gctx *gin.Context, id uint64) (*doma
 	defer cancel()
 	var product domain.Product
 
-	query := psql.Select("*").
+	query := psql.Select(productColumns).
 		From("products").
 		Where(sq.Eq{"id": id}).
 		Limit(1)
@@ -78,17 +107,7 @@ func (pr *ProductRepository) GetProductByID(gctx *gin.Context, id uint64) (*doma
 		return nil, err
 	}
 
-	err = pr.Db.QueryRow(ctx, sql, args...).Scan(
-		&product.ID,
-		&product.CategoryID,
-		&product.SKU,
-		&product.Name,
-		&product.Stock,
-		&product.Price,
-		&product.Image,
-		&product.CreatedAt,
-		&product.UpdatedAt,
-	)
+	err = scanProduct(pr.Db.QueryRow(ctx, sql, args...), &product)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, port.ErrDataNotFound
@@ -99,62 +118,120 @@ func (pr *ProductRepository) GetProductByID(gctx *gin.Context, id uint64) (*doma
 	return &product, nil
 }
 
-// ListProducts retrieves a list of products from the database
-func (pr *ProductRepository) ListProducts(gctx *gin.Context, search string, categoryId, skip, limit uint64) ([]domain.Product, error) {
+// productSearchWord matches the words of a search, letters and digits being the only characters safe in a tsquery
+var productSearchWord = regexp.MustCompile(`[\pL\pN]+`)
+
+// productSearchQuery is the tsquery of a search, its words matched as prefixes for as-you-type search,
+// empty when the search has no words
+func productSearchQuery(search string) string {
+	words := productSearchWord.FindAllString(search, -1)
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// searchProducts selects the products matching search on their name, category name, SKU or barcode,
+// or with a name close to it to tolerate typos, with how well they match in the search_rank column
+func searchProducts(search string) sq.SelectBuilder {
+	rank := sq.Expr("word_similarity(?, name)", search)
+	match := sq.Or{sq.Expr("? <% name", search)}
+	if tsquery := productSearchQuery(search); tsquery != "" {
+		rank = sq.Expr("ts_rank(search_vector, to_tsquery('simple', ?)) + word_similarity(?, name)", tsquery, search)
+		match = append(match, sq.Expr("search_vector @@ to_tsquery('simple', ?)", tsquery))
+	}
+
+	matched := psql.Select(productColumns).
+		Column(sq.Alias(rank, "search_rank")).
+		From("products").
+		Where(match)
+	return psql.Select(productColumns, "search_rank").FromSelect(matched, "matched")
+}
+
+// productRankKeyset lists searched products the best match first
+var productRankKeyset = keyset{columns: []keyColumn{{name: "search_rank", sqlType: "real", desc: true}}, id: "id", desc: true}
+
+// ListProducts retrieves a page of the products matching lq from the database in its sort order.
+// With a search, only the matching products are listed, the best match first unless lq is sorted.
+func (pr *ProductRepository) ListProducts(gctx *gin.Context, search string, categoryId uint64, lq ListQuery, params PageParams) ([]domain.Product, Page, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	
-	var product domain.Product
-	var products []domain.Product
 
-	query := psql.Select("*").
-		From("products").
-		OrderBy("id").
-		Limit(limit).
-		Offset((skip - 1) * limit)
+	query := psql.Select(productColumns).
+		From("products")
+	ks := ProductSchema.keyset(lq)
+
+	if search != "" {
+		query = searchProducts(search)
+		if len(lq.Sort) == 0 {
+			ks = productRankKeyset
+		}
+	}
 
 	if categoryId != 0 {
 		query = query.Where(sq.Eq{"category_id": categoryId})
 	}
 
-	if search != "" {
-		query = query.Where(sq.ILike{"name": "%" + search + "%"})
+	var products []domain.Product
+	var page Page
+	err := pr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		products, page, err = selectPage(ctx, tx, ProductSchema.where(query, lq), ks, params,
+			func(row pgx.CollectableRow) (domain.Product, error) {
+				var product domain.Product
+				if search != "" {
+					err := scanProduct(row, &product, &product.SearchRank)
+					return product, err
+				}
+				err := scanProduct(row, &product)
+				return product, err
+			},
+			func(product *domain.Product) []any { return ProductSchema.key(ks, product) })
+		return err
+	})
+	if err != nil {
+		return nil, Page{}, err
 	}
 
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return nil, err
+	return products, page, nil
+}
+
+// GetProductByCode retrieves the product with an SKU or barcode from the database, as scanned at the till
+func (pr *ProductRepository) GetProductByCode(gctx *gin.Context, code string) (*domain.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var product domain.Product
+
+	match := sq.Or{sq.Eq{"barcode": code}}
+	if sku, err := uuid.Parse(code); err == nil {
+		match = append(match, sq.Eq{"sku": sku})
 	}
 
-	rows, err := pr.Db.Query(ctx, sql, args...)
+	query := psql.Select(productColumns).
+		From("products").
+		Where(match).
+		Limit(1)
+
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	for rows.Next() {
-		err := rows.Scan(
-			&product.ID,
-			&product.CategoryID,
-			&product.SKU,
-			&product.Name,
-			&product.Stock,
-			&product.Price,
-			&product.Image,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
+	err = scanProduct(pr.Db.QueryRow(ctx, sql, args...), &product)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, port.ErrDataNotFound
 		}
-
-		products = append(products, product)
+		return nil, err
 	}
 
-	return products, nil
+	return &product, nil
 }
 
-// UpdateProduct updates a product record in the database
-func (pr *ProductRepository) UpdateProduct(gctx *gin.Context, product *domain.Product) (*domain.Product, error) {
+// UpdateProduct updates a product record in the database.
+// A nil tax rate keeps the current one unless clearTaxRate is set, the product then using the rate of its category.
+// Setting the stock records the change in its ledger as a stocktake posted by userID.
+func (pr *ProductRepository) UpdateProduct(gctx *gin.Context, product *domain.Product, clearTaxRate bool, userID uint64) (*domain.Product, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	
@@ -167,36 +244,104 @@ func (pr *ProductRepository) UpdateProduct(gctx *gin.Context, product *domain.Pr
 	query := psql.Update("products").
 		Set("name", sq.Expr("COALESCE(?, name)", name)).
 		Set("category_id", sq.Expr("COALESCE(?, category_id)", categoryId)).
+		Set("barcode", sq.Expr("COALESCE(?, barcode)", product.Barcode)).
 		Set("image", sq.Expr("COALESCE(?, image)", image)).
 		Set("price", sq.Expr("COALESCE(?, price)", price)).
 		Set("stock", sq.Expr("COALESCE(?, stock)", stock)).
+		Set("tax_rate", taxRateExpr(product.TaxRate, clearTaxRate)).
 		Set("updated_at", time.Now()).
 		Where(sq.Eq{"id": product.ID}).
-		Suffix("RETURNING *")
+		Suffix("RETURNING " + productColumns)
+	if product.Image != "" {
+		// The thumbnail of an uploaded image does not show another one
+		query = query.Set("thumbnail", sq.Expr("CASE WHEN image = ? THEN thumbnail ELSE '' END", product.Image))
+	}
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	err = pr.Db.QueryRow(ctx, sql, args...).Scan(
-		&product.ID,
-		&product.CategoryID,
-		&product.SKU,
-		&product.Name,
-		&product.Stock,
-		&product.Price,
-		&product.Image,
-		&product.CreatedAt,
-		&product.UpdatedAt,
-	)
+	err = pr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		var previous int64
+		if stock.Valid {
+			var err error
+			if previous, err = lockProductStock(ctx, tx, product.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := scanProduct(tx.QueryRow(ctx, sql, args...), product); err != nil {
+			return err
+		}
+
+		if !stock.Valid || product.Stock == previous {
+			return nil
+		}
+		reason := "Stock set by a product update"
+		return insertStockMovement(ctx, tx, &domain.StockMovement{
+			ProductID:  product.ID,
+			Source:     domain.StockStocktake,
+			Quantity:   product.Stock - previous,
+			StockAfter: product.Stock,
+			UserID:     &userID,
+			Reason:     &reason,
+		})
+	})
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, port.ErrDataNotFound
+		}
 		return nil, err
 	}
 
 	return product, nil
 }
 
+// SetProductImage replaces the image and thumbnail URLs of a product with those of product, reading the product
+// back into it. It returns the product as it was before, whose files the new ones replace.
+func (pr *ProductRepository) SetProductImage(gctx *gin.Context, product *domain.Product) (*domain.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var previous domain.Product
+	err := pr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		query := psql.Select(productColumns).
+			From("products").
+			Where(sq.Eq{"id": product.ID}).
+			Suffix("FOR UPDATE")
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+		if err := scanProduct(tx.QueryRow(ctx, sql, args...), &previous); err != nil {
+			return err
+		}
+
+		update := psql.Update("products").
+			Set("image", product.Image).
+			Set("thumbnail", product.Thumbnail).
+			Set("updated_at", time.Now()).
+			Where(sq.Eq{"id": product.ID}).
+			Suffix("RETURNING " + productColumns)
+
+		sql, args, err = update.ToSql()
+		if err != nil {
+			return err
+		}
+		return scanProduct(tx.QueryRow(ctx, sql, args...), product)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, port.ErrDataNotFound
+		}
+		return nil, err
+	}
+
+	return &previous, nil
+}
+
 // DeleteProduct deletes a product record from the database by id
 func (pr *ProductRepository) DeleteProduct(gctx *gin.Context, id uint64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
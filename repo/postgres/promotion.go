@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+/**
+ * PromotionRepository stores the promotions applied when pricing orders
+ * in the postgres database
+ */
+type PromotionRepository struct {
+	Db  *DB
+	log *logger.Logger
+}
+
+// NewPromotionRepository creates a new promotion repository instance
+func NewPromotionRepository(Db *DB, log *logger.Logger) *PromotionRepository {
+	return &PromotionRepository{
+		Db,
+		log,
+	}
+}
+
+// promotionColumns lists the promotions columns read by scanPromotion
+const promotionColumns = "id, name, kind, scope, target_id, value, buy_qty, get_qty, min_subtotal, starts_at, ends_at, active, created_at, updated_at"
+
+// scanPromotion scans a row selected with promotionColumns into promotion
+func scanPromotion(row pgx.Row, promotion *domain.Promotion) error {
+	return row.Scan(
+		&promotion.ID,
+		&promotion.Name,
+		&promotion.Kind,
+		&promotion.Scope,
+		&promotion.TargetID,
+		&promotion.Value,
+		&promotion.BuyQuantity,
+		&promotion.GetQuantity,
+		&promotion.MinSubtotal,
+		&promotion.StartsAt,
+		&promotion.EndsAt,
+		&promotion.Active,
+		&promotion.CreatedAt,
+		&promotion.UpdatedAt,
+	)
+}
+
+// selectPromotions selects the promotions matching query into a slice
+func selectPromotions(ctx context.Context, tx pgx.Tx, query sq.SelectBuilder) ([]domain.Promotion, error) {
+	var promotions []domain.Promotion
+	err := scanRows(ctx, tx, query, func(rows pgx.Rows) error {
+		var promotion domain.Promotion
+		if err := scanPromotion(rows, &promotion); err != nil {
+			return err
+		}
+		promotions = append(promotions, promotion)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+// activePromotionsQuery selects the enabled promotions whose time window includes at
+func activePromotionsQuery(at time.Time) sq.SelectBuilder {
+	return psql.Select(promotionColumns).
+		From("promotions").
+		Where(sq.Eq{"active": true}).
+		Where(sq.Or{sq.Eq{"starts_at": nil}, sq.LtOrEq{"starts_at": at}}).
+		Where(sq.Or{sq.Eq{"ends_at": nil}, sq.Gt{"ends_at": at}})
+}
+
+// activePromotions selects the enabled promotions whose time window includes at
+func activePromotions(ctx context.Context, tx pgx.Tx, at time.Time) ([]domain.Promotion, error) {
+	return selectPromotions(ctx, tx, activePromotionsQuery(at).OrderBy("id"))
+}
+
+// CreatePromotion creates a new promotion record in the database
+func (pr *PromotionRepository) CreatePromotion(gctx *gin.Context, promotion *domain.Promotion) (*domain.Promotion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Insert("promotions").
+		Columns("name", "kind", "scope", "target_id", "value", "buy_qty", "get_qty", "min_subtotal", "starts_at", "ends_at", "active").
+		Values(promotion.Name, promotion.Kind, promotion.Scope, promotion.TargetID, promotion.Value, promotion.BuyQuantity, promotion.GetQuantity, promotion.MinSubtotal, promotion.StartsAt, promotion.EndsAt, promotion.Active).
+		Suffix("RETURNING " + promotionColumns)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanPromotion(pr.Db.QueryRow(ctx, sql, args...), promotion)
+	if err != nil {
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+// GetPromotionByID retrieves a promotion record from the database by id
+func (pr *PromotionRepository) GetPromotionByID(gctx *gin.Context, id uint64) (*domain.Promotion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var promotion domain.Promotion
+
+	query := psql.Select(promotionColumns).
+		From("promotions").
+		Where(sq.Eq{"id": id}).
+		Limit(1)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanPromotion(pr.Db.QueryRow(ctx, sql, args...), &promotion)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, port.ErrDataNotFound
+		}
+		return nil, err
+	}
+
+	return &promotion, nil
+}
+
+// ListPromotions retrieves a page of the promotions matching lq from the database in its sort order, only those active now when activeOnly is set
+func (pr *PromotionRepository) ListPromotions(gctx *gin.Context, activeOnly bool, lq ListQuery, params PageParams) ([]domain.Promotion, Page, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Select(promotionColumns).
+		From("promotions")
+	if activeOnly {
+		query = activePromotionsQuery(time.Now())
+	}
+
+	var promotions []domain.Promotion
+	var page Page
+	err := pr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		promotions, page, err = selectList(ctx, tx, query, PromotionSchema, lq, params,
+			func(row pgx.CollectableRow) (domain.Promotion, error) {
+				var promotion domain.Promotion
+				err := scanPromotion(row, &promotion)
+				return promotion, err
+			})
+		return err
+	})
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	return promotions, page, nil
+}
+
+// UpdatePromotion replaces a promotion record in the database
+func (pr *PromotionRepository) UpdatePromotion(gctx *gin.Context, promotion *domain.Promotion) (*domain.Promotion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Update("promotions").
+		Set("name", promotion.Name).
+		Set("kind", promotion.Kind).
+		Set("scope", promotion.Scope).
+		Set("target_id", promotion.TargetID).
+		Set("value", promotion.Value).
+		Set("buy_qty", promotion.BuyQuantity).
+		Set("get_qty", promotion.GetQuantity).
+		Set("min_subtotal", promotion.MinSubtotal).
+		Set("starts_at", promotion.StartsAt).
+		Set("ends_at", promotion.EndsAt).
+		Set("active", promotion.Active).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": promotion.ID}).
+		Suffix("RETURNING " + promotionColumns)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanPromotion(pr.Db.QueryRow(ctx, sql, args...), promotion)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, port.ErrDataNotFound
+		}
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+// DeletePromotion deletes a promotion record from the database by id.
+// Orders priced with it keep their discounts.
+func (pr *PromotionRepository) DeletePromotion(gctx *gin.Context, id uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Delete("promotions").
+		Where(sq.Eq{"id": id})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	tag, err := pr.Db.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return port.ErrDataNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gotemplate/core/domain"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Operator is a comparison of a list filter
+type Operator string
+
+// Operator enum values
+const (
+	OpEq  Operator = "eq"
+	OpNe  Operator = "ne"
+	OpGt  Operator = "gt"
+	OpGte Operator = "gte"
+	OpLt  Operator = "lt"
+	OpLte Operator = "lte"
+	// OpLike matches the values containing the filter value, ignoring case
+	OpLike Operator = "like"
+	// OpIn matches any of the filter values
+	OpIn Operator = "in"
+)
+
+// ValueKind is the kind of values a list field holds, which filter values are parsed as
+type ValueKind string
+
+// ValueKind enum values
+const (
+	KindString ValueKind = "string"
+	KindInt    ValueKind = "int"
+	KindUint   ValueKind = "uint"
+	KindFloat  ValueKind = "float"
+	KindBool   ValueKind = "bool"
+	KindTime   ValueKind = "time"
+	KindUUID   ValueKind = "uuid"
+)
+
+// sqlTypes maps the value kinds to the SQL type cursor values are cast to
+var sqlTypes = map[ValueKind]string{
+	KindString: "text",
+	KindInt:    "bigint",
+	KindUint:   "bigint",
+	KindFloat:  "numeric",
+	KindBool:   "boolean",
+	KindTime:   "timestamptz",
+	KindUUID:   "uuid",
+}
+
+// ListField is a field of a list: its name in the responses and the query string, the kind of its values,
+// the filter operators it allows and whether the list can be sorted by it
+type ListField struct {
+	Name      string
+	Kind      ValueKind
+	Operators []Operator
+	Sortable  bool
+	column    string
+	index     []int
+}
+
+// Allows reports whether the field can be filtered with op
+func (f ListField) Allows(op Operator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// ListSchema is the whitelist of the fields a list can be filtered, sorted and selected on,
+// read from the fields of a domain struct. The json tag names a field and its column; the query tag
+// lists the operators it can be filtered with and sort when the list can be sorted by it, as in `query:"eq,in,sort"`.
+type ListSchema struct {
+	fields map[string]ListField
+	names  []string
+}
+
+// NewListSchema reads the schema of the lists of the domain struct of model.
+// It panics on a query tag the field cannot support, as a schema is declared once for the life of the program.
+func NewListSchema(model any) *ListSchema {
+	schema := &ListSchema{fields: make(map[string]ListField)}
+
+	t := reflect.TypeOf(model)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.SplitN(sf.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" || !sf.IsExported() {
+			continue
+		}
+
+		field := ListField{Name: name, column: name, index: sf.Index}
+		schema.names = append(schema.names, name)
+
+		tag, ok := sf.Tag.Lookup("query")
+		if !ok {
+			schema.fields[name] = field
+			continue
+		}
+
+		nullable := sf.Type.Kind() == reflect.Pointer
+		kind, ok := valueKind(sf.Type)
+		if !ok {
+			panic(fmt.Sprintf("list field %s of %s has no filterable type", name, t.Name()))
+		}
+		field.Kind = kind
+
+		for _, option := range strings.Split(tag, ",") {
+			switch op := Operator(option); {
+			case option == "sort":
+				if nullable {
+					panic(fmt.Sprintf("list field %s of %s is nullable and cannot be sorted by", name, t.Name()))
+				}
+				field.Sortable = true
+			case op == OpLike && kind != KindString:
+				panic(fmt.Sprintf("list field %s of %s is not a string and cannot be filtered with like", name, t.Name()))
+			case op == OpEq, op == OpNe, op == OpGt, op == OpGte, op == OpLt, op == OpLte, op == OpLike, op == OpIn:
+				field.Operators = append(field.Operators, op)
+			default:
+				panic(fmt.Sprintf("list field %s of %s has an unknown query option %q", name, t.Name(), option))
+			}
+		}
+		schema.fields[name] = field
+	}
+
+	if _, ok := schema.fields["id"]; !ok {
+		panic(fmt.Sprintf("list schema of %s has no id field", t.Name()))
+	}
+
+	return schema
+}
+
+// valueKind returns the kind of the values of a field of type t, dereferencing pointers
+func valueKind(t reflect.Type) (ValueKind, bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return KindTime, true
+	case t == reflect.TypeOf(uuid.UUID{}):
+		return KindUUID, true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return KindString, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return KindInt, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return KindUint, true
+	case reflect.Float32, reflect.Float64:
+		return KindFloat, true
+	case reflect.Bool:
+		return KindBool, true
+	}
+	return "", false
+}
+
+// Field returns the field of the list named name
+func (s *ListSchema) Field(name string) (ListField, bool) {
+	field, ok := s.fields[name]
+	return field, ok
+}
+
+// Fields returns the names of the fields of the list in declaration order
+func (s *ListSchema) Fields() []string {
+	return s.names
+}
+
+// Condition keeps the rows of a list whose field compares to Values with Op,
+// Values holding one value but for OpIn. The values have the Go type of the kind of the field.
+type Condition struct {
+	Field  string
+	Op     Operator
+	Values []any
+}
+
+// SortField orders a list by a field, descending when Desc is set
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListQuery filters a list with all its conditions and sorts it by its sort fields, then by id
+type ListQuery struct {
+	Conditions []Condition
+	Sort       []SortField
+}
+
+// where adds the conditions of lq to query
+func (s *ListSchema) where(query sq.SelectBuilder, lq ListQuery) sq.SelectBuilder {
+	for _, condition := range lq.Conditions {
+		column := s.fields[condition.Field].column
+		value := condition.Values[0]
+
+		switch condition.Op {
+		case OpEq:
+			query = query.Where(sq.Eq{column: value})
+		case OpNe:
+			query = query.Where(sq.NotEq{column: value})
+		case OpGt:
+			query = query.Where(sq.Gt{column: value})
+		case OpGte:
+			query = query.Where(sq.GtOrEq{column: value})
+		case OpLt:
+			query = query.Where(sq.Lt{column: value})
+		case OpLte:
+			query = query.Where(sq.LtOrEq{column: value})
+		case OpLike:
+			query = query.Where(sq.ILike{column: "%" + likeEscaper.Replace(value.(string)) + "%"})
+		case OpIn:
+			query = query.Where(sq.Eq{column: condition.Values})
+		}
+	}
+
+	return query
+}
+
+// keyset returns the keyset of the sort of lq, ids going the way of the last sort field
+func (s *ListSchema) keyset(lq ListQuery) keyset {
+	ks := idKeyset(s.fields["id"].column)
+	for _, sort := range lq.Sort {
+		field := s.fields[sort.Field]
+		ks.desc = sort.Desc
+		// Nothing sorts after the unique id
+		if field.Name == "id" {
+			break
+		}
+		ks.columns = append(ks.columns, keyColumn{name: field.column, sqlType: sqlTypes[field.Kind], desc: sort.Desc})
+	}
+	return ks
+}
+
+// key returns the values of the columns of ks of a row scanned into item
+func (s *ListSchema) key(ks keyset, item any) []any {
+	value := reflect.ValueOf(item).Elem()
+
+	var key []any
+	for _, column := range ks.all() {
+		key = append(key, value.FieldByIndex(s.fields[column.name].index).Interface())
+	}
+	return key
+}
+
+// selectList selects the page of query described by params, filtered and sorted by lq with the fields of schema
+func selectList[T any](ctx context.Context, tx pgx.Tx, query sq.SelectBuilder, schema *ListSchema, lq ListQuery,
+	params PageParams, scan pgx.RowToFunc[T]) ([]T, Page, error) {
+	ks := schema.keyset(lq)
+	return selectPage(ctx, tx, schema.where(query, lq), ks, params, scan,
+		func(item *T) []any { return schema.key(ks, item) })
+}
+
+// List schemas of the resources listed with a ListQuery
+var (
+	CategorySchema  = NewListSchema(domain.Category{})
+	ProductSchema   = NewListSchema(domain.Product{})
+	PaymentSchema   = NewListSchema(domain.Payment{})
+	PromotionSchema = NewListSchema(domain.Promotion{})
+)
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/logger"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+/**
+ * ReportRepository computes sales reports over orders and order_products
+ * in the postgres database
+ */
+type ReportRepository struct {
+	Db  *DB
+	log *logger.Logger
+}
+
+// NewReportRepository creates a new report repository instance
+func NewReportRepository(Db *DB, log *logger.Logger) *ReportRepository {
+	return &ReportRepository{
+		Db,
+		log,
+	}
+}
+
+// Aggregates of the order lines selected by reportLines, net of refunded quantities
+const (
+	reportOrders  = "COUNT(DISTINCT o.id) AS orders"
+	reportItems   = "COALESCE(SUM(op.quantity - op.refunded_quantity), 0)::bigint"
+	reportRevenue = "COALESCE(ROUND(SUM(op.total_price * (op.quantity - op.refunded_quantity) / op.quantity)::numeric, 2), 0)::float8"
+)
+
+// reportLines selects the lines of the orders matching filter, skipping cancelled orders.
+// With a category filter only the lines of products in that category are counted.
+func reportLines(filter domain.ReportFilter, columns ...string) sq.SelectBuilder {
+	query := psql.Select(columns...).
+		From("order_products op").
+		Join("orders o ON o.id = op.order_id").
+		Join("products p ON p.id = op.product_id").
+		Where(sq.NotEq{"o.status": domain.OrderCancelled}).
+		Where(sq.GtOrEq{"o.created_at": filter.From}).
+		Where(sq.Lt{"o.created_at": filter.To})
+
+	if filter.CategoryID != 0 {
+		query = query.Where(sq.Eq{"p.category_id": filter.CategoryID})
+	}
+	return query
+}
+
+// SalesByPeriod sums revenue, orders and items per day, week or month.
+// Periods start at midnight in the filter timezone and are labelled with their first date.
+func (rr *ReportRepository) SalesByPeriod(gctx *gin.Context, filter domain.ReportFilter, interval domain.ReportInterval) ([]domain.PeriodSales, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := reportLines(filter, reportOrders, reportItems+" AS items", reportRevenue+" AS revenue").
+		Column(sq.Expr("to_char(date_trunc(?, o.created_at AT TIME ZONE ?), 'YYYY-MM-DD') AS period", string(interval), filter.Timezone)).
+		GroupBy("period").
+		OrderBy("period")
+
+	return SelectRows(ctx, rr.Db, query, pgx.RowToStructByName[domain.PeriodSales], rr.log)
+}
+
+// TopProducts lists the limit products with the highest net quantity sold, or revenue when byRevenue is set
+func (rr *ReportRepository) TopProducts(gctx *gin.Context, filter domain.ReportFilter, limit uint64, byRevenue bool) ([]domain.ProductSales, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	orderBy := "quantity DESC, revenue DESC, product_id"
+	if byRevenue {
+		orderBy = "revenue DESC, quantity DESC, product_id"
+	}
+
+	query := reportLines(filter, "p.id AS product_id", "p.name", reportItems+" AS quantity", reportRevenue+" AS revenue").
+		GroupBy("p.id", "p.name").
+		OrderBy(orderBy).
+		Limit(limit)
+
+	return SelectRows(ctx, rr.Db, query, pgx.RowToStructByName[domain.ProductSales], rr.log)
+}
+
+// SalesByCashier sums revenue, orders and items per cashier
+func (rr *ReportRepository) SalesByCashier(gctx *gin.Context, filter domain.ReportFilter) ([]domain.CashierSales, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := reportLines(filter, "o.user_id", "COALESCE(u.name, '') AS name", reportOrders, reportItems+" AS items", reportRevenue+" AS revenue").
+		LeftJoin("users u ON u.id = o.user_id").
+		GroupBy("o.user_id", "u.name").
+		OrderBy("revenue DESC, o.user_id")
+
+	return SelectRows(ctx, rr.Db, query, pgx.RowToStructByName[domain.CashierSales], rr.log)
+}
+
+// SalesByPayment sums revenue and orders per payment type
+func (rr *ReportRepository) SalesByPayment(gctx *gin.Context, filter domain.ReportFilter) ([]domain.PaymentSales, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := reportLines(filter, "o.payment_id", "COALESCE(pay.name, '') AS name", "COALESCE(pay.type::text, '') AS type", reportOrders, reportRevenue+" AS revenue").
+		LeftJoin("payments pay ON pay.id = o.payment_id").
+		GroupBy("o.payment_id", "pay.name", "pay.type").
+		OrderBy("revenue DESC, o.payment_id")
+
+	return SelectRows(ctx, rr.Db, query, pgx.RowToStructByName[domain.PaymentSales], rr.log)
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+/**
+ * SessionRepository stores hashed refresh tokens
+ * and provides an access to the postgres database
+ */
+type SessionRepository struct {
+	Db  *DB
+	log *logger.Logger
+}
+
+// NewSessionRepository creates a new session repository instance
+func NewSessionRepository(Db *DB, log *logger.Logger) *SessionRepository {
+	return &SessionRepository{
+		Db,
+		log,
+	}
+}
+
+// sessionColumns lists the columns scanned into domain.Session
+var sessionColumns = []string{
+	"id", "user_id", "family_id", "token_hash", "user_agent", "client_ip",
+	"expires_at", "revoked_at", "replaced_by", "last_used_at", "created_at",
+}
+
+// CreateSession stores a new refresh token session in the database
+func (sr *SessionRepository) CreateSession(gctx *gin.Context, session *domain.Session) (*domain.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Insert("sessions").
+		Columns("user_id", "family_id", "token_hash", "user_agent", "client_ip", "expires_at").
+		Values(session.UserID, session.FamilyID, session.TokenHash, session.UserAgent, session.ClientIP, session.ExpiresAt).
+		Suffix("RETURNING *")
+
+	return InsertReturning(ctx, sr.Db, query, pgx.RowToAddrOfStructByName[domain.Session], sr.log)
+}
+
+// RotateSession exchanges the session identified by tokenHash for next.
+// Presenting a token that was already rotated or revoked revokes its whole family.
+func (sr *SessionRepository) RotateSession(gctx *gin.Context, tokenHash string, next *domain.Session) (*domain.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var current domain.Session
+	reused := false
+
+	err := sr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		reused = false
+
+		currentQuery := psql.Select(sessionColumns...).
+			From("sessions").
+			Where(sq.Eq{"token_hash": tokenHash}).
+			Suffix("FOR UPDATE")
+
+		err := TxReturnRow(ctx, tx, currentQuery, pgx.RowToStructByName[domain.Session], sr.log, &current)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return port.ErrInvalidToken
+			}
+			return err
+		}
+
+		now := time.Now()
+
+		if current.RevokedAt != nil {
+			reused = true
+			revokeFamilyQuery := psql.Update("sessions").
+				Set("revoked_at", now).
+				Where(sq.Eq{"family_id": current.FamilyID}).
+				Where(sq.Eq{"revoked_at": nil})
+			return TxExec(ctx, tx, revokeFamilyQuery, sr.log)
+		}
+
+		if now.After(current.ExpiresAt) {
+			return port.ErrExpiredToken
+		}
+
+		insertQuery := psql.Insert("sessions").
+			Columns("user_id", "family_id", "token_hash", "user_agent", "client_ip", "expires_at").
+			Values(current.UserID, current.FamilyID, next.TokenHash, next.UserAgent, next.ClientIP, next.ExpiresAt).
+			Suffix("RETURNING *")
+
+		err = TxReturnRow(ctx, tx, insertQuery, pgx.RowToStructByName[domain.Session], sr.log, next)
+		if err != nil {
+			return err
+		}
+
+		revokeQuery := psql.Update("sessions").
+			Set("revoked_at", now).
+			Set("last_used_at", now).
+			Set("replaced_by", next.ID).
+			Where(sq.Eq{"id": current.ID})
+
+		return TxExec(ctx, tx, revokeQuery, sr.log)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The family revocation above has to commit, so reuse is reported after the transaction
+	if reused {
+		sr.log.Warn("refresh token reuse detected for user %d", current.UserID)
+		return nil, port.ErrInvalidToken
+	}
+
+	return next, nil
+}
+
+// ListSessions lists the active sessions of a user
+func (sr *SessionRepository) ListSessions(gctx *gin.Context, userID uint64) ([]domain.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Select(sessionColumns...).
+		From("sessions").
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil}).
+		Where(sq.Expr("expires_at > now()")).
+		OrderBy("created_at DESC")
+
+	return SelectRows(ctx, sr.Db, query, pgx.RowToStructByName[domain.Session], sr.log)
+}
+
+// RevokeSession revokes one active session of a user
+func (sr *SessionRepository) RevokeSession(gctx *gin.Context, userID uint64, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Update("sessions").
+		Set("revoked_at", time.Now()).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil})
+
+	ct, err := Update(ctx, sr.Db, query, sr.log)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return port.ErrDataNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of a user and returns how many were revoked
+func (sr *SessionRepository) RevokeUserSessions(gctx *gin.Context, userID uint64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Update("sessions").
+		Set("revoked_at", time.Now()).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil})
+
+	ct, err := Update(ctx, sr.Db, query, sr.log)
+	if err != nil {
+		return 0, err
+	}
+
+	return ct.RowsAffected(), nil
+}
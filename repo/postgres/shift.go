@@ -0,0 +1,375 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+/**
+ * ShiftRepository opens and closes cashier shifts and computes their Z-reports
+ * in the postgres database
+ */
+type ShiftRepository struct {
+	Db  *DB
+	log *logger.Logger
+}
+
+// NewShiftRepository creates a new shift repository instance
+func NewShiftRepository(Db *DB, log *logger.Logger) *ShiftRepository {
+	return &ShiftRepository{
+		Db,
+		log,
+	}
+}
+
+// shiftColumns lists the shifts columns read by scanShift
+const shiftColumns = "id, user_id, opening_float, expected_cash, counted_cash, variance, note, opened_at, closed_at, closed_by"
+
+// scanShift scans a row selected with shiftColumns into shift
+func scanShift(row pgx.Row, shift *domain.Shift) error {
+	return row.Scan(
+		&shift.ID,
+		&shift.UserID,
+		&shift.OpeningFloat,
+		&shift.ExpectedCash,
+		&shift.CountedCash,
+		&shift.Variance,
+		&shift.Note,
+		&shift.OpenedAt,
+		&shift.ClosedAt,
+		&shift.ClosedBy,
+	)
+}
+
+// openShiftID returns the id of the open shift of a cashier, or nil when none is open.
+// The shift row is share locked so it cannot be closed before the calling transaction ends.
+func openShiftID(ctx context.Context, tx pgx.Tx, userID uint64) (*uint64, error) {
+	query := psql.Select("id").
+		From("shifts").
+		Where(sq.Eq{"user_id": userID, "closed_at": nil}).
+		Suffix("FOR SHARE")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var id uint64
+	err = tx.QueryRow(ctx, sql, args...).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &id, nil
+}
+
+// OpenShift opens a shift for a cashier with a starting cash float
+func (sr *ShiftRepository) OpenShift(gctx *gin.Context, shift *domain.Shift) (*domain.Shift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Insert("shifts").
+		Columns("user_id", "opening_float").
+		Values(shift.UserID, roundMoney(shift.OpeningFloat)).
+		Suffix("RETURNING " + shiftColumns)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanShift(sr.Db.QueryRow(ctx, sql, args...), shift)
+	if err != nil {
+		if port.IsUniqueConstraintViolationError(err) {
+			return nil, port.ErrShiftAlreadyOpen
+		}
+		return nil, err
+	}
+
+	return shift, nil
+}
+
+// getShift selects the shift matching where
+func getShift(ctx context.Context, tx pgx.Tx, where sq.Eq, forUpdate bool) (*domain.Shift, error) {
+	query := psql.Select(shiftColumns).
+		From("shifts").
+		Where(where).
+		Limit(1)
+	if forUpdate {
+		query = query.Suffix("FOR UPDATE")
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var shift domain.Shift
+	err = scanShift(tx.QueryRow(ctx, sql, args...), &shift)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, port.ErrDataNotFound
+		}
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// GetShiftByID gets a shift by ID from the database
+func (sr *ShiftRepository) GetShiftByID(gctx *gin.Context, id uint64) (*domain.Shift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var shift *domain.Shift
+	err := sr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		shift, err = getShift(ctx, tx, sq.Eq{"id": id}, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shift, nil
+}
+
+// GetOpenShift gets the open shift of a cashier from the database
+func (sr *ShiftRepository) GetOpenShift(gctx *gin.Context, userID uint64) (*domain.Shift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var shift *domain.Shift
+	err := sr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		shift, err = getShift(ctx, tx, sq.Eq{"user_id": userID, "closed_at": nil}, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shift, nil
+}
+
+// CloseShift closes a shift with the cash counted in the drawer and returns its Z-report.
+// The expected cash and the over/short variance are computed and stored with the shift.
+func (sr *ShiftRepository) CloseShift(gctx *gin.Context, id, closedBy uint64, countedCash float64, note *string) (*domain.ZReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var report *domain.ZReport
+	err := sr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		shift, err := getShift(ctx, tx, sq.Eq{"id": id}, true)
+		if err != nil {
+			return err
+		}
+		if !shift.IsOpen() {
+			return port.ErrShiftClosed
+		}
+
+		report, err = zReport(ctx, tx, []domain.Shift{*shift})
+		if err != nil {
+			return err
+		}
+
+		closeQuery := psql.Update("shifts").
+			Set("expected_cash", report.ExpectedCash).
+			Set("counted_cash", roundMoney(countedCash)).
+			Set("variance", roundMoney(countedCash-report.ExpectedCash)).
+			Set("note", note).
+			Set("closed_at", time.Now()).
+			Set("closed_by", closedBy).
+			Where(sq.Eq{"id": shift.ID}).
+			Suffix("RETURNING " + shiftColumns)
+
+		sql, args, err := closeQuery.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if err := scanShift(tx.QueryRow(ctx, sql, args...), shift); err != nil {
+			return err
+		}
+
+		report.Shifts = []domain.Shift{*shift}
+		report.CountedCash = shift.CountedCash
+		report.Variance = shift.Variance
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// ShiftReport computes the Z-report of a shift, a running total while the shift is open
+func (sr *ShiftRepository) ShiftReport(gctx *gin.Context, id uint64) (*domain.ZReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var report *domain.ZReport
+	err := sr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		shift, err := getShift(ctx, tx, sq.Eq{"id": id}, false)
+		if err != nil {
+			return err
+		}
+
+		report, err = zReport(ctx, tx, []domain.Shift{*shift})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// DailyReport computes the Z-report of every shift opened in [from, to)
+func (sr *ShiftRepository) DailyReport(gctx *gin.Context, from, to time.Time) (*domain.ZReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Select(shiftColumns).
+		From("shifts").
+		Where(sq.GtOrEq{"opened_at": from}).
+		Where(sq.Lt{"opened_at": to}).
+		OrderBy("opened_at", "id")
+
+	var report *domain.ZReport
+	err := sr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var shifts []domain.Shift
+		err := scanRows(ctx, tx, query, func(rows pgx.Rows) error {
+			var shift domain.Shift
+			if err := scanShift(rows, &shift); err != nil {
+				return err
+			}
+			shifts = append(shifts, shift)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		report, err = zReport(ctx, tx, shifts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// zReport sums the orders, refunds and payments of shifts and reconciles their cash.
+// Expected cash is the opening floats plus cash sales less cash refunds.
+func zReport(ctx context.Context, tx pgx.Tx, shifts []domain.Shift) (*domain.ZReport, error) {
+	report := &domain.ZReport{
+		Shifts:   shifts,
+		Payments: []domain.PaymentTotal{},
+	}
+
+	ids := make([]uint64, 0, len(shifts))
+	for _, shift := range shifts {
+		ids = append(ids, shift.ID)
+		report.OpeningFloat += shift.OpeningFloat
+
+		if !shift.IsOpen() && shift.CountedCash != nil && shift.Variance != nil {
+			counted, variance := *shift.CountedCash, *shift.Variance
+			if report.CountedCash != nil {
+				counted += *report.CountedCash
+				variance += *report.Variance
+			}
+			counted, variance = roundMoney(counted), roundMoney(variance)
+			report.CountedCash, report.Variance = &counted, &variance
+		}
+	}
+	report.OpeningFloat = roundMoney(report.OpeningFloat)
+	if len(ids) == 0 {
+		return report, nil
+	}
+
+	ordersQuery := psql.Select().
+		Column(sq.Expr("COUNT(*) FILTER (WHERE status <> ?)", domain.OrderCancelled)).
+		Column(sq.Expr("COALESCE(SUM(total_price) FILTER (WHERE status <> ?), 0)::float8", domain.OrderCancelled)).
+		Column(sq.Expr("COUNT(*) FILTER (WHERE status = ?)", domain.OrderCancelled)).
+		Column(sq.Expr("COALESCE(SUM(total_price) FILTER (WHERE status = ?), 0)::float8", domain.OrderCancelled)).
+		From("orders").
+		Where("shift_id = ANY(?)", ids)
+
+	sql, args, err := ordersQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, sql, args...).Scan(
+		&report.Orders,
+		&report.GrossSales,
+		&report.Cancelled,
+		&report.CancelledAmount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	refundsQuery := psql.Select("COUNT(*)", "COALESCE(SUM(amount), 0)::float8").
+		From("order_refunds").
+		Where("shift_id = ANY(?)", ids)
+
+	sql, args, err = refundsQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, sql, args...).Scan(
+		&report.Refunds,
+		&report.RefundAmount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sales are counted on the payment of the order, refunds on the payment of the refunded order
+	paymentsQuery := psql.Select(
+		"pay.id AS payment_id",
+		"pay.name",
+		"pay.type::text AS type",
+		"COALESCE(s.orders, 0) AS orders",
+		"COALESCE(s.sales, 0)::float8 AS sales",
+		"COALESCE(r.refunds, 0)::float8 AS refunds",
+	).
+		From("payments pay").
+		LeftJoin("(SELECT payment_id, COUNT(*) AS orders, SUM(total_price) AS sales FROM orders WHERE shift_id = ANY(?) AND status <> ? GROUP BY payment_id) s ON s.payment_id = pay.id", ids, domain.OrderCancelled).
+		LeftJoin("(SELECT o.payment_id, SUM(rf.amount) AS refunds FROM order_refunds rf JOIN orders o ON o.id = rf.order_id WHERE rf.shift_id = ANY(?) GROUP BY o.payment_id) r ON r.payment_id = pay.id", ids).
+		Where("s.payment_id IS NOT NULL OR r.payment_id IS NOT NULL").
+		OrderBy("pay.id")
+
+	err = scanRows(ctx, tx, paymentsQuery, func(rows pgx.Rows) error {
+		total, err := pgx.RowToStructByName[domain.PaymentTotal](rows)
+		if err != nil {
+			return err
+		}
+		report.Payments = append(report.Payments, total)
+		if total.Type == domain.Cash {
+			report.CashSales += total.Sales
+			report.CashRefunds += total.Refunds
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.CashSales = roundMoney(report.CashSales)
+	report.CashRefunds = roundMoney(report.CashRefunds)
+	report.NetSales = roundMoney(report.GrossSales - report.RefundAmount)
+	report.ExpectedCash = roundMoney(report.OpeningFloat + report.CashSales - report.CashRefunds)
+
+	return report, nil
+}
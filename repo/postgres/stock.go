@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+	"gotemplate/logger"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+/**
+ * StockRepository posts manual stock movements and lists the stock ledger of products
+ * in the postgres database
+ */
+type StockRepository struct {
+	Db  *DB
+	log *logger.Logger
+}
+
+// NewStockRepository creates a new stock repository instance
+func NewStockRepository(Db *DB, log *logger.Logger) *StockRepository {
+	return &StockRepository{
+		Db,
+		log,
+	}
+}
+
+// stockMovementColumns lists the stock_movements columns read by scanStockMovement
+const stockMovementColumns = "id, product_id, source, quantity, stock_after, user_id, order_id, refund_id, reference, reason, created_at"
+
+// stockMovementKeyset lists the movements the most recent first
+var stockMovementKeyset = keyset{id: "id", desc: true}
+
+// scanStockMovement scans a row selected with stockMovementColumns into movement
+func scanStockMovement(row pgx.Row, movement *domain.StockMovement) error {
+	return row.Scan(
+		&movement.ID,
+		&movement.ProductID,
+		&movement.Source,
+		&movement.Quantity,
+		&movement.StockAfter,
+		&movement.UserID,
+		&movement.OrderID,
+		&movement.RefundID,
+		&movement.Reference,
+		&movement.Reason,
+		&movement.CreatedAt,
+	)
+}
+
+// insertStockMovement records movement in the ledger, the stock of its product having already been changed in tx
+func insertStockMovement(ctx context.Context, tx pgx.Tx, movement *domain.StockMovement) error {
+	query := psql.Insert("stock_movements").
+		Columns("product_id", "source", "quantity", "stock_after", "user_id", "order_id", "refund_id", "reference", "reason").
+		Values(movement.ProductID, movement.Source, movement.Quantity, movement.StockAfter, movement.UserID,
+			movement.OrderID, movement.RefundID, movement.Reference, movement.Reason).
+		Suffix("RETURNING " + stockMovementColumns)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return scanStockMovement(tx.QueryRow(ctx, sql, args...), movement)
+}
+
+// moveStock changes the stock of the product of movement by its quantity and records the movement
+// with the stock it left, in tx. A change that would leave the stock negative fails with port.ErrInsufficientStock.
+// Callers moving the stock of several products lock their rows in id order first.
+func moveStock(ctx context.Context, tx pgx.Tx, movement *domain.StockMovement) error {
+	query := psql.Update("products").
+		Set("stock", sq.Expr("stock + ?", movement.Quantity)).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": movement.ProductID}).
+		Where(sq.Expr("stock + ? >= 0", movement.Quantity)).
+		Suffix("RETURNING stock")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, sql, args...).Scan(&movement.StockAfter)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return port.ErrInsufficientStock
+		}
+		return err
+	}
+
+	return insertStockMovement(ctx, tx, movement)
+}
+
+// moveStocks moves the stock of every product of quantities by its quantity in product id order,
+// recording movements copied from movement
+func moveStocks(ctx context.Context, tx pgx.Tx, quantities map[uint64]int64, movement domain.StockMovement) error {
+	productIDs := make([]uint64, 0, len(quantities))
+	for id, quantity := range quantities {
+		if quantity != 0 {
+			productIDs = append(productIDs, id)
+		}
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	for _, id := range productIDs {
+		m := movement
+		m.ProductID = id
+		m.Quantity = quantities[id]
+		if err := moveStock(ctx, tx, &m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockProductStock locks the row of a product and returns its stock
+func lockProductStock(ctx context.Context, tx pgx.Tx, productID uint64) (int64, error) {
+	query := psql.Select("stock").
+		From("products").
+		Where(sq.Eq{"id": productID}).
+		Suffix("FOR UPDATE")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var stock int64
+	err = tx.QueryRow(ctx, sql, args...).Scan(&stock)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, port.ErrDataNotFound
+		}
+		return 0, err
+	}
+	return stock, nil
+}
+
+// PostStockMovement records a manual movement of the stock of a product and applies it to the product stock.
+// Adjustments and receivings change the stock by Quantity; a stocktake sets it to StockAfter,
+// the quantity counted, Quantity being set to the difference.
+func (sr *StockRepository) PostStockMovement(gctx *gin.Context, movement *domain.StockMovement) (*domain.StockMovement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := sr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		stock, err := lockProductStock(ctx, tx, movement.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if movement.Source == domain.StockStocktake {
+			movement.Quantity = movement.StockAfter - stock
+		}
+
+		return moveStock(ctx, tx, movement)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// ListStockMovements retrieves a page of the stock movements matching filter from the database, the most recent first.
+// Listing the movements of a product that does not exist fails with port.ErrDataNotFound.
+func (sr *StockRepository) ListStockMovements(gctx *gin.Context, filter domain.StockMovementFilter, params PageParams) ([]domain.StockMovement, Page, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := psql.Select(stockMovementColumns).
+		From("stock_movements")
+	if filter.ProductID != 0 {
+		query = query.Where(sq.Eq{"product_id": filter.ProductID})
+	}
+	if filter.Source != "" {
+		query = query.Where(sq.Eq{"source": filter.Source})
+	}
+	if filter.From != nil {
+		query = query.Where(sq.GtOrEq{"created_at": *filter.From})
+	}
+	if filter.To != nil {
+		query = query.Where(sq.Lt{"created_at": *filter.To})
+	}
+
+	var movements []domain.StockMovement
+	var page Page
+	err := sr.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		if filter.ProductID != 0 {
+			exists := psql.Select("1").
+				From("products").
+				Where(sq.Eq{"id": filter.ProductID})
+
+			sql, args, err := exists.ToSql()
+			if err != nil {
+				return err
+			}
+
+			var one int
+			if err := tx.QueryRow(ctx, sql, args...).Scan(&one); err != nil {
+				if err == pgx.ErrNoRows {
+					return port.ErrDataNotFound
+				}
+				return err
+			}
+		}
+
+		var err error
+		movements, page, err = selectPage(ctx, tx, query, stockMovementKeyset, params,
+			func(row pgx.CollectableRow) (domain.StockMovement, error) {
+				var movement domain.StockMovement
+				err := scanStockMovement(row, &movement)
+				return movement, err
+			},
+			func(movement *domain.StockMovement) []any { return []any{movement.ID} })
+		return err
+	})
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	return movements, page, nil
+}
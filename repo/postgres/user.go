@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	//"encoding/json"
@@ -92,51 +91,61 @@ func (ur *UserRepository) GetUserByEmail(gctx *gin.Context, email string) (*doma
 	return SelectOneOK(ctx, ur.Db, query, pgx.RowToAddrOfStructByName[domain.User], ur.log)
 }
 
-// ListUsers lists all users from the database
-func (ur *UserRepository) ListUsers(gctx *gin.Context, skip, limit uint64) ([]domain.User, error) {
+// ListUsers lists a page of users from the database in id order
+func (ur *UserRepository) ListUsers(gctx *gin.Context, params PageParams) ([]domain.User, Page, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	query := psql.Select("name,email,password").
-		From("users").
-		OrderBy("id").
-		Limit(limit).
-		Offset((skip - 1) * limit)
-	//return SelectRows(ctx, ur.Db, query, pgx.RowToAddrOfStructByNameLax[domain.User], ur.log)
-
-	// sql, args, err := query.ToSql()
-	// if err != nil {
-	// 	ur.log.Debug("Logs :", err.Error())
-	// 	return nil, err
-	// }
-	//s,_:=SelectWithSecondary(ctx,ur.Db,"select",domain.User{},sql,args)
-
-	//return nil,nil
-	//return SelectRows(ctx, ur.Db, query, RowToStructByTagExp[domain.User], ur.log)
-	if ctx.Err() == context.DeadlineExceeded {
-		ur.log.Error("Context deadline exceeded")
-		return nil, errors.New("context deadline exceeded")
+	query := psql.Select("id", "name", "email", "role", "created_at", "updated_at").
+		From("users")
+
+	var users []domain.User
+	var page Page
+	err := ur.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		users, page, err = selectPage(ctx, tx, query, idKeyset("id"), params, pgx.RowToStructByNameLax[domain.User],
+			func(user *domain.User) []any { return []any{user.ID} })
+		return err
+	})
+	if err != nil {
+		return nil, Page{}, err
 	}
-	return SelectRowsTag[domain.User](ctx, ur.Db, query, ur.log, "select")
 
+	return users, page, nil
 }
 
 // UpdateUser updates a user by ID in the database
 func (ur *UserRepository) UpdateUser(gctx *gin.Context, user *domain.User) (*domain.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	name := nullString(user.Name)
+	email := nullString(user.Email)
+	password := nullString(user.Password)
+	role := nullString(string(user.Role))
+
 	query := psql.Update("users").
-		// Set("name", sq.Expr("COALESCE(?, name)", name)).
-		// Set("email", sq.Expr("COALESCE(?, email)", email)).
-		// Set("password", sq.Expr("COALESCE(?, password)", password)).
-		// Set("role", sq.Expr("COALESCE(?, role)", role)).
-		// Set("updated_at", time.Now()).
-		SetMap(generateMapFromStruct(user, "insert")).
+		Set("name", sq.Expr("COALESCE(?, name)", name)).
+		Set("email", sq.Expr("COALESCE(?, email)", email)).
+		Set("password", sq.Expr("COALESCE(?, password)", password)).
+		Set("role", sq.Expr("COALESCE(?, role)", role)).
+		Set("updated_at", time.Now()).
 		Where(sq.Eq{"id": user.ID}).
 		Suffix("RETURNING *")
-	return UpdateReturning(ctx, ur.Db, query, pgx.RowToAddrOfStructByPos[domain.User], ur.log)
+	return UpdateReturning(ctx, ur.Db, query, pgx.RowToAddrOfStructByNameLax[domain.User], ur.log)
 
 }
 
+// UpdatePassword replaces the stored password hash of a user
+func (ur *UserRepository) UpdatePassword(gctx *gin.Context, id uint64, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	query := psql.Update("users").
+		Set("password", passwordHash).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id})
+	_, err := Update(ctx, ur.Db, query, ur.log)
+	return err
+}
+
 // DeleteUser deletes a user by ID from the database
 func (ur *UserRepository) DeleteUser(gctx *gin.Context, id uint64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -146,3 +155,32 @@ func (ur *UserRepository) DeleteUser(gctx *gin.Context, id uint64) error {
 	_, err := Delete(ctx, ur.Db, query, ur.log)
 	return err
 }
+
+// CreateInitialAdmin creates user as an admin while the database has no admin and no user with its email.
+// It reports whether the user was created.
+func (ur *UserRepository) CreateInitialAdmin(ctx context.Context, user *domain.User) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	user.Role = domain.Admin
+	query := psql.Insert("users").
+		Columns("name", "email", "password", "role").
+		Select(psql.Select().
+			Column(sq.Expr("?, ?, ?, ?", user.Name, user.Email, user.Password, user.Role)).
+			Where(sq.Expr("NOT EXISTS (SELECT 1 FROM users WHERE role = ? OR email = ?)", domain.Admin, user.Email))).
+		Suffix("RETURNING id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	err = ur.Db.QueryRow(ctx, sql, args...).Scan(&user.ID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
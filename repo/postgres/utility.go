@@ -527,6 +527,28 @@ func TxRows[T any](ctx context.Context, tx pgx.Tx, builder sq.Sqlizer, scanFn pg
 	return nil
 }
 
+// scanRows runs query in tx and calls scan for every returned row
+func scanRows(ctx context.Context, tx pgx.Tx, query sq.SelectBuilder, scan func(rows pgx.Rows) error) error {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func TxExec(ctx context.Context, tx pgx.Tx, builder sq.Sqlizer, log *logger.Logger) error {
 	sql, args, err := builder.ToSql()
 	if err != nil {
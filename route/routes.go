@@ -1,10 +1,16 @@
 package route
 
 import (
+	"context"
+	"time"
+
 	"gotemplate/config"
 	handler "gotemplate/handler"
 	"gotemplate/logger"
+	"gotemplate/ratelimit"
 	repo "gotemplate/repo/postgres"
+	"gotemplate/storage"
+	"gotemplate/token"
 )
 
 //	func LoggerInit(loglevel string) *logger.Logger {
@@ -13,16 +19,48 @@ import (
 //	}
 func Routes(db *repo.DB, log *logger.Logger, cfg config.Econfig, validatorService *handler.ValidatorService) (router *handler.Router, err1 error) {
 
+	tokenMaker, err1 := token.NewJWTMaker(cfg)
+	if err1 != nil {
+		return nil, err1
+	}
+
+	limiter, err1 := ratelimit.NewLimiter(cfg)
+	if err1 != nil {
+		return nil, err1
+	}
+
+	rateLimiter, err1 := handler.NewRateLimiter(cfg, limiter)
+	if err1 != nil {
+		return nil, err1
+	}
+
 	userRepo := repo.NewUserRepository(db, log)
 
 	userHandler := handler.NewUserHandler(*userRepo, log, validatorService)
+	if err1 = userHandler.CreateInitialAdmin(context.Background(), cfg.AdminEmail(), cfg.AdminPassword()); err1 != nil {
+		return nil, err1
+	}
+
+	sessionRepo := repo.NewSessionRepository(db, log)
+
+	authHandler := handler.NewAuthHandler(*userRepo, *sessionRepo, tokenMaker, log, validatorService)
 
 	bagRepo := repo.NewBagRepository(db, log)
 
 	bagHandler := handler.NewBagHandler(*bagRepo, log, validatorService)
 
+	// Uploaded product images and payment logos
+	store, err1 := storage.NewStore(cfg)
+	if err1 != nil {
+		return nil, err1
+	}
+	images, err1 := handler.NewImageUploader(cfg, store, log)
+	if err1 != nil {
+		return nil, err1
+	}
+
 	paymentRepo := repo.NewPaymentRepository(db, log)
-	paymentHandler := handler.NewPaymentHandler(*paymentRepo, log, validatorService)
+	paymentHandler := handler.NewPaymentHandler(*paymentRepo, log, validatorService, images)
 
 	// Category
 	categoryRepo := repo.NewCategoryRepository(db, log)
@@ -30,19 +68,72 @@ func Routes(db *repo.DB, log *logger.Logger, cfg config.Econfig, validatorServic
 
 	// Product
 	productRepo := repo.NewProductRepository(db, log)
-	productHandler := handler.NewProductHandler(*productRepo, log, validatorService)
+	productHandler := handler.NewProductHandler(*productRepo, log, validatorService, images)
+
+	// Idempotency keys of order and payment creation
+	idempotencyRepo := repo.NewIdempotencyRepository(db, log)
+	idempotency, err1 := handler.NewIdempotency(*idempotencyRepo, cfg, log)
+	if err1 != nil {
+		return nil, err1
+	}
+	if cfg.IdempotencyCleanup() != "" {
+		interval, err1 := time.ParseDuration(cfg.IdempotencyCleanup())
+		if err1 != nil {
+			return nil, err1
+		}
+		go idempotencyRepo.RunCleanup(context.Background(), interval)
+	}
 
 	// Order
 	orderRepo := repo.NewOrderRepository(db, log)
-	orderHandler := handler.NewOrderHandler(*orderRepo, log, validatorService)
+	orderHandler, err1 := handler.NewOrderHandler(*orderRepo, log, validatorService, cfg)
+	if err1 != nil {
+		return nil, err1
+	}
+	receiptHandler := handler.NewReceiptHandler(*orderRepo, log, validatorService, cfg)
+	if cfg.HeldOrderCleanup() != "" {
+		interval, err1 := time.ParseDuration(cfg.HeldOrderCleanup())
+		if err1 != nil {
+			return nil, err1
+		}
+		go orderRepo.RunHeldOrderCleanup(context.Background(), interval)
+	}
+
+	// Report
+	reportRepo := repo.NewReportRepository(db, log)
+	reportHandler, err1 := handler.NewReportHandler(*reportRepo, log, validatorService, cfg)
+	if err1 != nil {
+		return nil, err1
+	}
+
+	// Shift
+	shiftRepo := repo.NewShiftRepository(db, log)
+	shiftHandler := handler.NewShiftHandler(*shiftRepo, log, validatorService, cfg)
+
+	// Promotion
+	promotionRepo := repo.NewPromotionRepository(db, log)
+	promotionHandler := handler.NewPromotionHandler(*promotionRepo, log, validatorService)
+
+	// Stock
+	stockRepo := repo.NewStockRepository(db, log)
+	stockHandler := handler.NewStockHandler(*stockRepo, log, validatorService)
 
 	router, err1 = handler.NewRouter(
 		cfg,
+		tokenMaker,
+		rateLimiter,
+		idempotency,
+		*authHandler,
 		*userHandler,
 		*paymentHandler,
 		*categoryHandler,
 		*productHandler,
 		*orderHandler,
+		*receiptHandler,
+		*reportHandler,
+		*shiftHandler,
+		*promotionHandler,
+		*stockHandler,
 		*bagHandler,
 	)
 	return router, err1
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"gotemplate/core/port"
+)
+
+// maxImagePixels bounds the decoded size of uploaded images, whose compressed size says little about it.
+// Decoding a 12 megapixel image takes up to 48MB, enough for any product photo.
+const maxImagePixels = 12_000_000
+
+// imageExtensions maps the accepted image content types to the extension of their files
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is an uploaded image with the thumbnail made of it. Data is the upload as it was sent.
+type Image struct {
+	Data          []byte
+	ContentType   string
+	Ext           string
+	Thumbnail     []byte
+	ThumbnailType string
+	ThumbnailExt  string
+}
+
+// ProcessImage checks that data is a JPEG, PNG or GIF image by its content and decodes it
+// to make a thumbnail fitting in a square of thumbSize pixels. Images smaller than that are not enlarged.
+// Thumbnails of JPEG images are JPEG, the others are PNG to keep their transparency.
+func ProcessImage(data []byte, thumbSize int) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, port.ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, port.ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, port.ErrUnsupportedImage
+	}
+	// Checked from the header, before decoding allocates the pixels
+	if int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return nil, port.ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, port.ErrUnsupportedImage
+	}
+
+	thumb := Thumbnail(src, thumbSize)
+	img := &Image{
+		Data:        data,
+		ContentType: contentType,
+		Ext:         ext,
+	}
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		img.ThumbnailType, img.ThumbnailExt = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(&buf, thumb)
+		img.ThumbnailType, img.ThumbnailExt = "image/png", ".png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	img.Thumbnail = buf.Bytes()
+
+	return img, nil
+}
+
+// Thumbnail scales src down to fit in a square of size pixels keeping its aspect ratio,
+// averaging the source pixels each thumbnail pixel covers. The source is converted a strip of rows
+// at a time, so that scaling takes little memory beyond the thumbnail.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+
+	dw, dh := sw, sh
+	if size > 0 && (sw > size || sh > size) {
+		if sw >= sh {
+			dw, dh = size, max(1, sh*size/sw)
+		} else {
+			dw, dh = max(1, sw*size/sh), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	if dw == sw && dh == sh {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	// Premultiplied RGBA pixels, which average without darkening transparent edges
+	strip := image.NewRGBA(image.Rect(0, 0, sw, (sh+dh-1)/dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		rows := image.Rect(0, 0, sw, y1-y0)
+		draw.Draw(strip, rows, src, image.Pt(bounds.Min.X, bounds.Min.Y+y0), draw.Src)
+
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+
+			var sum [4]int
+			for sy := 0; sy < y1-y0; sy++ {
+				row := strip.Pix[sy*strip.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			i := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
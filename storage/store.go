@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"gotemplate/config"
+)
+
+// Backend names accepted by the StorageBackend config value
+const (
+	LocalBackend = "local"
+	S3Backend    = "s3"
+)
+
+// errInvalidKey is returned when a key is empty or would leave the root of a store
+var errInvalidKey = errors.New("invalid storage key")
+
+// Store keeps uploaded files under keys, as in products/1/3f2a9c.jpg, and hands out the URLs they are served from
+type Store interface {
+	// Put stores data under key, returning the URL of the stored file
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+	// Delete removes the file served from url. URLs the store did not hand out are ignored.
+	Delete(ctx context.Context, url string) error
+}
+
+// NewStore creates the store selected by the StorageBackend config value
+func NewStore(cfg config.Econfig) (Store, error) {
+	switch cfg.StorageBackend() {
+	case "", LocalBackend:
+		return NewLocalStore(cfg.StorageLocalDir(), cfg.StoragePublicURL())
+	case S3Backend:
+		return NewS3Store(S3Options{
+			Endpoint:  cfg.StorageS3Endpoint(),
+			Region:    cfg.StorageS3Region(),
+			Bucket:    cfg.StorageS3Bucket(),
+			AccessKey: cfg.StorageS3AccessKey(),
+			SecretKey: cfg.StorageS3SecretKey(),
+			PublicURL: cfg.StoragePublicURL(),
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend())
+	}
+}
+
+// checkKey checks that key is a relative slash separated path staying inside the root of a store
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("%w %q", errInvalidKey, key)
+	}
+	return nil
+}
+
+// keyOf returns the key of the file served from rawURL under base, false when the URL is not under base
+func keyOf(base, rawURL string) (string, bool) {
+	rest, ok := strings.CutPrefix(rawURL, base+"/")
+	if !ok {
+		return "", false
+	}
+	key, err := url.PathUnescape(rest)
+	if err != nil {
+		return "", false
+	}
+	if err := checkKey(key); err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// escapeKey escapes the segments of key for use in a URL path
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
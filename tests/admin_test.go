@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"gotemplate/supertest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sendDiagnosticsRequest(t *testing.T, url string, bearer string, handleFunc func(req *http.Request, rr *httptest.ResponseRecorder)) {
+	test := supertest.NewSuperTest(router, t)
+	test.Get(url)
+	test.Send(nil)
+	if bearer != "" {
+		test.Set("Authorization", "Bearer "+bearer)
+	}
+	test.End(handleFunc)
+}
+
+func TestDiagnosticsAdmin(t *testing.T) {
+	for _, url := range []string{"/swagger/index.html", "/debug/pprof/"} {
+		sendDiagnosticsRequest(t, url, authToken, func(req *http.Request, rr *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusOK, rr.Code, url)
+		})
+	}
+}
+
+func TestDiagnosticsUnauthenticatedError(t *testing.T) {
+	for _, url := range []string{"/swagger/index.html", "/debug/pprof/"} {
+		sendDiagnosticsRequest(t, url, "", func(req *http.Request, rr *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, url)
+		})
+	}
+}
+
+func TestDiagnosticsCashierForbiddenError(t *testing.T) {
+	for _, url := range []string{"/swagger/index.html", "/debug/pprof/"} {
+		sendDiagnosticsRequest(t, url, cashierToken, func(req *http.Request, rr *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusForbidden, rr.Code, url)
+		})
+	}
+}
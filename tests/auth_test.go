@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"gotemplate/supertest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginInvalidCredentialsError(t *testing.T) {
+	test := supertest.NewSuperTest(router, t)
+	url := "/v1/auth/login"
+	payload := gin.H{
+		"email":    "nouser@example.com",
+		"password": "wrongpassword",
+	}
+
+	sendAndAssertErrorRequest(t, test, url, payload, "", "post", http.StatusUnauthorized)
+}
+
+func TestAuthEmptyHeaderError(t *testing.T) {
+	test := supertest.NewSuperTest(router, t)
+	test.Get("/v1/users/1")
+	test.Send(nil)
+	test.Set("Content-Type", "application/json")
+
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		Negativeassertions(t, rr, "", "get")
+	})
+}
+
+func TestAuthInvalidTokenError(t *testing.T) {
+	test := supertest.NewSuperTest(router, t)
+	test.Get("/v1/users/1")
+	test.Send(nil)
+	test.Set("Content-Type", "application/json")
+	test.Set("Authorization", "Bearer invalid.token.value")
+
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		Negativeassertions(t, rr, "", "get")
+	})
+}
+
+func TestCashierForbiddenError(t *testing.T) {
+	test := supertest.NewSuperTest(router, t)
+	test.Get("/v1/users/1")
+	test.Send(nil)
+	test.Set("Content-Type", "application/json")
+	test.Set("Authorization", "Bearer "+cashierToken)
+
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		Negativeassertions(t, rr, "", "get")
+	})
+}
+
+func TestRefreshInvalidTokenError(t *testing.T) {
+	test := supertest.NewSuperTest(router, t)
+	url := "/v1/auth/refresh"
+	payload := gin.H{
+		"refresh_token": "not-a-refresh-token",
+	}
+
+	sendAndAssertErrorRequest(t, test, url, payload, "", "post", http.StatusUnauthorized)
+}
+
+func TestUserRegisterRequiresAdmin(t *testing.T) {
+	payload := gin.H{
+		"email":    "self-registered@example.com",
+		"password": "fghjklhjgf",
+		"name":     "Self Registered",
+		"check":    10,
+	}
+
+	for token, code := range map[string]int{"": http.StatusUnauthorized, cashierToken: http.StatusForbidden} {
+		test := supertest.NewSuperTest(router, t)
+		test.Post("/v1/users/")
+		test.Send(payload)
+		test.Set("Content-Type", "application/json")
+		if token != "" {
+			test.Set("Authorization", "Bearer "+token)
+		}
+
+		test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+			assert.Equal(t, code, rr.Code)
+			Negativeassertions(t, rr, "", "post")
+		})
+	}
+}
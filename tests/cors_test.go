@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"gotemplate/supertest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sendPreflightRequest(t *testing.T, origin string, handleFunc func(req *http.Request, rr *httptest.ResponseRecorder)) {
+	test := supertest.NewSuperTest(router, t)
+	test.Options("/v1/products/")
+	test.Send(nil)
+	test.Set("Origin", origin)
+	test.Set("Access-Control-Request-Method", http.MethodGet)
+	test.End(handleFunc)
+}
+
+func TestCorsAllowedOrigin(t *testing.T) {
+	sendPreflightRequest(t, "http://localhost:3000", func(req *http.Request, rr *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "http://localhost:3000", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "3600", rr.Header().Get("Access-Control-Max-Age"))
+	})
+}
+
+func TestCorsWildcardSubdomain(t *testing.T) {
+	sendPreflightRequest(t, "https://shop.example.com", func(req *http.Request, rr *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "https://shop.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestCorsDisallowedOriginError(t *testing.T) {
+	for _, origin := range []string{"https://example.com", "http://shop.example.com", "https://evil.com"} {
+		sendPreflightRequest(t, origin, func(req *http.Request, rr *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+			assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestCorsExposesPaginationLinks(t *testing.T) {
+	// The test config leaves HttpExposedHeaders empty, so the defaults apply
+	test := supertest.NewSuperTest(router, t)
+	test.Get("/v1/products/?limit=1")
+	test.Send(nil)
+	test.Set("Origin", "http://localhost:3000")
+	test.Set("Authorization", "Bearer "+authToken)
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Header().Get("Access-Control-Expose-Headers"), "Link")
+	})
+}
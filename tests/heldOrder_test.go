@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHeldOrderResponse holds the held order fields checked by the held order tests
+type testHeldOrderResponse struct {
+	ID           uint64 `json:"id"`
+	Terminal     string `json:"terminal"`
+	CustomerName string `json:"customer_name"`
+	Products     []struct {
+		ProductID uint64 `json:"product_id"`
+		Quantity  int64  `json:"qty"`
+	} `json:"products"`
+	Cart *struct {
+		TotalPrice float64 `json:"total_price"`
+	} `json:"cart"`
+}
+
+func TestHoldAndCompleteOrder(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1500, 5)
+
+	var held testHeldOrderResponse
+	code := sendJSONRequest(t, http.MethodPost, "/v1/held-orders/", gin.H{
+		"terminal":      "held-test-till",
+		"customer_name": "Held Doe",
+		"products":      []gin.H{{"product_id": productID, "qty": 2}},
+	}, &held)
+	require.Equal(t, http.StatusOK, code)
+	require.NotNil(t, held.Cart)
+	assert.Equal(t, 3000.0, held.Cart.TotalPrice)
+
+	// Holding reserves no stock
+	assert.Equal(t, int64(5), productStock(t, productID))
+
+	var list struct {
+		HeldOrders []testHeldOrderResponse `json:"held_orders"`
+	}
+	code = sendJSONRequest(t, http.MethodGet, "/v1/held-orders/?limit=5&terminal=held-test-till", nil, &list)
+	require.Equal(t, http.StatusOK, code)
+	require.NotEmpty(t, list.HeldOrders)
+	assert.Equal(t, held.ID, list.HeldOrders[0].ID)
+	assert.Nil(t, list.HeldOrders[0].Cart)
+
+	// Resume, add a unit and hold again
+	var resumed testHeldOrderResponse
+	code = sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/held-orders/%d", held.ID), nil, &resumed)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Held Doe", resumed.CustomerName)
+	require.Len(t, resumed.Products, 1)
+
+	code = sendJSONRequest(t, http.MethodPut, fmt.Sprintf("/v1/held-orders/%d", held.ID), gin.H{
+		"terminal":      "held-test-till",
+		"customer_name": "Held Doe",
+		"products":      []gin.H{{"product_id": productID, "qty": 3}},
+	}, &resumed)
+	require.Equal(t, http.StatusOK, code)
+	require.NotNil(t, resumed.Cart)
+	assert.Equal(t, 4500.0, resumed.Cart.TotalPrice)
+
+	var order testOrderResponse
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/held-orders/%d/complete", held.ID), gin.H{
+		"payment_id": paymentID,
+		"total_paid": 5000,
+	}, &order)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 4500.0, order.TotalPrice)
+	assert.Equal(t, 500.0, order.TotalReturn)
+	assert.Equal(t, int64(2), productStock(t, productID))
+
+	// A completed held order is gone
+	code = sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/held-orders/%d", held.ID), nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/held-orders/%d/complete", held.ID), gin.H{
+		"payment_id": paymentID,
+		"total_paid": 5000,
+	}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCompleteHeldOrderInsufficientStock(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 100, 1)
+
+	var held testHeldOrderResponse
+	code := sendJSONRequest(t, http.MethodPost, "/v1/held-orders/", gin.H{
+		"products": []gin.H{{"product_id": productID, "qty": 3}},
+	}, &held)
+	require.Equal(t, http.StatusOK, code)
+
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/held-orders/%d/complete", held.ID), gin.H{
+		"payment_id": paymentID,
+		"total_paid": 1000,
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// The held order is kept when it cannot be completed
+	code = sendJSONRequest(t, http.MethodDelete, fmt.Sprintf("/v1/held-orders/%d", held.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestHoldOrderUnknownProduct(t *testing.T) {
+	code := sendJSONRequest(t, http.MethodPost, "/v1/held-orders/", gin.H{
+		"products": []gin.H{{"product_id": 999999999, "qty": 1}},
+	}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCompleteFullyDiscountedHeldOrder(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 5)
+	createTestPromotion(t, gin.H{
+		"name":      "Free held sample",
+		"kind":      "percentage",
+		"scope":     "product",
+		"target_id": productID,
+		"value":     100,
+	})
+
+	var held testHeldOrderResponse
+	code := sendJSONRequest(t, http.MethodPost, "/v1/held-orders/", gin.H{
+		"products": []gin.H{{"product_id": productID, "qty": 1}},
+	}, &held)
+	require.Equal(t, http.StatusOK, code)
+
+	var order testOrderResponse
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/held-orders/%d/complete", held.ID), gin.H{
+		"payment_id": paymentID,
+		"total_paid": 0,
+	}, &order)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 0.0, order.TotalPrice)
+	assert.Equal(t, int64(4), productStock(t, productID))
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"fmt"
+	"gotemplate/supertest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendIdempotentRequest posts payload with an Idempotency-Key and returns the recorded response
+func sendIdempotentRequest(t *testing.T, url, key string, payload gin.H) *httptest.ResponseRecorder {
+	test := supertest.NewSuperTest(router, t)
+	test.Post(url)
+	test.Send(payload)
+	test.Set("Content-Type", "application/json")
+	test.Set("Authorization", "Bearer "+authToken)
+	test.Set("Idempotency-Key", key)
+
+	var recorder *httptest.ResponseRecorder
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		recorder = rr
+	})
+	return recorder
+}
+
+func TestCreateOrderIdempotencyReplay(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 10)
+	key := fmt.Sprintf("order-%d", time.Now().UnixNano())
+	payload := gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "John Doe",
+		"total_paid":    2000,
+		"products":      []gin.H{{"product_id": productID, "qty": 2}},
+	}
+
+	first := sendIdempotentRequest(t, "/v1/orders/", key, payload)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	replay := sendIdempotentRequest(t, "/v1/orders/", key, payload)
+	require.Equal(t, http.StatusOK, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+
+	assert.Equal(t, int64(8), productStock(t, productID))
+}
+
+func TestCreateOrderIdempotencyMismatchError(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 10)
+	key := fmt.Sprintf("order-%d", time.Now().UnixNano())
+	payload := gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "John Doe",
+		"total_paid":    2000,
+		"products":      []gin.H{{"product_id": productID, "qty": 2}},
+	}
+
+	first := sendIdempotentRequest(t, "/v1/orders/", key, payload)
+	require.Equal(t, http.StatusOK, first.Code)
+
+	payload["products"] = []gin.H{{"product_id": productID, "qty": 1}}
+	mismatch := sendIdempotentRequest(t, "/v1/orders/", key, payload)
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatch.Code)
+	Negativeassertions(t, mismatch, "", "post")
+
+	assert.Equal(t, int64(8), productStock(t, productID))
+}
+
+func TestCreatePaymentIdempotencyReplay(t *testing.T) {
+	key := fmt.Sprintf("payment-%d", time.Now().UnixNano())
+	payload := gin.H{"name": "Idempotent cash", "type": "CASH"}
+
+	first := sendIdempotentRequest(t, "/v1/payments/", key, payload)
+	require.Equal(t, http.StatusOK, first.Code)
+
+	replay := sendIdempotentRequest(t, "/v1/payments/", key, payload)
+	require.Equal(t, http.StatusOK, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+}
+
+func TestCompleteHeldOrderIdempotencyKeyReuseError(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 10)
+	key := fmt.Sprintf("complete-%d", time.Now().UnixNano())
+	payload := gin.H{"payment_id": paymentID, "total_paid": 1000}
+
+	var first, second testHeldOrderResponse
+	for _, held := range []*testHeldOrderResponse{&first, &second} {
+		code := sendJSONRequest(t, http.MethodPost, "/v1/held-orders/", gin.H{
+			"products": []gin.H{{"product_id": productID, "qty": 1}},
+		}, held)
+		require.Equal(t, http.StatusOK, code)
+	}
+
+	completed := sendIdempotentRequest(t, fmt.Sprintf("/v1/held-orders/%d/complete", first.ID), key, payload)
+	require.Equal(t, http.StatusOK, completed.Code)
+
+	// The same key and body on another held order is not a retry of the first request
+	reused := sendIdempotentRequest(t, fmt.Sprintf("/v1/held-orders/%d/complete", second.ID), key, payload)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Empty(t, reused.Header().Get("Idempotent-Replayed"))
+
+	assert.Equal(t, int64(9), productStock(t, productID))
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/held-orders/%d", second.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUploadedProduct holds the product fields checked by the image upload tests
+type testUploadedProduct struct {
+	ID        uint64 `json:"id"`
+	Image     string `json:"image"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+// testPNG encodes a w x h PNG image
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 255})
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// uploadFile posts data as the file of field of a multipart form, returning the status code
+func uploadFile(t *testing.T, url, field string, data []byte, out any) int {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile(field, "upload.bin")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, url, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+authToken)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code == http.StatusOK && out != nil {
+		var response struct {
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.NoError(t, json.Unmarshal(response.Data, out))
+	}
+	return rr.Code
+}
+
+// getFile gets a file served by the router, returning the status code and the body
+func getFile(url string) (int, []byte) {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, url, nil))
+	return rr.Code, rr.Body.Bytes()
+}
+
+func TestUploadProductImage(t *testing.T) {
+	_, productID := createOrderFixtures(t, 1000, 10)
+	url := fmt.Sprintf("/v1/products/%d/image", productID)
+
+	var product testUploadedProduct
+	require.Equal(t, http.StatusOK, uploadFile(t, url, "image", testPNG(t, 64, 32), &product))
+	assert.True(t, strings.HasPrefix(product.Image, fmt.Sprintf("/uploads/products/%d/", productID)), product.Image)
+	assert.True(t, strings.HasSuffix(product.Image, ".png"))
+	require.NotEmpty(t, product.Thumbnail)
+
+	// The stored URLs are written back to the product
+	var fetched testUploadedProduct
+	require.Equal(t, http.StatusOK, sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/products/%d", productID), nil, &fetched))
+	assert.Equal(t, product, fetched)
+
+	code, data := getFile(product.Thumbnail)
+	require.Equal(t, http.StatusOK, code)
+	thumb, err := png.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 32, thumb.Width)
+	assert.Equal(t, 16, thumb.Height)
+
+	// A new upload replaces the files of the previous one
+	var jpegData bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpegData, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil))
+	var replaced testUploadedProduct
+	require.Equal(t, http.StatusOK, uploadFile(t, url, "image", jpegData.Bytes(), &replaced))
+	assert.True(t, strings.HasSuffix(replaced.Image, ".jpg"))
+	assert.NotEqual(t, product.Image, replaced.Image)
+	code, _ = getFile(product.Image)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = getFile(replaced.Image)
+	assert.Equal(t, http.StatusOK, code)
+
+	// Setting the image as a URL drops the thumbnail of the upload
+	code = sendJSONRequest(t, http.MethodPut, fmt.Sprintf("/v1/products/%d", productID), gin.H{"image": "https://example.com/other.png"}, &fetched)
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, fetched.Thumbnail)
+}
+
+func TestUploadPaymentLogo(t *testing.T) {
+	paymentID, _ := createOrderFixtures(t, 1000, 10)
+
+	var payment struct {
+		Logo          string `json:"logo"`
+		LogoThumbnail string `json:"logo_thumbnail"`
+	}
+	code := uploadFile(t, fmt.Sprintf("/v1/payments/%d/logo", paymentID), "logo", testPNG(t, 10, 10), &payment)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, strings.HasPrefix(payment.Logo, fmt.Sprintf("/uploads/payments/%d/", paymentID)), payment.Logo)
+	assert.NotEmpty(t, payment.LogoThumbnail)
+
+	code, _ = getFile(payment.Logo)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestUploadImageError(t *testing.T) {
+	_, productID := createOrderFixtures(t, 1000, 10)
+	url := fmt.Sprintf("/v1/products/%d/image", productID)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, uploadFile(t, url, "image", []byte("%PDF-1.4 not an image"), nil))
+	// The test config limits uploads to 64 KiB
+	assert.Equal(t, http.StatusRequestEntityTooLarge, uploadFile(t, url, "image", bytes.Repeat([]byte{0}, 100<<10), nil))
+	assert.Equal(t, http.StatusBadRequest, uploadFile(t, url, "file", testPNG(t, 10, 10), nil))
+	assert.Equal(t, http.StatusNotFound, uploadFile(t, "/v1/products/999999999/image", "image", testPNG(t, 10, 10), nil))
+}
@@ -0,0 +1,450 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"gotemplate/supertest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testOrderResponse holds the order fields checked by the order tests
+type testOrderResponse struct {
+	ID          uint64  `json:"id"`
+	TotalPrice  float64 `json:"total_price"`
+	TotalPaid   float64 `json:"total_paid"`
+	TotalReturn float64 `json:"total_return"`
+	Products    []struct {
+		ID              uint64  `json:"id"`
+		ProductID       uint64  `json:"product_id"`
+		Quantity        int64   `json:"qty"`
+		Price           float64 `json:"price"`
+		TotalFinalPrice float64 `json:"total_final_price"`
+	} `json:"products"`
+}
+
+// sendJSONRequest sends an admin request and decodes the data of the response into data
+func sendJSONRequest(t *testing.T, method, url string, payload gin.H, data any) int {
+	test := supertest.NewSuperTest(router, t)
+	switch method {
+	case http.MethodPost:
+		test.Post(url)
+	case http.MethodPut:
+		test.Put(url)
+	case http.MethodDelete:
+		test.Delete(url)
+	default:
+		test.Get(url)
+	}
+	test.Send(payload)
+	test.Set("Content-Type", "application/json")
+	test.Set("Authorization", "Bearer "+authToken)
+
+	var code int
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		code = rr.Code
+		if rr.Code != http.StatusOK || data == nil {
+			return
+		}
+
+		var response struct {
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.NoError(t, json.Unmarshal(response.Data, data))
+	})
+	return code
+}
+
+// createOrderFixtures creates a payment and a product with the given price and stock
+func createOrderFixtures(t *testing.T, price float64, stock int64) (paymentID, productID uint64) {
+	var created struct {
+		ID uint64 `json:"id"`
+	}
+
+	code := sendJSONRequest(t, http.MethodPost, "/v1/categories/", gin.H{"name": "Order test category"}, &created)
+	require.Equal(t, http.StatusOK, code)
+	categoryID := created.ID
+
+	code = sendJSONRequest(t, http.MethodPost, "/v1/payments/", gin.H{"name": "Order test cash", "type": "CASH"}, &created)
+	require.Equal(t, http.StatusOK, code)
+	paymentID = created.ID
+
+	code = sendJSONRequest(t, http.MethodPost, "/v1/products/", gin.H{
+		"category_id": categoryID,
+		"name":        "Order test product",
+		"image":       "https://example.com/order-test.png",
+		"price":       price,
+		"stock":       stock,
+	}, &created)
+	require.Equal(t, http.StatusOK, code)
+	productID = created.ID
+
+	return paymentID, productID
+}
+
+// productStock reads the current stock of a product
+func productStock(t *testing.T, productID uint64) int64 {
+	var product struct {
+		Stock int64 `json:"stock"`
+	}
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/products/%d", productID), nil, &product)
+	require.Equal(t, http.StatusOK, code)
+	return product.Stock
+}
+
+func TestCreateOrderPricing(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 2500, 10)
+
+	var order testOrderResponse
+	code := sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "John Doe",
+		"total_paid":    10000,
+		"total_price":   1,
+		"products":      []gin.H{{"product_id": productID, "qty": 3}},
+	}, &order)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 7500.0, order.TotalPrice)
+	assert.Equal(t, 10000.0, order.TotalPaid)
+	assert.Equal(t, 2500.0, order.TotalReturn)
+	require.Len(t, order.Products, 1)
+	assert.Equal(t, 2500.0, order.Products[0].Price)
+	assert.Equal(t, 7500.0, order.Products[0].TotalFinalPrice)
+	assert.Equal(t, int64(7), productStock(t, productID))
+}
+
+func TestCreateOrderInsufficientPaymentError(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 2500, 10)
+	test := supertest.NewSuperTest(router, t)
+
+	payload := gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "John Doe",
+		"total_paid":    5000,
+		"products":      []gin.H{{"product_id": productID, "qty": 3}},
+	}
+
+	sendAndAssertErrorRequest(t, test, "/v1/orders/", payload, "", "post", http.StatusBadRequest)
+	assert.Equal(t, int64(10), productStock(t, productID))
+}
+
+func TestCreateOrderInsufficientStockError(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 2500, 2)
+	test := supertest.NewSuperTest(router, t)
+
+	payload := gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "John Doe",
+		"total_paid":    100000,
+		"products":      []gin.H{{"product_id": productID, "qty": 3}},
+	}
+
+	sendAndAssertErrorRequest(t, test, "/v1/orders/", payload, "", "post", http.StatusBadRequest)
+	assert.Equal(t, int64(2), productStock(t, productID))
+}
+
+func TestCreateOrderConcurrentLastItems(t *testing.T) {
+	const stock, buyers = 5, 12
+	paymentID, productID := createOrderFixtures(t, 1000, stock)
+
+	codes := make(chan int, buyers)
+	var wg sync.WaitGroup
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+				"payment_id":    paymentID,
+				"customer_name": "John Doe",
+				"total_paid":    1000,
+				"products":      []gin.H{{"product_id": productID, "qty": 1}},
+			}, nil)
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	sold, rejected := 0, 0
+	for code := range codes {
+		switch code {
+		case http.StatusOK:
+			sold++
+		case http.StatusBadRequest:
+			rejected++
+		default:
+			t.Errorf("unexpected status code %d", code)
+		}
+	}
+
+	assert.Equal(t, stock, sold)
+	assert.Equal(t, buyers-stock, rejected)
+	assert.Equal(t, int64(0), productStock(t, productID))
+}
+
+// createTestOrder creates a paid order for qty items of a new product with the given stock
+func createTestOrder(t *testing.T, stock, qty int64) (order testOrderResponse, productID uint64) {
+	paymentID, productID := createOrderFixtures(t, 1000, stock)
+
+	code := sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "John Doe",
+		"total_paid":    float64(qty) * 1000,
+		"products":      []gin.H{{"product_id": productID, "qty": qty}},
+	}, &order)
+	require.Equal(t, http.StatusOK, code)
+
+	return order, productID
+}
+
+func TestCancelOrderRestoresStock(t *testing.T) {
+	order, productID := createTestOrder(t, 10, 4)
+	require.Equal(t, int64(6), productStock(t, productID))
+
+	var cancelled struct {
+		Status       string `json:"status"`
+		CancelledBy  uint64 `json:"cancelled_by"`
+		CancelReason string `json:"cancel_reason"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/orders/%d/cancel", order.ID), gin.H{"reason": "Customer changed their mind"}, &cancelled)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	assert.Equal(t, uint64(1), cancelled.CancelledBy)
+	assert.Equal(t, "Customer changed their mind", cancelled.CancelReason)
+	assert.Equal(t, int64(10), productStock(t, productID))
+
+	test := supertest.NewSuperTest(router, t)
+	sendAndAssertErrorRequest(t, test, fmt.Sprintf("/v1/orders/%d/cancel", order.ID), gin.H{"reason": "again"}, "", "post", http.StatusConflict)
+	assert.Equal(t, int64(10), productStock(t, productID))
+}
+
+func TestOrderOfAnotherCashierForbidden(t *testing.T) {
+	order, productID := createTestOrder(t, 10, 4)
+
+	for path, payload := range map[string]gin.H{
+		fmt.Sprintf("/v1/orders/%d/cancel", order.ID):  {"reason": "Not my order"},
+		fmt.Sprintf("/v1/orders/%d/refunds", order.ID): {"reason": "Not my order"},
+	} {
+		test := supertest.NewSuperTest(router, t)
+		test.Post(path)
+		test.Send(payload)
+		test.Set("Content-Type", "application/json")
+		test.Set("Authorization", "Bearer "+cashierToken)
+
+		test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusForbidden, rr.Code, path)
+		})
+	}
+
+	// The order was taken by the admin and is left untouched
+	assert.Equal(t, int64(6), productStock(t, productID))
+}
+
+func TestRefundOrderPartiallyThenFully(t *testing.T) {
+	order, productID := createTestOrder(t, 10, 4)
+	lineID := order.Products[0].ID
+
+	var refund struct {
+		Amount float64 `json:"amount"`
+		Order  struct {
+			Status string `json:"status"`
+		} `json:"order"`
+	}
+	url := fmt.Sprintf("/v1/orders/%d/refunds", order.ID)
+
+	code := sendJSONRequest(t, http.MethodPost, url, gin.H{
+		"reason":   "Damaged item",
+		"products": []gin.H{{"order_product_id": lineID, "qty": 1}},
+	}, &refund)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1000.0, refund.Amount)
+	assert.Equal(t, "partially_refunded", refund.Order.Status)
+	assert.Equal(t, int64(7), productStock(t, productID))
+
+	test := supertest.NewSuperTest(router, t)
+	sendAndAssertErrorRequest(t, test, url, gin.H{
+		"reason":   "Too many",
+		"products": []gin.H{{"order_product_id": lineID, "qty": 4}},
+	}, "", "post", http.StatusBadRequest)
+
+	code = sendJSONRequest(t, http.MethodPost, url, gin.H{"reason": "Returned the rest"}, &refund)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 3000.0, refund.Amount)
+	assert.Equal(t, "refunded", refund.Order.Status)
+	assert.Equal(t, int64(10), productStock(t, productID))
+
+	test = supertest.NewSuperTest(router, t)
+	sendAndAssertErrorRequest(t, test, fmt.Sprintf("/v1/orders/%d/cancel", order.ID), gin.H{"reason": "too late"}, "", "post", http.StatusConflict)
+}
+
+// testHydratedOrder holds the referenced records of an order response
+type testHydratedOrder struct {
+	ID          uint64 `json:"id"`
+	PaymentType struct {
+		ID   uint64 `json:"id"`
+		Type string `json:"type"`
+	} `json:"payment_type"`
+	Cashier struct {
+		ID   uint64 `json:"id"`
+		Role string `json:"role"`
+	} `json:"cashier"`
+	Products []struct {
+		Product struct {
+			ID       uint64 `json:"id"`
+			Name     string `json:"name"`
+			Category struct {
+				ID   uint64 `json:"id"`
+				Name string `json:"name"`
+			} `json:"category"`
+		} `json:"product"`
+	} `json:"products"`
+}
+
+// assertHydrated checks that the payment, the cashier and every product with its category are loaded
+func assertHydrated(t *testing.T, order testHydratedOrder, productID uint64) {
+	assert.NotZero(t, order.PaymentType.ID)
+	assert.Equal(t, "CASH", order.PaymentType.Type)
+	assert.Equal(t, uint64(1), order.Cashier.ID)
+	assert.Equal(t, "admin", order.Cashier.Role)
+	require.NotEmpty(t, order.Products)
+	for _, line := range order.Products {
+		assert.Equal(t, productID, line.Product.ID)
+		assert.Equal(t, "Order test product", line.Product.Name)
+		assert.NotZero(t, line.Product.Category.ID)
+		assert.Equal(t, "Order test category", line.Product.Category.Name)
+	}
+}
+
+func TestGetOrderHydrated(t *testing.T) {
+	created, productID := createTestOrder(t, 10, 2)
+
+	var order testHydratedOrder
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/orders/%d", created.ID), nil, &order)
+
+	require.Equal(t, http.StatusOK, code)
+	assertHydrated(t, order, productID)
+}
+
+func TestListOrdersHydrated(t *testing.T) {
+	created, productID := createTestOrder(t, 10, 1)
+
+	var list struct {
+		Meta struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"meta"`
+		Orders []testHydratedOrder `json:"orders"`
+	}
+	found := false
+	for cursor := ""; !found; cursor = list.Meta.NextCursor {
+		list.Meta.NextCursor = ""
+		code := sendJSONRequest(t, http.MethodGet, "/v1/orders/?limit=100&cursor="+cursor, nil, &list)
+		require.Equal(t, http.StatusOK, code)
+		require.NotEmpty(t, list.Orders, "order %d not listed", created.ID)
+
+		for _, order := range list.Orders {
+			if order.ID == created.ID {
+				assertHydrated(t, order, productID)
+				found = true
+			}
+		}
+		require.True(t, found || list.Meta.NextCursor != "", "order %d not listed", created.ID)
+	}
+}
+
+// searchOrders lists the first page of orders matching query
+func searchOrders(t *testing.T, query string) []testOrderResponse {
+	var list struct {
+		Orders []testOrderResponse `json:"orders"`
+	}
+	code := sendJSONRequest(t, http.MethodGet, "/v1/orders/?limit=100&"+query, nil, &list)
+	require.Equal(t, http.StatusOK, code)
+	return list.Orders
+}
+
+func TestListOrdersFilters(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 100)
+	customer := fmt.Sprintf("Search_%d", time.Now().UnixNano())
+
+	var orders []testOrderResponse
+	for _, qty := range []int64{1, 3, 5} {
+		var order testOrderResponse
+		code := sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+			"payment_id":    paymentID,
+			"customer_name": customer + " Doe",
+			"total_paid":    float64(qty) * 1000,
+			"products":      []gin.H{{"product_id": productID, "qty": qty}},
+		}, &order)
+		require.Equal(t, http.StatusOK, code)
+		orders = append(orders, order)
+	}
+
+	name := url.QueryEscape(strings.ToLower(customer))
+	found := searchOrders(t, "customer_name="+name+"&sort=-total_price")
+	require.Len(t, found, 3)
+	assert.Equal(t, []uint64{orders[2].ID, orders[1].ID, orders[0].ID}, []uint64{found[0].ID, found[1].ID, found[2].ID})
+
+	found = searchOrders(t, "customer_name="+name+"&min_total=2000&max_total=3000")
+	require.Len(t, found, 1)
+	assert.Equal(t, orders[1].ID, found[0].ID)
+
+	found = searchOrders(t, fmt.Sprintf("payment_id=%d&user_id=1&sort=created_at", paymentID))
+	require.Len(t, found, 3)
+	assert.Equal(t, orders[0].ID, found[0].ID)
+
+	from := url.QueryEscape(time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))
+	to := url.QueryEscape(time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	found = searchOrders(t, "customer_name="+name+"&from="+from+"&to="+to)
+	assert.Len(t, found, 3)
+
+	found = searchOrders(t, "customer_name="+name+"&to="+from)
+	assert.Empty(t, found)
+
+	var receipt testReceiptResponse
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/orders/%d/receipt", orders[0].ID), nil, &receipt)
+	require.Equal(t, http.StatusOK, code)
+	found = searchOrders(t, "receipt_code="+strings.ToUpper(receipt.ReceiptCode[:13]))
+	require.Len(t, found, 1)
+	assert.Equal(t, orders[0].ID, found[0].ID)
+
+	// LIKE wildcards in the customer name are matched literally
+	assert.Empty(t, searchOrders(t, "customer_name="+url.QueryEscape("Search%Doe")))
+}
+
+func TestListOrdersFilterValidationError(t *testing.T) {
+	for _, query := range []string{
+		"sort=price",
+		"from=yesterday",
+		"min_total=-1",
+		"from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z",
+		"min_total=500&max_total=100",
+	} {
+		code := sendJSONRequest(t, http.MethodGet, "/v1/orders/?limit=10&"+query, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestListOrdersInvertedRangeErrorCodes(t *testing.T) {
+	test := supertest.NewSuperTest(router, t)
+	test.Get("/v1/orders/?from=2024-02-01T00:00:00Z&to=2024-02-01T00:00:00Z&min_total=500&max_total=100")
+	test.Send(nil)
+	test.Set("Authorization", "Bearer "+authToken)
+
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		var response testerrordbResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		// The time range must not be empty, the total range may be a single value
+		assert.Equal(t, []string{"F8", "F7"}, response.Errorno)
+	})
+}
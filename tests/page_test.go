@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"gotemplate/supertest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPage holds a page of orders with its meta and Link header
+type testPage struct {
+	Meta struct {
+		Total          *uint64 `json:"total"`
+		TotalEstimated bool    `json:"total_estimated"`
+		Limit          uint64  `json:"limit"`
+		NextCursor     string  `json:"next_cursor"`
+		PrevCursor     string  `json:"prev_cursor"`
+	} `json:"meta"`
+	Orders []testOrderResponse `json:"orders"`
+	Link   string              `json:"-"`
+}
+
+// ids returns the ids of the orders of the page
+func (p testPage) ids() []uint64 {
+	var ids []uint64
+	for _, order := range p.Orders {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
+
+// listOrdersPage lists a page of orders, returning the status code
+func listOrdersPage(t *testing.T, query string, page *testPage) int {
+	test := supertest.NewSuperTest(router, t)
+	test.Get("/v1/orders/?" + query)
+	test.Send(nil)
+	test.Set("Authorization", "Bearer "+authToken)
+
+	var code int
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		code = rr.Code
+		if rr.Code != http.StatusOK {
+			return
+		}
+
+		var response struct {
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.NoError(t, json.Unmarshal(response.Data, page))
+		page.Link = rr.Header().Get("Link")
+	})
+	return code
+}
+
+func TestListOrdersCursorPages(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 100)
+	customer := fmt.Sprintf("Page_%d", time.Now().UnixNano())
+
+	var ids []uint64
+	for _, qty := range []int64{1, 2, 3} {
+		var order testOrderResponse
+		code := sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+			"payment_id":    paymentID,
+			"customer_name": customer,
+			"total_paid":    float64(qty) * 1000,
+			"products":      []gin.H{{"product_id": productID, "qty": qty}},
+		}, &order)
+		require.Equal(t, http.StatusOK, code)
+		ids = append(ids, order.ID)
+	}
+	query := "customer_name=" + url.QueryEscape(customer) + "&sort=total_price&limit=2"
+
+	var first testPage
+	require.Equal(t, http.StatusOK, listOrdersPage(t, query+"&total=exact", &first))
+	assert.Equal(t, ids[:2], first.ids())
+	require.NotNil(t, first.Meta.Total)
+	assert.Equal(t, uint64(3), *first.Meta.Total)
+	assert.False(t, first.Meta.TotalEstimated)
+	assert.Equal(t, uint64(2), first.Meta.Limit)
+	assert.Empty(t, first.Meta.PrevCursor)
+	require.NotEmpty(t, first.Meta.NextCursor)
+	assert.Contains(t, first.Link, `rel="next"`)
+	assert.NotContains(t, first.Link, `rel="prev"`)
+
+	var second testPage
+	require.Equal(t, http.StatusOK, listOrdersPage(t, query+"&cursor="+first.Meta.NextCursor, &second))
+	assert.Equal(t, ids[2:], second.ids())
+	assert.Nil(t, second.Meta.Total)
+	assert.Empty(t, second.Meta.NextCursor)
+	require.NotEmpty(t, second.Meta.PrevCursor)
+	assert.True(t, strings.HasSuffix(second.Link, `rel="prev"`))
+
+	var back testPage
+	require.Equal(t, http.StatusOK, listOrdersPage(t, query+"&cursor="+second.Meta.PrevCursor, &back))
+	assert.Equal(t, ids[:2], back.ids())
+	assert.Empty(t, back.Meta.PrevCursor)
+	assert.NotEmpty(t, back.Meta.NextCursor)
+
+	var estimated testPage
+	require.Equal(t, http.StatusOK, listOrdersPage(t, query+"&total=estimate", &estimated))
+	assert.NotNil(t, estimated.Meta.Total)
+	assert.True(t, estimated.Meta.TotalEstimated)
+}
+
+func TestListOrdersCursorError(t *testing.T) {
+	createTestOrder(t, 10, 1)
+	createTestOrder(t, 10, 1)
+
+	var first testPage
+	require.Equal(t, http.StatusOK, listOrdersPage(t, "limit=1&sort=created_at", &first))
+	require.NotEmpty(t, first.Meta.NextCursor)
+
+	for _, query := range []string{
+		"cursor=not-a-cursor",
+		"cursor=" + first.Meta.NextCursor + "&sort=total_price",
+		"limit=101",
+		"total=all",
+	} {
+		assert.Equal(t, http.StatusBadRequest, listOrdersPage(t, query, &testPage{}), query)
+	}
+}
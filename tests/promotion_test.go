@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPricedOrder holds the pricing fields of an order or a cart preview checked by the promotion tests
+type testPricedOrder struct {
+	ID               uint64  `json:"id"`
+	TotalNormalPrice float64 `json:"total_normal_price"`
+	Discount         float64 `json:"discount"`
+	TotalPrice       float64 `json:"total_price"`
+	TotalReturn      float64 `json:"total_return"`
+	PromotionID      *uint64 `json:"promotion_id"`
+	Products         []struct {
+		Price            float64 `json:"price"`
+		TotalNormalPrice float64 `json:"total_normal_price"`
+		Discount         float64 `json:"discount"`
+		TotalFinalPrice  float64 `json:"total_final_price"`
+		PromotionID      *uint64 `json:"promotion_id"`
+		Promotion        *struct {
+			Name string `json:"name"`
+		} `json:"promotion"`
+	} `json:"products"`
+}
+
+// createTestPromotion creates a promotion, deleted again when the test ends
+func createTestPromotion(t *testing.T, promotion gin.H) uint64 {
+	var created struct {
+		ID uint64 `json:"id"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, "/v1/promotions/", promotion, &created)
+	require.Equal(t, http.StatusOK, code)
+
+	t.Cleanup(func() {
+		sendJSONRequest(t, http.MethodDelete, fmt.Sprintf("/v1/promotions/%d", created.ID), nil, nil)
+	})
+	return created.ID
+}
+
+func TestCreateOrderWithPromotion(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 10)
+	promotionID := createTestPromotion(t, gin.H{
+		"name":      "Buy 2 get 1 free",
+		"kind":      "buy_x_get_y",
+		"scope":     "product",
+		"target_id": productID,
+		"buy_qty":   2,
+		"get_qty":   1,
+	})
+
+	var order testPricedOrder
+	code := sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "Promo Doe",
+		"total_paid":    2000,
+		"products":      []gin.H{{"product_id": productID, "qty": 3}},
+	}, &order)
+	require.Equal(t, http.StatusOK, code)
+
+	assert.Equal(t, 3000.0, order.TotalNormalPrice)
+	assert.Equal(t, 1000.0, order.Discount)
+	assert.Equal(t, 2000.0, order.TotalPrice)
+	assert.Equal(t, 0.0, order.TotalReturn)
+	require.Len(t, order.Products, 1)
+	line := order.Products[0]
+	assert.Equal(t, 1000.0, line.Price)
+	assert.Equal(t, 3000.0, line.TotalNormalPrice)
+	assert.Equal(t, 1000.0, line.Discount)
+	assert.Equal(t, 2000.0, line.TotalFinalPrice)
+	require.NotNil(t, line.PromotionID)
+	assert.Equal(t, promotionID, *line.PromotionID)
+	require.NotNil(t, line.Promotion)
+	assert.Equal(t, "Buy 2 get 1 free", line.Promotion.Name)
+
+	// The discount is stored with the order
+	var stored testPricedOrder
+	code = sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/orders/%d", order.ID), nil, &stored)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, order.Discount, stored.Discount)
+	assert.Equal(t, order.Products[0].TotalFinalPrice, stored.Products[0].TotalFinalPrice)
+}
+
+func TestCreateFullyDiscountedOrder(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 10)
+	createTestPromotion(t, gin.H{
+		"name":      "Free sample",
+		"kind":      "percentage",
+		"scope":     "product",
+		"target_id": productID,
+		"value":     100,
+	})
+
+	// Nothing is due, so a payment of 0 is enough
+	var order testPricedOrder
+	code := sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "Free Doe",
+		"total_paid":    0,
+		"products":      []gin.H{{"product_id": productID, "qty": 2}},
+	}, &order)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2000.0, order.Discount)
+	assert.Equal(t, 0.0, order.TotalPrice)
+	assert.Equal(t, 0.0, order.TotalReturn)
+	assert.Equal(t, int64(8), productStock(t, productID))
+
+	// The payment is still required
+	code = sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "Free Doe",
+		"products":      []gin.H{{"product_id": productID, "qty": 1}},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestPreviewOrder(t *testing.T) {
+	_, productID := createOrderFixtures(t, 2000, 1)
+	ended := time.Now().Add(-time.Hour)
+	createTestPromotion(t, gin.H{
+		"name":      "10% off",
+		"kind":      "percentage",
+		"scope":     "product",
+		"target_id": productID,
+		"value":     10,
+	})
+	createTestPromotion(t, gin.H{
+		"name":      "Expired half price",
+		"kind":      "percentage",
+		"scope":     "product",
+		"target_id": productID,
+		"value":     50,
+		"starts_at": ended.Add(-time.Hour),
+		"ends_at":   ended,
+	})
+
+	// Stock is not checked by a preview
+	var preview testPricedOrder
+	code := sendJSONRequest(t, http.MethodPost, "/v1/orders/preview", gin.H{
+		"products": []gin.H{{"product_id": productID, "qty": 5}},
+	}, &preview)
+	require.Equal(t, http.StatusOK, code)
+
+	assert.Equal(t, 10000.0, preview.TotalNormalPrice)
+	assert.Equal(t, 1000.0, preview.Discount)
+	assert.Equal(t, 9000.0, preview.TotalPrice)
+	require.Len(t, preview.Products, 1)
+	assert.Equal(t, 9000.0, preview.Products[0].TotalFinalPrice)
+	assert.Equal(t, int64(1), productStock(t, productID))
+
+	code = sendJSONRequest(t, http.MethodPost, "/v1/orders/preview", gin.H{
+		"products": []gin.H{{"product_id": 999999999, "qty": 1}},
+	}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCreatePromotionValidationError(t *testing.T) {
+	for _, promotion := range []gin.H{
+		{"name": "No target", "kind": "percentage", "scope": "product", "value": 10},
+		{"name": "Order target", "kind": "fixed", "scope": "order", "target_id": 1, "value": 10},
+		{"name": "Too much", "kind": "percentage", "scope": "order", "value": 150},
+		{"name": "Order buy get", "kind": "buy_x_get_y", "scope": "order", "buy_qty": 1, "get_qty": 1},
+		{"name": "No quantities", "kind": "buy_x_get_y", "scope": "product", "target_id": 1},
+		{"name": "Backwards", "kind": "fixed", "scope": "order", "value": 10, "starts_at": "2024-02-01T00:00:00Z", "ends_at": "2024-01-01T00:00:00Z"},
+		{"name": "Unknown kind", "kind": "free", "scope": "order", "value": 10},
+	} {
+		code := sendJSONRequest(t, http.MethodPost, "/v1/promotions/", promotion, nil)
+		assert.Equal(t, http.StatusBadRequest, code, promotion["name"])
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testProductPage holds a page of products listed with a field selection
+type testProductPage struct {
+	Meta struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"meta"`
+	Products []map[string]any `json:"products"`
+}
+
+// listProducts lists the products matching query, failing the test on another status than 200
+func listProducts(t *testing.T, query string) testProductPage {
+	var page testProductPage
+	code := sendJSONRequest(t, http.MethodGet, "/v1/products/?"+query, nil, &page)
+	require.Equal(t, http.StatusOK, code, query)
+	return page
+}
+
+// productNames returns the names of the products of a page
+func (p testProductPage) productNames() []string {
+	var names []string
+	for _, product := range p.Products {
+		names = append(names, product["name"].(string))
+	}
+	return names
+}
+
+func TestListProductsQuery(t *testing.T) {
+	var created struct {
+		ID uint64 `json:"id"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, "/v1/categories/", gin.H{"name": "Query test category"}, &created)
+	require.Equal(t, http.StatusOK, code)
+	categoryID := created.ID
+
+	prefix := fmt.Sprintf("Query_%d", time.Now().UnixNano())
+	var ids []uint64
+	for _, product := range []struct {
+		name  string
+		price float64
+		stock int64
+	}{{"a", 300, 5}, {"b", 100, 5}, {"c", 200, 9}} {
+		code := sendJSONRequest(t, http.MethodPost, "/v1/products/", gin.H{
+			"category_id": categoryID,
+			"name":        prefix + "_" + product.name,
+			"image":       "https://example.com/query-test.png",
+			"price":       product.price,
+			"stock":       product.stock,
+		}, &created)
+		require.Equal(t, http.StatusOK, code)
+		ids = append(ids, created.ID)
+	}
+	category := fmt.Sprintf("filter[category_id]=%d", categoryID)
+	a, b, c := prefix+"_a", prefix+"_b", prefix+"_c"
+
+	page := listProducts(t, category+"&sort=-price")
+	assert.Equal(t, []string{a, c, b}, page.productNames())
+
+	page = listProducts(t, category+"&filter[price][gte]=150&sort=name&fields=id,name")
+	require.Len(t, page.Products, 2)
+	assert.Equal(t, []string{a, c}, page.productNames())
+	for _, product := range page.Products {
+		assert.Len(t, product, 2)
+		assert.Contains(t, product, "id")
+	}
+
+	page = listProducts(t, "filter[name][like]="+url.QueryEscape(prefix+"_B"))
+	assert.Equal(t, []string{b}, page.productNames())
+
+	page = listProducts(t, fmt.Sprintf("filter[id][in]=%d,%d&sort=-id", ids[0], ids[2]))
+	assert.Equal(t, []string{c, a}, page.productNames())
+
+	// Ties on stock are broken by name, across pages
+	var names []string
+	query := category + "&sort=-stock,name&limit=1"
+	for cursor := ""; ; {
+		page = listProducts(t, query+"&cursor="+cursor)
+		names = append(names, page.productNames()...)
+		if cursor = page.Meta.NextCursor; cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{c, a, b}, names)
+}
+
+func TestListProductsQueryError(t *testing.T) {
+	for _, query := range []string{
+		"filter[image]=x",
+		"filter[price][like]=1",
+		"filter[price][between]=1",
+		"filter[price][gte]=cheap",
+		"filter[created_at][gt]=yesterday",
+		"filter[price",
+		"sort=image",
+		"sort=price,-price",
+		"fields=id,password",
+	} {
+		code := sendJSONRequest(t, http.MethodGet, "/v1/products/?"+query, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"fmt"
+	"gotemplate/supertest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testReceiptResponse holds the receipt fields checked by the receipt tests
+type testReceiptResponse struct {
+	StoreName   string  `json:"store_name"`
+	Footer      string  `json:"footer"`
+	ReceiptCode string  `json:"receipt_code"`
+	OrderID     uint64  `json:"order_id"`
+	TotalPrice  float64 `json:"total_price"`
+	Change      float64 `json:"change"`
+	PaymentType string  `json:"payment_type"`
+	Lines       []struct {
+		Name      string  `json:"name"`
+		Quantity  int64   `json:"qty"`
+		UnitPrice float64 `json:"unit_price"`
+		Total     float64 `json:"total"`
+	} `json:"lines"`
+}
+
+// getRaw sends an admin GET request with the given Accept header and returns the recorded response
+func getRaw(t *testing.T, url, accept string) *httptest.ResponseRecorder {
+	test := supertest.NewSuperTest(router, t)
+	test.Get(url)
+	test.Send(nil)
+	test.Set("Authorization", "Bearer "+authToken)
+	if accept != "" {
+		test.Set("Accept", accept)
+	}
+
+	var recorder *httptest.ResponseRecorder
+	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+		recorder = rr
+	})
+	return recorder
+}
+
+func TestGetOrderReceipt(t *testing.T) {
+	order, _ := createTestOrder(t, 10, 3)
+
+	var rcpt testReceiptResponse
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/orders/%d/receipt", order.ID), nil, &rcpt)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Go POS Test Store", rcpt.StoreName)
+	assert.Equal(t, "Thank you for shopping with us", rcpt.Footer)
+	assert.Equal(t, order.ID, rcpt.OrderID)
+	assert.Equal(t, 3000.0, rcpt.TotalPrice)
+	assert.Equal(t, 0.0, rcpt.Change)
+	assert.Equal(t, "CASH", rcpt.PaymentType)
+	require.Len(t, rcpt.Lines, 1)
+	assert.Equal(t, "Order test product", rcpt.Lines[0].Name)
+	assert.Equal(t, int64(3), rcpt.Lines[0].Quantity)
+	assert.Equal(t, 1000.0, rcpt.Lines[0].UnitPrice)
+	assert.Equal(t, 3000.0, rcpt.Lines[0].Total)
+}
+
+func TestGetReceiptByCodeFormats(t *testing.T) {
+	order, _ := createTestOrder(t, 10, 2)
+
+	var rcpt testReceiptResponse
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/orders/%d/receipt", order.ID), nil, &rcpt)
+	require.Equal(t, http.StatusOK, code)
+	url := "/v1/receipts/" + rcpt.ReceiptCode
+
+	text := getRaw(t, url+"?format=text", "")
+	require.Equal(t, http.StatusOK, text.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", text.Header().Get("Content-Type"))
+	assert.Contains(t, text.Body.String(), "Go POS Test Store")
+	assert.Contains(t, text.Body.String(), "2000.00")
+
+	html := getRaw(t, url, "text/html")
+	require.Equal(t, http.StatusOK, html.Code)
+	assert.Equal(t, "text/html; charset=utf-8", html.Header().Get("Content-Type"))
+	assert.Contains(t, html.Body.String(), "<h1>Go POS Test Store</h1>")
+
+	assert.Equal(t, http.StatusNotAcceptable, getRaw(t, url, "image/png").Code)
+	assert.Equal(t, http.StatusBadRequest, getRaw(t, url+"?format=pdf", "").Code)
+}
+
+func TestGetReceiptErrors(t *testing.T) {
+	assert.Equal(t, http.StatusBadRequest, getRaw(t, "/v1/receipts/not-a-uuid", "").Code)
+	assert.Equal(t, http.StatusNotFound, getRaw(t, "/v1/receipts/4979cf6e-d215-4ff8-9d0d-b3e99bcc7750", "").Code)
+	assert.Equal(t, http.StatusNotFound, getRaw(t, "/v1/orders/999999999/receipt", "").Code)
+}
+
+func TestGetOrderReceiptESCPOS(t *testing.T) {
+	order, _ := createTestOrder(t, 10, 1)
+	url := fmt.Sprintf("/v1/orders/%d/receipt", order.ID)
+
+	for _, rr := range []*httptest.ResponseRecorder{
+		getRaw(t, url+"?format=escpos", ""),
+		getRaw(t, url, "application/octet-stream"),
+	} {
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+
+		body := rr.Body.Bytes()
+		assert.Equal(t, []byte{0x1b, 0x40}, body[:2], "starts with printer init")
+		assert.Equal(t, []byte{0x1d, 0x56, 0x41, 0x00}, body[len(body)-4:], "ends with a cut")
+		assert.Contains(t, string(body), "Go POS Test Store")
+	}
+}
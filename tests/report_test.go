@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testReportRow holds the report row fields checked by the report tests
+type testReportRow struct {
+	Period    string  `json:"period"`
+	ProductID uint64  `json:"product_id"`
+	UserID    uint64  `json:"user_id"`
+	PaymentID uint64  `json:"payment_id"`
+	Orders    int64   `json:"orders"`
+	Items     int64   `json:"items"`
+	Quantity  int64   `json:"qty"`
+	Revenue   float64 `json:"revenue"`
+	Share     float64 `json:"share"`
+}
+
+// createReportOrders creates two orders of 2 and 3 items at 1000 in a new category, refunds one item
+// of the second and cancels a third order. It returns the category, payment and product ids.
+func createReportOrders(t *testing.T) (categoryID, paymentID, productID uint64) {
+	paymentID, productID = createOrderFixtures(t, 1000, 100)
+
+	var product struct {
+		CategoryID uint64 `json:"category_id"`
+	}
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/products/%d", productID), nil, &product)
+	require.Equal(t, http.StatusOK, code)
+
+	var orders []testOrderResponse
+	for _, qty := range []int64{2, 3, 4} {
+		var order testOrderResponse
+		code := sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+			"payment_id":    paymentID,
+			"customer_name": "Report Doe",
+			"total_paid":    float64(qty) * 1000,
+			"products":      []gin.H{{"product_id": productID, "qty": qty}},
+		}, &order)
+		require.Equal(t, http.StatusOK, code)
+		orders = append(orders, order)
+	}
+
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/orders/%d/refunds", orders[1].ID), gin.H{
+		"reason":   "Damaged item",
+		"products": []gin.H{{"order_product_id": orders[1].Products[0].ID, "qty": 1}},
+	}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/orders/%d/cancel", orders[2].ID), gin.H{"reason": "Customer left"}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	return product.CategoryID, paymentID, productID
+}
+
+// getReport reads the rows of a report over today in UTC
+func getReport(t *testing.T, path, query string) []testReportRow {
+	today := time.Now().UTC().Format("2006-01-02")
+
+	var report struct {
+		Rows []testReportRow `json:"rows"`
+	}
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/reports/%s?from=%s&to=%s&tz=UTC&%s", path, today, today, query), nil, &report)
+	require.Equal(t, http.StatusOK, code)
+	return report.Rows
+}
+
+func TestSalesReports(t *testing.T) {
+	categoryID, paymentID, productID := createReportOrders(t)
+	category := fmt.Sprintf("category_id=%d", categoryID)
+
+	// The cancelled order is left out and the refunded item is deducted
+	sales := getReport(t, "sales", category)
+	require.Len(t, sales, 1)
+	assert.Equal(t, time.Now().UTC().Format("2006-01-02"), sales[0].Period)
+	assert.Equal(t, int64(2), sales[0].Orders)
+	assert.Equal(t, int64(4), sales[0].Items)
+	assert.Equal(t, 4000.0, sales[0].Revenue)
+
+	products := getReport(t, "top-products", category+"&by=revenue")
+	require.Len(t, products, 1)
+	assert.Equal(t, productID, products[0].ProductID)
+	assert.Equal(t, int64(4), products[0].Quantity)
+	assert.Equal(t, 4000.0, products[0].Revenue)
+
+	cashiers := getReport(t, "cashiers", category)
+	require.Len(t, cashiers, 1)
+	assert.Equal(t, uint64(1), cashiers[0].UserID)
+	assert.Equal(t, 4000.0, cashiers[0].Revenue)
+
+	payments := getReport(t, "payments", category)
+	require.Len(t, payments, 1)
+	assert.Equal(t, paymentID, payments[0].PaymentID)
+	assert.Equal(t, int64(2), payments[0].Orders)
+	assert.Equal(t, 100.0, payments[0].Share)
+
+	// A range that ended yesterday has no sales of the new category
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	var report struct {
+		Rows []testReportRow `json:"rows"`
+	}
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/reports/sales?from=%s&to=%s&tz=UTC&%s", yesterday, yesterday, category), nil, &report)
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, report.Rows)
+}
+
+func TestSalesReportCSV(t *testing.T) {
+	categoryID, _, _ := createReportOrders(t)
+	today := time.Now().UTC().Format("2006-01-02")
+	url := fmt.Sprintf("/v1/reports/sales?from=%s&to=%s&tz=UTC&category_id=%d", today, today, categoryID)
+
+	for _, rr := range [...]struct{ url, accept string }{
+		{url + "&format=csv", ""},
+		{url, "text/csv"},
+	} {
+		rec := getRaw(t, rr.url, rr.accept)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv"))
+		assert.Contains(t, rec.Header().Get("Content-Disposition"), "attachment")
+		assert.Equal(t, "period,orders,items,revenue\n"+today+",2,4,4000.00\n", rec.Body.String())
+	}
+}
+
+func TestSalesReportValidationError(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"from=2024-01-31&to=2024-01-01",
+		"from=2024-01-01&to=2024-01-31&interval=year",
+		"from=2024-01-01&to=2024-01-31&tz=Mars/Olympus",
+		"from=2024-01-01T00:00:00Z&to=2024-01-31",
+		"from=2024-01-01&to=2024-01-31&format=xml",
+	} {
+		code := sendJSONRequest(t, http.MethodGet, "/v1/reports/sales?"+query, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+
+	code := sendJSONRequest(t, http.MethodGet, "/v1/reports/top-products?from=2024-01-01&to=2024-01-31&limit=101", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSearchedProduct holds the product fields checked by the search tests
+type testSearchedProduct struct {
+	ID         uint64  `json:"id"`
+	SKU        string  `json:"sku"`
+	Barcode    *string `json:"barcode"`
+	Name       string  `json:"name"`
+	SearchRank float32 `json:"search_rank"`
+}
+
+// uniqueWord makes a word of letters found in no other test data
+func uniqueWord() string {
+	n := time.Now().UnixNano()
+	word := []byte("zq")
+	for n > 0 {
+		word = append(word, byte('a'+n%26))
+		n /= 26
+	}
+	return string(word)
+}
+
+// createSearchProduct creates a product of a category with a name and barcode
+func createSearchProduct(t *testing.T, categoryID uint64, name string, barcode string) testSearchedProduct {
+	var product testSearchedProduct
+	code := sendJSONRequest(t, http.MethodPost, "/v1/products/", gin.H{
+		"category_id": categoryID,
+		"barcode":     barcode,
+		"name":        name,
+		"image":       "https://example.com/search-test.png",
+		"price":       1000,
+		"stock":       10,
+	}, &product)
+	require.Equal(t, http.StatusOK, code)
+	return product
+}
+
+// searchProducts lists the products matching a search
+func searchProducts(t *testing.T, search string) []testSearchedProduct {
+	var list struct {
+		Products []testSearchedProduct `json:"products"`
+	}
+	code := sendJSONRequest(t, http.MethodGet, "/v1/products/?limit=100&q="+url.QueryEscape(search), nil, &list)
+	require.Equal(t, http.StatusOK, code)
+	return list.Products
+}
+
+// productIDs returns the ids of products
+func productIDs(products []testSearchedProduct) []uint64 {
+	var ids []uint64
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+	return ids
+}
+
+func TestSearchProducts(t *testing.T) {
+	word, categoryWord := uniqueWord(), uniqueWord()
+
+	var category struct {
+		ID uint64 `json:"id"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, "/v1/categories/", gin.H{"name": categoryWord + " Snacks"}, &category)
+	require.Equal(t, http.StatusOK, code)
+
+	named := createSearchProduct(t, category.ID, word+" Marshmallow", fmt.Sprint(time.Now().UnixNano()))
+	inCategory := createSearchProduct(t, category.ID, categoryWord+" Chips", fmt.Sprint(time.Now().UnixNano()))
+	plain := createSearchProduct(t, category.ID, "Plain crackers", fmt.Sprint(time.Now().UnixNano()))
+
+	// As-you-type prefix of a word of the name
+	found := searchProducts(t, word[:len(word)-3])
+	require.Equal(t, []uint64{named.ID}, productIDs(found))
+	assert.Positive(t, found[0].SearchRank)
+
+	// One letter missing
+	found = searchProducts(t, word[:5]+word[6:])
+	assert.Contains(t, productIDs(found), named.ID)
+
+	// Matches on the name rank before matches on the category name only
+	found = searchProducts(t, categoryWord)
+	require.Len(t, found, 3)
+	assert.Equal(t, inCategory.ID, found[0].ID)
+	assert.ElementsMatch(t, []uint64{named.ID, inCategory.ID, plain.ID}, productIDs(found))
+
+	// Renaming the category changes what its products are found by
+	renamed := uniqueWord()
+	code = sendJSONRequest(t, http.MethodPut, fmt.Sprintf("/v1/categories/%d", category.ID), gin.H{"name": renamed}, nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, searchProducts(t, renamed), 3)
+
+	assert.Empty(t, searchProducts(t, uniqueWord()))
+}
+
+func TestLookupProduct(t *testing.T) {
+	var category struct {
+		ID uint64 `json:"id"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, "/v1/categories/", gin.H{"name": "Lookup test category"}, &category)
+	require.Equal(t, http.StatusOK, code)
+
+	barcode := fmt.Sprint(time.Now().UnixNano())
+	created := createSearchProduct(t, category.ID, "Lookup test product", barcode)
+	require.NotNil(t, created.Barcode)
+	assert.Equal(t, barcode, *created.Barcode)
+
+	for _, scanned := range []string{barcode, created.SKU} {
+		var product testSearchedProduct
+		code = sendJSONRequest(t, http.MethodGet, "/v1/products/lookup/"+scanned, nil, &product)
+		require.Equal(t, http.StatusOK, code, scanned)
+		assert.Equal(t, created.ID, product.ID)
+	}
+
+	code = sendJSONRequest(t, http.MethodGet, "/v1/products/lookup/"+barcode+"0", nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Barcodes are unique
+	code = sendJSONRequest(t, http.MethodPost, "/v1/products/", gin.H{
+		"category_id": category.ID,
+		"barcode":     barcode,
+		"name":        "Lookup test duplicate",
+		"image":       "https://example.com/search-test.png",
+		"price":       1000,
+		"stock":       10,
+	}, nil)
+	assert.Equal(t, http.StatusConflict, code)
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"fmt"
+	"gotemplate/supertest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testZReportResponse holds the Z-report fields checked by the shift tests
+type testZReportResponse struct {
+	Shifts []struct {
+		ID   uint64 `json:"id"`
+		Open bool   `json:"open"`
+	} `json:"shifts"`
+	Orders       int64    `json:"orders"`
+	GrossSales   float64  `json:"gross_sales"`
+	Refunds      int64    `json:"refunds"`
+	RefundAmount float64  `json:"refund_amount"`
+	NetSales     float64  `json:"net_sales"`
+	CashSales    float64  `json:"cash_sales"`
+	CashRefunds  float64  `json:"cash_refunds"`
+	ExpectedCash float64  `json:"expected_cash"`
+	CountedCash  *float64 `json:"counted_cash"`
+	Variance     *float64 `json:"variance"`
+	Payments     []struct {
+		Type    string  `json:"type"`
+		Orders  int64   `json:"orders"`
+		Sales   float64 `json:"sales"`
+		Refunds float64 `json:"refunds"`
+	} `json:"payments"`
+}
+
+// openTestShift opens an admin shift with the given float, closing any shift left open by an earlier run
+func openTestShift(t *testing.T, openingFloat float64) uint64 {
+	var current testZReportResponse
+	if sendJSONRequest(t, http.MethodGet, "/v1/shifts/current", nil, &current) == http.StatusOK {
+		code := sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/shifts/%d/close", current.Shifts[0].ID), gin.H{"counted_cash": current.ExpectedCash}, nil)
+		require.Equal(t, http.StatusOK, code)
+	}
+
+	var shift struct {
+		ID   uint64 `json:"id"`
+		Open bool   `json:"open"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, "/v1/shifts/", gin.H{"opening_float": openingFloat}, &shift)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, shift.Open)
+	return shift.ID
+}
+
+func TestShiftOpenAndClose(t *testing.T) {
+	shiftID := openTestShift(t, 50000)
+
+	code := sendJSONRequest(t, http.MethodPost, "/v1/shifts/", gin.H{"opening_float": 0}, nil)
+	assert.Equal(t, http.StatusConflict, code)
+
+	// 3 items at 1000 paid in cash, then one of them refunded
+	order, _ := createTestOrder(t, 10, 3)
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/orders/%d/refunds", order.ID), gin.H{
+		"reason":   "Damaged item",
+		"products": []gin.H{{"order_product_id": order.Products[0].ID, "qty": 1}},
+	}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	var hydrated struct {
+		ShiftID uint64 `json:"shift_id"`
+	}
+	code = sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/orders/%d", order.ID), nil, &hydrated)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, shiftID, hydrated.ShiftID)
+
+	var running testZReportResponse
+	code = sendJSONRequest(t, http.MethodGet, "/v1/shifts/current", nil, &running)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(1), running.Orders)
+	assert.Equal(t, 3000.0, running.GrossSales)
+	assert.Equal(t, int64(1), running.Refunds)
+	assert.Equal(t, 1000.0, running.RefundAmount)
+	assert.Equal(t, 2000.0, running.NetSales)
+	assert.Equal(t, 52000.0, running.ExpectedCash)
+	assert.Nil(t, running.Variance)
+	require.Len(t, running.Payments, 1)
+	assert.Equal(t, "CASH", running.Payments[0].Type)
+
+	var closed testZReportResponse
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/shifts/%d/close", shiftID), gin.H{"counted_cash": 51500, "note": "Short one 500 coin"}, &closed)
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, closed.Shifts[0].Open)
+	assert.Equal(t, 52000.0, closed.ExpectedCash)
+	require.NotNil(t, closed.Variance)
+	assert.Equal(t, 51500.0, *closed.CountedCash)
+	assert.Equal(t, -500.0, *closed.Variance)
+
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/shifts/%d/close", shiftID), gin.H{"counted_cash": 51500}, nil)
+	assert.Equal(t, http.StatusConflict, code)
+
+	code = sendJSONRequest(t, http.MethodGet, "/v1/shifts/current", nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Orders taken without an open shift are not tied to one
+	order, _ = createTestOrder(t, 10, 1)
+	hydrated.ShiftID = 0
+	code = sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/orders/%d", order.ID), nil, &hydrated)
+	require.Equal(t, http.StatusOK, code)
+	assert.Zero(t, hydrated.ShiftID)
+
+	rec := getRaw(t, fmt.Sprintf("/v1/shifts/%d/report?format=text", shiftID), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("Z-REPORT SHIFT #%d", shiftID))
+	assert.Contains(t, rec.Body.String(), "SHORT")
+
+	rec = getRaw(t, fmt.Sprintf("/v1/shifts/%d/report", shiftID), "application/octet-stream")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+}
+
+func TestShiftOfAnotherCashierForbidden(t *testing.T) {
+	shiftID := openTestShift(t, 0)
+	defer sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/shifts/%d/close", shiftID), gin.H{"counted_cash": 0}, nil)
+
+	for _, url := range []string{
+		fmt.Sprintf("/v1/shifts/%d", shiftID),
+		fmt.Sprintf("/v1/shifts/%d/report", shiftID),
+		"/v1/reports/z?date=2024-01-01",
+	} {
+		test := supertest.NewSuperTest(router, t)
+		test.Get(url)
+		test.Send(nil)
+		test.Set("Content-Type", "application/json")
+		test.Set("Authorization", "Bearer "+cashierToken)
+
+		test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusForbidden, rr.Code, url)
+		})
+	}
+}
+
+func TestDailyZReport(t *testing.T) {
+	shiftID := openTestShift(t, 1000)
+	createTestOrder(t, 10, 2)
+	code := sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/shifts/%d/close", shiftID), gin.H{"counted_cash": 3100}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	var report testZReportResponse
+	code = sendJSONRequest(t, http.MethodGet, "/v1/reports/z?tz=UTC&date="+time.Now().UTC().Format("2006-01-02"), nil, &report)
+	require.Equal(t, http.StatusOK, code)
+
+	found := false
+	for _, shift := range report.Shifts {
+		found = found || shift.ID == shiftID
+	}
+	assert.True(t, found)
+	assert.GreaterOrEqual(t, report.GrossSales, 2000.0)
+	require.NotNil(t, report.Variance)
+
+	code = sendJSONRequest(t, http.MethodGet, "/v1/reports/z?date=yesterday", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
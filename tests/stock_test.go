@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStockMovement holds the stock movement fields checked by the ledger tests
+type testStockMovement struct {
+	ProductID  uint64  `json:"product_id"`
+	Source     string  `json:"source"`
+	Quantity   int64   `json:"quantity"`
+	StockAfter int64   `json:"stock_after"`
+	UserID     *uint64 `json:"user_id"`
+	OrderID    *uint64 `json:"order_id"`
+	RefundID   *uint64 `json:"refund_id"`
+	Reference  *string `json:"reference"`
+	Reason     *string `json:"reason"`
+}
+
+// stockMovements lists the ledger of a product, the most recent movement first
+func stockMovements(t *testing.T, productID uint64) []testStockMovement {
+	var list struct {
+		StockMovements []testStockMovement `json:"stock_movements"`
+	}
+	code := sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/products/%d/stock-movements", productID), nil, &list)
+	require.Equal(t, http.StatusOK, code)
+	return list.StockMovements
+}
+
+func TestStockLedgerRecordsOrders(t *testing.T) {
+	paymentID, productID := createOrderFixtures(t, 1000, 10)
+
+	placeOrder := func(qty int64) testOrderResponse {
+		var order testOrderResponse
+		code := sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+			"payment_id":    paymentID,
+			"customer_name": "John Doe",
+			"total_paid":    float64(qty) * 1000,
+			"products":      []gin.H{{"product_id": productID, "qty": qty}},
+		}, &order)
+		require.Equal(t, http.StatusOK, code)
+		return order
+	}
+
+	refunded := placeOrder(4)
+	code := sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/orders/%d/refunds", refunded.ID), gin.H{
+		"reason":   "Damaged item",
+		"products": []gin.H{{"order_product_id": refunded.Products[0].ID, "qty": 1}},
+	}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	cancelled := placeOrder(2)
+	code = sendJSONRequest(t, http.MethodPost, fmt.Sprintf("/v1/orders/%d/cancel", cancelled.ID), gin.H{"reason": "Customer left"}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	movements := stockMovements(t, productID)
+	require.Len(t, movements, 5)
+
+	// Most recent first
+	assert.Equal(t, "cancellation", movements[0].Source)
+	assert.Equal(t, int64(2), movements[0].Quantity)
+	assert.Equal(t, int64(7), movements[0].StockAfter)
+	require.NotNil(t, movements[0].OrderID)
+	assert.Equal(t, cancelled.ID, *movements[0].OrderID)
+	require.NotNil(t, movements[0].Reason)
+	assert.Equal(t, "Customer left", *movements[0].Reason)
+
+	assert.Equal(t, "sale", movements[1].Source)
+	assert.Equal(t, int64(-2), movements[1].Quantity)
+	assert.Equal(t, int64(5), movements[1].StockAfter)
+
+	assert.Equal(t, "refund", movements[2].Source)
+	assert.Equal(t, int64(1), movements[2].Quantity)
+	assert.Equal(t, int64(7), movements[2].StockAfter)
+	assert.NotNil(t, movements[2].RefundID)
+	require.NotNil(t, movements[2].OrderID)
+	assert.Equal(t, refunded.ID, *movements[2].OrderID)
+
+	assert.Equal(t, "sale", movements[3].Source)
+	assert.Equal(t, int64(-4), movements[3].Quantity)
+	assert.Equal(t, int64(6), movements[3].StockAfter)
+	require.NotNil(t, movements[3].UserID)
+	assert.Equal(t, uint64(1), *movements[3].UserID)
+
+	assert.Equal(t, "opening", movements[4].Source)
+	assert.Equal(t, int64(10), movements[4].StockAfter)
+
+	// The ledger adds up to the product stock
+	var total int64
+	for _, m := range movements {
+		total += m.Quantity
+	}
+	assert.Equal(t, productStock(t, productID), total)
+
+	var sales struct {
+		StockMovements []testStockMovement `json:"stock_movements"`
+	}
+	code = sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/products/%d/stock-movements?source=sale", productID), nil, &sales)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, sales.StockMovements, 2)
+	assert.Equal(t, "sale", sales.StockMovements[0].Source)
+}
+
+func TestPostStockMovement(t *testing.T) {
+	_, productID := createOrderFixtures(t, 1000, 10)
+	url := fmt.Sprintf("/v1/products/%d/stock", productID)
+
+	var movement testStockMovement
+	code := sendJSONRequest(t, http.MethodPost, url, gin.H{"source": "adjustment", "quantity": -3, "reason": "Broken in storage"}, &movement)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, productID, movement.ProductID)
+	assert.Equal(t, int64(-3), movement.Quantity)
+	assert.Equal(t, int64(7), movement.StockAfter)
+	require.NotNil(t, movement.UserID)
+	assert.Equal(t, uint64(1), *movement.UserID)
+
+	code = sendJSONRequest(t, http.MethodPost, url, gin.H{"source": "receiving", "quantity": 20, "reference": "DN-0012"}, &movement)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(27), movement.StockAfter)
+	require.NotNil(t, movement.Reference)
+	assert.Equal(t, "DN-0012", *movement.Reference)
+
+	// A stocktake records the difference with the counted stock
+	code = sendJSONRequest(t, http.MethodPost, url, gin.H{"source": "stocktake", "counted": 25}, &movement)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(-2), movement.Quantity)
+	assert.Equal(t, int64(25), movement.StockAfter)
+	assert.Equal(t, int64(25), productStock(t, productID))
+
+	// Setting the stock with a product update is recorded as well
+	code = sendJSONRequest(t, http.MethodPut, fmt.Sprintf("/v1/products/%d", productID), gin.H{"stock": 30}, nil)
+	require.Equal(t, http.StatusOK, code)
+	movements := stockMovements(t, productID)
+	require.Len(t, movements, 5)
+	assert.Equal(t, "stocktake", movements[0].Source)
+	assert.Equal(t, int64(5), movements[0].Quantity)
+	assert.Equal(t, int64(30), movements[0].StockAfter)
+}
+
+func TestPostStockMovementError(t *testing.T) {
+	_, productID := createOrderFixtures(t, 1000, 5)
+	url := fmt.Sprintf("/v1/products/%d/stock", productID)
+
+	for _, payload := range []gin.H{
+		{"source": "sale", "quantity": -1},
+		{"source": "adjustment", "quantity": -1},
+		{"source": "adjustment", "quantity": 0, "reason": "Nothing"},
+		{"source": "receiving", "quantity": -1},
+		{"source": "receiving", "quantity": 1, "counted": 3},
+		{"source": "stocktake"},
+		{"source": "stocktake", "counted": -1},
+		{"source": "stocktake", "counted": 3, "quantity": 1},
+	} {
+		assert.Equal(t, http.StatusBadRequest, sendJSONRequest(t, http.MethodPost, url, payload, nil), payload)
+	}
+
+	// The stock never goes negative
+	code := sendJSONRequest(t, http.MethodPost, url, gin.H{"source": "adjustment", "quantity": -6, "reason": "Lost"}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, int64(5), productStock(t, productID))
+	assert.Len(t, stockMovements(t, productID), 1)
+
+	code = sendJSONRequest(t, http.MethodPost, "/v1/products/999999999/stock", gin.H{"source": "receiving", "quantity": 1}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	code = sendJSONRequest(t, http.MethodGet, "/v1/products/999999999/stock-movements", nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	code = sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/products/%d/stock-movements?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", productID), nil, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
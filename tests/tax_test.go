@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTaxedOrder holds the tax fields of an order checked by the tax tests
+type testTaxedOrder struct {
+	ID           uint64  `json:"id"`
+	TotalPrice   float64 `json:"total_price"`
+	TotalReturn  float64 `json:"total_return"`
+	TaxAmount    float64 `json:"tax_amount"`
+	TaxInclusive bool    `json:"tax_inclusive"`
+	TaxRounding  string  `json:"tax_rounding"`
+	Taxes        []struct {
+		Rate    float64 `json:"rate"`
+		Taxable float64 `json:"taxable"`
+		Amount  float64 `json:"amount"`
+	} `json:"taxes"`
+	Products []struct {
+		ProductID       uint64  `json:"product_id"`
+		TaxRate         float64 `json:"tax_rate"`
+		TaxAmount       float64 `json:"tax_amount"`
+		TotalFinalPrice float64 `json:"total_final_price"`
+	} `json:"products"`
+}
+
+// createTaxedProduct creates a product at the given price in category, with its own tax rate when not nil
+func createTaxedProduct(t *testing.T, categoryID uint64, price float64, taxRate any) uint64 {
+	var created struct {
+		ID      uint64   `json:"id"`
+		TaxRate *float64 `json:"tax_rate"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, "/v1/products/", gin.H{
+		"category_id": categoryID,
+		"name":        "Taxed product",
+		"image":       "https://example.com/taxed.png",
+		"price":       price,
+		"stock":       10,
+		"tax_rate":    taxRate,
+	}, &created)
+	require.Equal(t, http.StatusOK, code)
+	return created.ID
+}
+
+func TestCreateOrderTaxes(t *testing.T) {
+	var category struct {
+		ID      uint64   `json:"id"`
+		TaxRate *float64 `json:"tax_rate"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, "/v1/categories/", gin.H{"name": "Taxed category", "tax_rate": 10}, &category)
+	require.Equal(t, http.StatusOK, code)
+	require.NotNil(t, category.TaxRate)
+	assert.Equal(t, 10.0, *category.TaxRate)
+
+	paymentID, _ := createOrderFixtures(t, 1, 1)
+	categoryRated := createTaxedProduct(t, category.ID, 1000, nil)
+	exempt := createTaxedProduct(t, category.ID, 500, 0)
+
+	var order testTaxedOrder
+	code = sendJSONRequest(t, http.MethodPost, "/v1/orders/", gin.H{
+		"payment_id":    paymentID,
+		"customer_name": "Tax Doe",
+		"total_paid":    5000,
+		"products": []gin.H{
+			{"product_id": categoryRated, "qty": 3},
+			{"product_id": exempt, "qty": 2},
+		},
+	}, &order)
+	require.Equal(t, http.StatusOK, code)
+
+	// Prices exclude tax in the test config, so the 10% is added on top
+	assert.False(t, order.TaxInclusive)
+	assert.Equal(t, "line", order.TaxRounding)
+	assert.Equal(t, 300.0, order.TaxAmount)
+	assert.Equal(t, 4300.0, order.TotalPrice)
+	assert.Equal(t, 700.0, order.TotalReturn)
+	require.Len(t, order.Products, 2)
+	assert.Equal(t, 10.0, order.Products[0].TaxRate)
+	assert.Equal(t, 300.0, order.Products[0].TaxAmount)
+	assert.Equal(t, 3300.0, order.Products[0].TotalFinalPrice)
+	assert.Equal(t, 0.0, order.Products[1].TaxRate)
+	assert.Equal(t, 1000.0, order.Products[1].TotalFinalPrice)
+	require.Len(t, order.Taxes, 1)
+	assert.Equal(t, 10.0, order.Taxes[0].Rate)
+	assert.Equal(t, 3000.0, order.Taxes[0].Taxable)
+	assert.Equal(t, 300.0, order.Taxes[0].Amount)
+
+	// The breakdown is stored with the order and printed on the receipt
+	var stored testTaxedOrder
+	code = sendJSONRequest(t, http.MethodGet, fmt.Sprintf("/v1/orders/%d", order.ID), nil, &stored)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, order.TaxAmount, stored.TaxAmount)
+	assert.Equal(t, order.Taxes, stored.Taxes)
+
+	rec := getRaw(t, fmt.Sprintf("/v1/orders/%d/receipt?format=text", order.ID), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Tax 10%")
+}
+
+func TestUpdateProductClearTaxRate(t *testing.T) {
+	var category struct {
+		ID uint64 `json:"id"`
+	}
+	code := sendJSONRequest(t, http.MethodPost, "/v1/categories/", gin.H{"name": "Clear tax category", "tax_rate": 5}, &category)
+	require.Equal(t, http.StatusOK, code)
+	productID := createTaxedProduct(t, category.ID, 100, 20)
+
+	var product struct {
+		TaxRate *float64 `json:"tax_rate"`
+	}
+	code = sendJSONRequest(t, http.MethodPut, fmt.Sprintf("/v1/products/%d", productID), gin.H{"name": "Renamed taxed product"}, &product)
+	require.Equal(t, http.StatusOK, code)
+	require.NotNil(t, product.TaxRate)
+	assert.Equal(t, 20.0, *product.TaxRate)
+
+	code = sendJSONRequest(t, http.MethodPut, fmt.Sprintf("/v1/products/%d", productID), gin.H{"clear_tax_rate": true}, &product)
+	require.Equal(t, http.StatusOK, code)
+	assert.Nil(t, product.TaxRate)
+
+	code = sendJSONRequest(t, http.MethodPut, fmt.Sprintf("/v1/products/%d", productID), gin.H{"tax_rate": 150}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -7,11 +7,13 @@ import (
 	"fmt"
 
 	"gotemplate/config"
+	"gotemplate/core/domain"
 	handler "gotemplate/handler"
 	"gotemplate/logger"
 	repo "gotemplate/repo/postgres"
 	r "gotemplate/route"
 	"gotemplate/supertest"
+	"gotemplate/token"
 
 	"net/http"
 	"net/http/httptest"
@@ -29,6 +31,8 @@ var (
 	err              error
 	c                config.Econfig
 	validatorService *handler.ValidatorService
+	authToken        string
+	cashierToken     string
 )
 
 func validation() error {
@@ -79,6 +83,21 @@ func setupTest() {
 		log.Error("error in router")
 	}
 
+	// Initialize access token for authenticated routes
+	tokenMaker, err := token.NewJWTMaker(c)
+	if err != nil {
+		log.Error("error in token maker")
+		return
+	}
+	authToken, err = tokenMaker.CreateToken(&domain.User{ID: 1, Role: domain.Admin})
+	if err != nil {
+		log.Error("error creating access token")
+	}
+	cashierToken, err = tokenMaker.CreateToken(&domain.User{ID: 2, Role: domain.Cashier})
+	if err != nil {
+		log.Error("error creating access token")
+	}
+
 }
 
 func getrouter(db *repo.DB, log *logger.Logger, validatorService *handler.ValidatorService) (router *handler.Router, err error) {
@@ -166,6 +185,7 @@ func sendAndAssertPostRequest(t *testing.T, test *supertest.Supertest, url strin
 	}
 
 	test.Set("Content-Type", "application/json")
+	test.Set("Authorization", "Bearer "+authToken)
 	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
 
 		assert.Equal(t, http.StatusOK, rr.Code, fmt.Sprintf("Expected status code %d, got %d", http.StatusOK, rr.Code))
@@ -200,6 +220,7 @@ func sendAndAssertErrorRequest(t *testing.T, test *supertest.Supertest, url stri
 	} else {
 		test.Set("Content-Type", "application/json")
 	}
+	test.Set("Authorization", "Bearer "+authToken)
 
 	test.End(func(req *http.Request, rr *httptest.ResponseRecorder) {
 		assert.Equal(t, expectedStatusCode, rr.Code, fmt.Sprintf("Expected status code %d, got %d", expectedStatusCode, rr.Code))
@@ -1,13 +1,19 @@
 package tests
 
 import (
+	"context"
+	"fmt"
 	"gotemplate/core/domain"
 	handler "gotemplate/handler"
+	repo "gotemplate/repo/postgres"
 	"gotemplate/supertest"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUserRegister(t *testing.T) {
@@ -47,11 +53,11 @@ type meta struct {
 func TestGetUsers(t *testing.T) {
 
 	test := supertest.NewSuperTest(router, t)
-	url := "/v1/users/?skip=1&limit=5"
+	url := "/v1/users/?limit=5"
 	payload := gin.H{}
 	type GetAll struct {
 		meta  `json:"meta"`
-		Users []domain.User `json:"users"`
+		Users []handler.UserResponse `json:"users"`
 	}
 	var userall GetAll
 	sendAndAssertPostRequest(t, test, url, payload, &userall, "get")
@@ -133,3 +139,23 @@ func TestUserdbError(t *testing.T) {
 	sendAndAssertErrorRequest(t, test, url, payload, "", "post", http.StatusInternalServerError)
 
 }
+
+func TestCreateInitialAdmin(t *testing.T) {
+	userRepo := repo.NewUserRepository(db, log)
+	user := domain.User{
+		Name:     "Initial Admin",
+		Email:    fmt.Sprintf("admin-%d@example.com", time.Now().UnixNano()),
+		Password: "not-a-real-hash",
+	}
+
+	// Only the first call can create it, and none does when an admin already exists
+	_, err := userRepo.CreateInitialAdmin(context.Background(), &user)
+	require.NoError(t, err)
+	created, err := userRepo.CreateInitialAdmin(context.Background(), &user)
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	var admins int
+	require.NoError(t, db.QueryRow(context.Background(), "SELECT count(*) FROM users WHERE role = 'admin'").Scan(&admins))
+	assert.GreaterOrEqual(t, admins, 1)
+}
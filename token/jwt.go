@@ -0,0 +1,122 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gotemplate/config"
+	"gotemplate/core/domain"
+	"gotemplate/core/port"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// minSecretKeySize is the minimum length of the symmetric key used to sign tokens
+const minSecretKeySize = 32
+
+/**
+ * JWTMaker issues and verifies HS256 signed access tokens
+ * using the symmetric key and duration from the config
+ */
+type JWTMaker struct {
+	key             []byte
+	duration        time.Duration
+	refreshDuration time.Duration
+}
+
+// claims represents the JWT claims carried by an access token
+type claims struct {
+	Role domain.UserRole `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// NewJWTMaker creates a new JWTMaker instance
+func NewJWTMaker(cfg config.Econfig) (*JWTMaker, error) {
+	key := cfg.TokenSymmetricKey()
+	if len(key) < minSecretKeySize {
+		return nil, fmt.Errorf("invalid token symmetric key size: must be at least %d characters", minSecretKeySize)
+	}
+
+	duration, err := time.ParseDuration(cfg.TokenDuration())
+	if err != nil {
+		return nil, fmt.Errorf("invalid token duration: %w", err)
+	}
+
+	refreshDuration, err := time.ParseDuration(cfg.RefreshDuration())
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token duration: %w", err)
+	}
+
+	return &JWTMaker{
+		key:             []byte(key),
+		duration:        duration,
+		refreshDuration: refreshDuration,
+	}, nil
+}
+
+// CreateToken creates a new access token for the given user
+func (jm *JWTMaker) CreateToken(user *domain.User) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	c := claims{
+		Role: user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id.String(),
+			Subject:   strconv.FormatUint(user.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(jm.duration)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(jm.key)
+}
+
+// VerifyToken checks the signature and expiry of an access token and returns its payload
+func (jm *JWTMaker) VerifyToken(tokenString string) (*domain.TokenPayload, error) {
+	var c claims
+
+	_, err := jwt.ParseWithClaims(tokenString, &c, func(t *jwt.Token) (any, error) {
+		return jm.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, port.ErrExpiredToken
+		}
+		return nil, port.ErrInvalidToken
+	}
+
+	id, err := uuid.Parse(c.ID)
+	if err != nil {
+		return nil, port.ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return nil, port.ErrInvalidToken
+	}
+
+	return &domain.TokenPayload{
+		ID:        id,
+		UserID:    userID,
+		Role:      c.Role,
+		IssuedAt:  c.IssuedAt.Time,
+		ExpiredAt: c.ExpiresAt.Time,
+	}, nil
+}
+
+// Duration returns the lifetime of the access tokens issued by the maker
+func (jm *JWTMaker) Duration() time.Duration {
+	return jm.duration
+}
+
+// RefreshDuration returns the lifetime of the refresh tokens issued alongside access tokens
+func (jm *JWTMaker) RefreshDuration() time.Duration {
+	return jm.refreshDuration
+}
@@ -0,0 +1,29 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// refreshTokenSize is the number of random bytes in a refresh token
+const refreshTokenSize = 32
+
+// NewRefreshToken generates an opaque refresh token and the hash to store in its place
+func NewRefreshToken() (plain string, hash string, err error) {
+	b := make([]byte, refreshTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	plain = base64.RawURLEncoding.EncodeToString(b)
+	return plain, HashRefreshToken(plain), nil
+}
+
+// HashRefreshToken returns the hex encoded SHA-256 digest of a refresh token.
+// Refresh tokens are high-entropy random values, so a fast hash is sufficient.
+func HashRefreshToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}